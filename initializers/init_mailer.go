@@ -0,0 +1,26 @@
+package initializers
+
+import (
+	"fmt"
+	"go-crud/mailer"
+	"os"
+)
+
+var Mailer mailer.Mailer
+
+func ConnectToMailer() {
+	host := os.Getenv("SMTP_HOST")
+	if host == "" {
+		fmt.Println("SMTP_HOST not configured, emails will be kept in memory")
+		Mailer = mailer.NewMemoryMailer()
+		return
+	}
+
+	Mailer = mailer.NewSMTPMailer(
+		host,
+		os.Getenv("SMTP_PORT"),
+		os.Getenv("SMTP_USERNAME"),
+		os.Getenv("SMTP_PASSWORD"),
+		os.Getenv("SMTP_FROM"),
+	)
+}
@@ -0,0 +1,35 @@
+package jobs
+
+import (
+	"go-crud/services"
+	"log"
+	"time"
+)
+
+// Start launches the periodic background jobs of the API process
+func Start() {
+	go runEvery("purge-revoked-tokens", time.Hour, services.NewTokenRevocationService().PurgeExpired)
+	go runEvery("purge-refresh-tokens", time.Hour, services.NewRefreshTokenService().PurgeExpired)
+	go runEvery("purge-one-time-tokens", time.Hour, services.NewOneTimeTokenService().PurgeExpired)
+	go runEvery("purge-sessions", time.Hour, services.NewSessionService().PurgeExpired)
+	go runEvery("purge-oauth-states", time.Hour, services.NewOIDCService().PurgeExpiredStates)
+	go runEvery("purge-login-throttles", time.Hour, services.NewLoginThrottleService().PurgeStale)
+	go runEvery("purge-passkey-challenges", time.Hour, services.NewPasskeyService().PurgeExpiredChallenges)
+	go runEvery("process-data-exports", time.Minute, services.NewDataExportService().ProcessPending)
+	go runEvery("purge-data-exports", time.Hour, services.NewDataExportService().PurgeExpired)
+	go runEvery("purge-deleted-accounts", time.Hour, services.NewAccountDeletionService().PurgeDue)
+	go runEvery("prune-post-revisions", time.Hour, services.NewPostRevisionService().PruneExpired)
+	go runEvery("run-post-scheduler", services.PostSchedulerInterval(), services.NewPostSchedulerService().RunDue)
+}
+
+// runEvery calls job on every tick of the interval, logging failures
+func runEvery(name string, interval time.Duration, job func() error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := job(); err != nil {
+			log.Printf("[JOB] %s failed: %v", name, err)
+		}
+	}
+}
@@ -4,6 +4,7 @@ package main
 
 import (
 	_ "go-crud/docs" // This will be generated
+	"go-crud/jobs"
 	"go-crud/router"
 )
 
@@ -25,5 +26,6 @@ import (
 
 func main() {
 	r := router.SetupRouter()
+	jobs.Start()
 	r.Run() // listen and serve on 0.0.0.0:8080
 }
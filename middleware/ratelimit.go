@@ -1,7 +1,10 @@
 package middleware
 
 import (
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -93,6 +96,7 @@ func RateLimitMiddleware(rate time.Duration, burst int) gin.HandlerFunc {
 		ip := c.ClientIP()
 
 		if !limiter.isAllowed(ip) {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(rate.Seconds()))))
 			c.JSON(http.StatusTooManyRequests, gin.H{
 				"error":   "Too many requests",
 				"message": "Rate limit exceeded. Please try again later.",
@@ -104,3 +108,17 @@ func RateLimitMiddleware(rate time.Duration, burst int) gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// RateLimitPrefixMiddleware rate limits only the requests whose path starts with prefix. It is meant
+// for router.Use, for route groups that are registered by several views.
+func RateLimitPrefixMiddleware(prefix string, rate time.Duration, burst int) gin.HandlerFunc {
+	limit := RateLimitMiddleware(rate, burst)
+
+	return func(c *gin.Context) {
+		if !strings.HasPrefix(c.Request.URL.Path, prefix) {
+			c.Next()
+			return
+		}
+		limit(c)
+	}
+}
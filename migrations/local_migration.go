@@ -21,6 +21,25 @@ func main() {
 		&models.Post{},
 		&models.Tag{},
 		&models.PostTag{},
+		&models.RefreshToken{},
+		&models.RevokedToken{},
+		&models.OneTimeToken{},
+		&models.RecoveryCode{},
+		&models.UserIdentity{},
+		&models.OAuthState{},
+		&models.PersonalAccessToken{},
+		&models.AuthThrottle{},
+		&models.SecurityEvent{},
+		&models.Session{},
+		&models.Passkey{},
+		&models.PasskeyChallenge{},
+		&models.Invite{},
+		&models.DataExport{},
+		&models.AccountDeletion{},
+		&models.Impersonation{},
+		&models.ImpersonationRequest{},
+		&models.PostRedirect{},
+		&models.PostRevision{},
 	)
 	if err != nil {
 		log.Fatal("Failed to migrate database:", err)
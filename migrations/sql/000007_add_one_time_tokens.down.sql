@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_one_time_tokens_user_id;
+
+DROP TABLE IF EXISTS one_time_tokens;
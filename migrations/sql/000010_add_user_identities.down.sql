@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_user_identities_user_id;
+DROP INDEX IF EXISTS idx_user_identities_provider_subject;
+
+DROP TABLE IF EXISTS oauth_states;
+DROP TABLE IF EXISTS user_identities;
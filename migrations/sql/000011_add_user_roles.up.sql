@@ -0,0 +1,8 @@
+-- Create enum type for user roles
+CREATE TYPE user_role AS ENUM ('reader', 'author', 'editor', 'admin');
+
+-- Existing users keep what they could already do: write and manage their own posts
+ALTER TABLE users ADD COLUMN role user_role DEFAULT 'author' NOT NULL;
+
+-- Create indexes for better performance
+CREATE INDEX IF NOT EXISTS idx_users_role ON users(role);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_security_events_type;
+DROP INDEX IF EXISTS idx_security_events_user_id;
+DROP INDEX IF EXISTS idx_auth_throttles_updated_at;
+
+DROP TABLE IF EXISTS security_events;
+DROP TABLE IF EXISTS auth_throttles;
@@ -0,0 +1,26 @@
+-- Create auth_throttles table for persisted failed login counters
+CREATE TABLE IF NOT EXISTS auth_throttles (
+    id SERIAL PRIMARY KEY,
+    key VARCHAR(320) UNIQUE NOT NULL,
+    failures INTEGER NOT NULL DEFAULT 0,
+    last_failure_at TIMESTAMP NOT NULL,
+    locked_until TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create security_events table for the account security audit trail
+CREATE TABLE IF NOT EXISTS security_events (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    type VARCHAR(50) NOT NULL,
+    ip VARCHAR(45),
+    user_agent VARCHAR(512),
+    details TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create indexes for better performance
+CREATE INDEX IF NOT EXISTS idx_auth_throttles_updated_at ON auth_throttles(updated_at);
+CREATE INDEX IF NOT EXISTS idx_security_events_user_id ON security_events(user_id);
+CREATE INDEX IF NOT EXISTS idx_security_events_type ON security_events(type);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_sessions_device_hash;
+DROP INDEX IF EXISTS idx_sessions_user_id;
+
+DROP TABLE IF EXISTS sessions;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_passkey_challenges_expires_at;
+DROP INDEX IF EXISTS idx_passkeys_user_id;
+
+DROP TABLE IF EXISTS passkey_challenges;
+DROP TABLE IF EXISTS passkeys;
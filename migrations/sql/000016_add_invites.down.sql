@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_users_invite_id;
+DROP INDEX IF EXISTS idx_invites_created_by_id;
+
+ALTER TABLE users DROP COLUMN IF EXISTS invite_id;
+
+DROP TABLE IF EXISTS invites;
@@ -0,0 +1,20 @@
+-- Create invites table for invite-only registration
+CREATE TABLE IF NOT EXISTS invites (
+    id SERIAL PRIMARY KEY,
+    prefix VARCHAR(16) NOT NULL,
+    code_hash VARCHAR(64) UNIQUE NOT NULL,
+    role user_role DEFAULT 'author' NOT NULL,
+    max_uses INTEGER NOT NULL DEFAULT 1,
+    uses INTEGER NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP,
+    created_by_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Record the invite each account signed up with
+ALTER TABLE users ADD COLUMN invite_id INTEGER REFERENCES invites(id) ON DELETE SET NULL;
+
+-- Create indexes for better performance
+CREATE INDEX IF NOT EXISTS idx_invites_created_by_id ON invites(created_by_id);
+CREATE INDEX IF NOT EXISTS idx_users_invite_id ON users(invite_id);
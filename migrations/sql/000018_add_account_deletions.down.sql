@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_account_deletions_purge_at;
+
+ALTER TABLE posts DROP CONSTRAINT IF EXISTS posts_user_id_fkey;
+ALTER TABLE posts ADD CONSTRAINT posts_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+
+DROP TABLE IF EXISTS account_deletions;
@@ -0,0 +1,18 @@
+-- Create account_deletions table for deletions waiting out their grace period
+CREATE TABLE IF NOT EXISTS account_deletions (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER UNIQUE NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    requested_by_id INTEGER NOT NULL,
+    posts_policy VARCHAR(20) NOT NULL,
+    transfer_to_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    purge_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Posts are deleted, reassigned or transferred explicitly when an account is purged,
+-- never silently through the user row
+ALTER TABLE posts DROP CONSTRAINT IF EXISTS posts_user_id_fkey;
+ALTER TABLE posts ADD CONSTRAINT posts_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE RESTRICT;
+
+-- Create indexes for better performance
+CREATE INDEX IF NOT EXISTS idx_account_deletions_purge_at ON account_deletions(purge_at);
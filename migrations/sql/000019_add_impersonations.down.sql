@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_impersonation_requests_impersonation_id;
+DROP INDEX IF EXISTS idx_impersonations_user_id;
+DROP INDEX IF EXISTS idx_impersonations_admin_id;
+
+DROP TABLE IF EXISTS impersonation_requests;
+DROP TABLE IF EXISTS impersonations;
@@ -0,0 +1,29 @@
+-- Create impersonations table for the audit trail of admins acting as other users
+CREATE TABLE IF NOT EXISTS impersonations (
+    id SERIAL PRIMARY KEY,
+    admin_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    reason TEXT NOT NULL,
+    token_id VARCHAR(64) UNIQUE NOT NULL,
+    ip VARCHAR(45),
+    user_agent VARCHAR(512),
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create impersonation_requests table for every request made with an impersonation token
+CREATE TABLE IF NOT EXISTS impersonation_requests (
+    id SERIAL PRIMARY KEY,
+    impersonation_id INTEGER NOT NULL REFERENCES impersonations(id) ON DELETE CASCADE,
+    method VARCHAR(10) NOT NULL,
+    path VARCHAR(2048) NOT NULL,
+    status INTEGER NOT NULL,
+    ip VARCHAR(45),
+    user_agent VARCHAR(512),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create indexes for better performance
+CREATE INDEX IF NOT EXISTS idx_impersonations_admin_id ON impersonations(admin_id);
+CREATE INDEX IF NOT EXISTS idx_impersonations_user_id ON impersonations(user_id);
+CREATE INDEX IF NOT EXISTS idx_impersonation_requests_impersonation_id ON impersonation_requests(impersonation_id);
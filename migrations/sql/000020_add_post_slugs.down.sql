@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_posts_slug;
+
+ALTER TABLE posts DROP COLUMN IF EXISTS slug;
@@ -0,0 +1,44 @@
+-- Add slugs to posts for readable permalinks
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS slug VARCHAR(255);
+
+-- Backfill existing posts from their titles the way the application slugifies them: decompose
+-- accented letters and drop the accents, spell out the letters that do not decompose, then turn
+-- whatever else is not a-z or 0-9 into dashes. "Thế giới" becomes "the-gioi".
+UPDATE posts
+SET slug = COALESCE(NULLIF(trim(both '-' from left(regexp_replace(
+    translate(
+        replace(replace(replace(replace(replace(replace(replace(replace(replace(replace(replace(replace(replace(replace(
+            regexp_replace(lower(normalize(title, NFKD)), '[\u0300-\u036f\u1ab0-\u1aff\u1dc0-\u1dff\u20d0-\u20ff\ufe20-\ufe2f]', '', 'g'),
+            'ß', 'ss'), 'æ', 'ae'), 'œ', 'oe'), 'þ', 'th'), 'ж', 'zh'), 'х', 'kh'), 'ц', 'ts'),
+            'ч', 'ch'), 'щ', 'shch'), 'ш', 'sh'), 'ю', 'yu'), 'я', 'ya'), 'є', 'ye'), 'ї', 'yi'),
+        'đðøłıабвгдеёзийклмнопрстуфыэіґъь', 'ddoliabvgdeeziyklmnoprstufyeig'),
+    '[^a-z0-9]+', '-', 'g'), 100)), ''), 'post')
+WHERE slug IS NULL;
+
+-- Suffix duplicates in creation order, so the oldest post keeps the plain slug. Each one gets the
+-- first of -2, -3, ... that no post uses yet: a title like "Foo 2" may already have taken "foo-2".
+DO $$
+DECLARE
+    duplicate RECORD;
+    n INTEGER;
+BEGIN
+    FOR duplicate IN
+        SELECT id, slug FROM (
+            SELECT id, slug, created_at, row_number() OVER (PARTITION BY slug ORDER BY created_at, id) AS position
+            FROM posts
+        ) numbered
+        WHERE position > 1
+        ORDER BY created_at, id
+    LOOP
+        n := 2;
+        WHILE EXISTS (SELECT 1 FROM posts WHERE slug = duplicate.slug || '-' || n) LOOP
+            n := n + 1;
+        END LOOP;
+        UPDATE posts SET slug = duplicate.slug || '-' || n WHERE id = duplicate.id;
+    END LOOP;
+END
+$$;
+
+ALTER TABLE posts ALTER COLUMN slug SET NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_posts_slug ON posts(slug);
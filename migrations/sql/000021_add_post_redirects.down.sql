@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_post_redirects_post_id;
+
+DROP TABLE IF EXISTS post_redirects;
@@ -0,0 +1,12 @@
+-- Create post_redirects table for old slugs and manual redirects to posts
+CREATE TABLE IF NOT EXISTS post_redirects (
+    id SERIAL PRIMARY KEY,
+    from_slug VARCHAR(255) UNIQUE NOT NULL,
+    post_id INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    kind VARCHAR(16) NOT NULL,
+    created_by_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create indexes for better performance
+CREATE INDEX IF NOT EXISTS idx_post_redirects_post_id ON post_redirects(post_id);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_post_revisions_author_id;
+DROP INDEX IF EXISTS idx_post_revisions_post_number;
+
+DROP TABLE IF EXISTS post_revisions;
@@ -0,0 +1,21 @@
+-- Create post_revisions table for the history of every save of a post
+CREATE TABLE IF NOT EXISTS post_revisions (
+    id SERIAL PRIMARY KEY,
+    post_id INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    number INTEGER NOT NULL,
+    author_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    title VARCHAR(255) NOT NULL,
+    content_markdown TEXT,
+    content_json TEXT,
+    restored_from INTEGER,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Existing posts start their history with their current content
+INSERT INTO post_revisions (post_id, number, author_id, title, content_markdown, content_json, created_at)
+SELECT id, 1, user_id, title, content_markdown, content_json, updated_at
+FROM posts;
+
+-- Create indexes for better performance
+CREATE UNIQUE INDEX IF NOT EXISTS idx_post_revisions_post_number ON post_revisions(post_id, number);
+CREATE INDEX IF NOT EXISTS idx_post_revisions_author_id ON post_revisions(author_id);
@@ -0,0 +1,14 @@
+DROP INDEX IF EXISTS idx_posts_unpublish_at;
+DROP INDEX IF EXISTS idx_posts_publish_at;
+
+ALTER TABLE posts DROP COLUMN IF EXISTS unpublish_at;
+ALTER TABLE posts DROP COLUMN IF EXISTS publish_at;
+
+-- Enum values cannot be dropped, recreate the type without 'scheduled'; scheduled posts become drafts
+ALTER TABLE posts ALTER COLUMN status DROP DEFAULT;
+ALTER TYPE post_status RENAME TO post_status_old;
+CREATE TYPE post_status AS ENUM ('draft', 'published');
+ALTER TABLE posts ALTER COLUMN status TYPE post_status
+    USING (CASE WHEN status::text = 'scheduled' THEN 'draft' ELSE status::text END)::post_status;
+ALTER TABLE posts ALTER COLUMN status SET DEFAULT 'draft';
+DROP TYPE post_status_old;
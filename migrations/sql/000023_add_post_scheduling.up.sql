@@ -0,0 +1,10 @@
+-- Scheduled posts are published by the scheduler once publish_at is reached
+ALTER TYPE post_status ADD VALUE IF NOT EXISTS 'scheduled';
+
+-- Add publishing times; unpublish_at puts a post back to draft when it is reached
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS publish_at TIMESTAMP;
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS unpublish_at TIMESTAMP;
+
+-- Create indexes for the scheduler
+CREATE INDEX IF NOT EXISTS idx_posts_publish_at ON posts(publish_at);
+CREATE INDEX IF NOT EXISTS idx_posts_unpublish_at ON posts(unpublish_at);
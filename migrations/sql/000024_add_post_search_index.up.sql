@@ -0,0 +1,6 @@
+-- Full-text search over posts, titles weighing more than content. Queries must use this exact
+-- expression for the index to be used.
+CREATE INDEX IF NOT EXISTS idx_posts_search ON posts USING GIN (
+    (setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+     setweight(to_tsvector('english', coalesce(content_markdown, '')), 'B'))
+);
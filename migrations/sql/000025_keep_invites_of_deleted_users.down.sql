@@ -0,0 +1,7 @@
+-- Invites whose creator was deleted cannot be kept with a required creator
+DELETE FROM invites WHERE created_by_id IS NULL;
+
+ALTER TABLE invites DROP CONSTRAINT IF EXISTS invites_created_by_id_fkey;
+ALTER TABLE invites ALTER COLUMN created_by_id SET NOT NULL;
+ALTER TABLE invites ADD CONSTRAINT invites_created_by_id_fkey
+    FOREIGN KEY (created_by_id) REFERENCES users(id) ON DELETE CASCADE;
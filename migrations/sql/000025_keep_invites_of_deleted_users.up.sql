@@ -0,0 +1,5 @@
+-- Invites outlive the account that created them, so accounts signed up with them keep their invite
+ALTER TABLE invites DROP CONSTRAINT IF EXISTS invites_created_by_id_fkey;
+ALTER TABLE invites ALTER COLUMN created_by_id DROP NOT NULL;
+ALTER TABLE invites ADD CONSTRAINT invites_created_by_id_fkey
+    FOREIGN KEY (created_by_id) REFERENCES users(id) ON DELETE SET NULL;
@@ -0,0 +1,11 @@
+-- Flag the account standing in as the author of posts of purged accounts, rather than trusting its address
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_placeholder BOOLEAN DEFAULT FALSE NOT NULL;
+
+-- The placeholder never had a password or a linked login; an account that does was registered by someone
+UPDATE users SET is_placeholder = TRUE
+WHERE email = 'deleted-user@deleted.invalid'
+  AND hashed_password = ''
+  AND NOT EXISTS (SELECT 1 FROM user_identities WHERE user_identities.user_id = users.id);
+
+-- There is only ever one placeholder
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_placeholder ON users(is_placeholder) WHERE is_placeholder;
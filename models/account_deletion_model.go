@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// DeletedPostsPolicy decides what happens to the posts of an account when it is purged
+type DeletedPostsPolicy string
+
+const (
+	DeletePosts   DeletedPostsPolicy = "delete"
+	ReassignPosts DeletedPostsPolicy = "reassign"
+	TransferPosts DeletedPostsPolicy = "transfer"
+)
+
+// IsValid reports whether the policy is one of the known policies
+func (p DeletedPostsPolicy) IsValid() bool {
+	switch p {
+	case DeletePosts, ReassignPosts, TransferPosts:
+		return true
+	}
+	return false
+}
+
+// AccountDeletion schedules an account to be purged once PurgeAt is reached. Until then the
+// deletion can be cancelled and the account works as before.
+type AccountDeletion struct {
+	ID            uint               `gorm:"primaryKey" json:"id" example:"1"`
+	UserID        uint               `gorm:"not null;uniqueIndex" json:"user_id" example:"1"`
+	RequestedByID uint               `gorm:"not null" json:"requested_by_id" example:"1"`
+	PostsPolicy   DeletedPostsPolicy `gorm:"not null;size:20" json:"posts_policy" example:"reassign"`
+	TransferToID  *uint              `json:"transfer_to_id,omitempty" example:"2"`
+	PurgeAt       time.Time          `gorm:"not null;index" json:"purge_at" example:"2023-01-15T00:00:00Z"`
+	CreatedAt     time.Time          `json:"created_at" example:"2023-01-01T00:00:00Z"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// AuthThrottle counts consecutive failed authentication attempts for a key
+// (an account email or a client IP) so that lockouts survive restarts
+type AuthThrottle struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	Key           string     `gorm:"not null;size:320;uniqueIndex" json:"key"`
+	Failures      int        `gorm:"not null;default:0" json:"failures"`
+	LastFailureAt time.Time  `gorm:"not null" json:"last_failure_at"`
+	LockedUntil   *time.Time `json:"locked_until,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `gorm:"index" json:"updated_at"`
+}
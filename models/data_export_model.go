@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+type DataExportStatus string
+
+const (
+	DataExportPending    DataExportStatus = "pending"
+	DataExportProcessing DataExportStatus = "processing"
+	DataExportReady      DataExportStatus = "ready"
+	DataExportFailed     DataExportStatus = "failed"
+)
+
+// DataExport is a user's request for a copy of their personal data. The archive is built in the
+// background, kept until ExpiresAt and then deleted.
+type DataExport struct {
+	ID          uint             `gorm:"primaryKey" json:"id" example:"1"`
+	UserID      uint             `gorm:"not null;index" json:"user_id" example:"1"`
+	Status      DataExportStatus `gorm:"not null;size:20;default:'pending';index" json:"status" example:"ready"`
+	Archive     []byte           `json:"-"`
+	Size        int64            `gorm:"not null;default:0" json:"size" example:"20480"`
+	Error       string           `gorm:"type:text" json:"error,omitempty"`
+	StartedAt   *time.Time       `json:"-"`
+	CompletedAt *time.Time       `json:"completed_at,omitempty" example:"2023-01-01T00:05:00Z"`
+	ExpiresAt   *time.Time       `gorm:"index" json:"expires_at,omitempty" example:"2023-01-08T00:05:00Z"`
+	CreatedAt   time.Time        `json:"created_at" example:"2023-01-01T00:00:00Z"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Impersonation is an admin acting as another user through a short-lived access token, issued to
+// reproduce problems the user reports. Rows are kept as the audit trail, together with every
+// request made with the token.
+type Impersonation struct {
+	ID        uint      `gorm:"primaryKey" json:"id" example:"1"`
+	AdminID   *uint     `gorm:"index" json:"admin_id" example:"1"`
+	UserID    *uint     `gorm:"index" json:"user_id" example:"2"`
+	Reason    string    `gorm:"type:text;not null" json:"reason" example:"Reproducing ticket #123"`
+	TokenID   string    `gorm:"not null;size:64;uniqueIndex" json:"-"`
+	IP        string    `gorm:"size:45" json:"ip" example:"203.0.113.7"`
+	UserAgent string    `gorm:"size:512" json:"user_agent"`
+	ExpiresAt time.Time `gorm:"not null" json:"expires_at" example:"2023-01-01T00:15:00Z"`
+	CreatedAt time.Time `json:"created_at" example:"2023-01-01T00:00:00Z"`
+}
+
+// ImpersonationRequest is one request made with an impersonation token
+type ImpersonationRequest struct {
+	ID              uint      `gorm:"primaryKey" json:"id" example:"1"`
+	ImpersonationID uint      `gorm:"not null;index" json:"impersonation_id" example:"1"`
+	Method          string    `gorm:"not null;size:10" json:"method" example:"GET"`
+	Path            string    `gorm:"not null;size:2048" json:"path" example:"/users/me/posts?status=draft"`
+	Status          int       `gorm:"not null" json:"status" example:"200"`
+	IP              string    `gorm:"size:45" json:"ip" example:"203.0.113.7"`
+	UserAgent       string    `gorm:"size:512" json:"user_agent"`
+	CreatedAt       time.Time `json:"created_at" example:"2023-01-01T00:01:00Z"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Invite lets people sign up while registration is restricted. Accounts created with it get
+// its role. Only the hash of the code is stored; Prefix is kept so admins can recognise invites.
+// Invites outlive the account that created them, which only clears CreatedByID.
+type Invite struct {
+	ID          uint       `gorm:"primaryKey" json:"id" example:"1"`
+	Prefix      string     `gorm:"not null;size:16" json:"prefix" example:"hbi_Zk3mP9c1"`
+	CodeHash    string     `gorm:"not null;size:64;uniqueIndex" json:"-"`
+	Role        Role       `gorm:"not null;default:'author'" json:"role" example:"author"`
+	MaxUses     int        `gorm:"not null;default:1" json:"max_uses" example:"5"`
+	Uses        int        `gorm:"not null;default:0" json:"uses" example:"2"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty" example:"2024-01-01T00:00:00Z"`
+	CreatedByID *uint      `gorm:"index" json:"created_by_id,omitempty" example:"1"`
+	CreatedBy   *User      `gorm:"foreignKey:CreatedByID;constraint:OnDelete:SET NULL" json:"-"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at" example:"2023-01-01T00:00:00Z"`
+}
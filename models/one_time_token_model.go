@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// OneTimeTokenPurpose identifies what a one-time token may be used for
+type OneTimeTokenPurpose string
+
+const (
+	PasswordResetPurpose OneTimeTokenPurpose = "password_reset"
+	MagicLinkPurpose     OneTimeTokenPurpose = "magic_link"
+)
+
+// OneTimeToken is a single-use, expiring secret sent to a user out of band (e.g. by email).
+// Only a hash of the token is stored.
+type OneTimeToken struct {
+	ID        uint                `gorm:"primaryKey" json:"id"`
+	UserID    uint                `gorm:"not null;index" json:"user_id"`
+	Purpose   OneTimeTokenPurpose `gorm:"not null;size:32" json:"purpose"`
+	TokenHash string              `gorm:"not null;size:64;uniqueIndex" json:"-"`
+	ExpiresAt time.Time           `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time          `json:"used_at,omitempty"`
+	CreatedAt time.Time           `json:"created_at"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// Passkey is a WebAuthn credential registered by a user. Only the public key is stored,
+// the private key never leaves the authenticator.
+type Passkey struct {
+	ID              uint       `gorm:"primaryKey" json:"id" example:"1"`
+	UserID          uint       `gorm:"not null;index" json:"user_id" example:"1"`
+	Name            string     `gorm:"not null;size:100" json:"name" example:"MacBook Touch ID"`
+	CredentialID    []byte     `gorm:"not null;uniqueIndex" json:"-"`
+	PublicKey       []byte     `gorm:"not null" json:"-"`
+	AttestationType string     `gorm:"size:32" json:"-"`
+	Transports      string     `gorm:"size:255" json:"-"`
+	AAGUID          []byte     `json:"-"`
+	SignCount       uint32     `gorm:"not null;default:0" json:"-"`
+	BackupEligible  bool       `gorm:"not null;default:false" json:"backup_eligible" example:"true"`
+	BackupState     bool       `gorm:"not null;default:false" json:"backup_state" example:"true"`
+	LastUsedAt      *time.Time `json:"last_used_at,omitempty" example:"2023-01-01T00:00:00Z"`
+	CreatedAt       time.Time  `json:"created_at" example:"2023-01-01T00:00:00Z"`
+}
+
+// PasskeyCeremony identifies which WebAuthn ceremony a challenge was issued for
+type PasskeyCeremony string
+
+const (
+	PasskeyRegistration PasskeyCeremony = "registration"
+	PasskeyLogin        PasskeyCeremony = "login"
+)
+
+// PasskeyChallenge holds the server-side state of an in-flight WebAuthn ceremony until the
+// authenticator's response comes back. It is deleted when used.
+type PasskeyChallenge struct {
+	ID          uint            `gorm:"primaryKey" json:"id"`
+	Challenge   string          `gorm:"not null;size:128;uniqueIndex" json:"-"`
+	Ceremony    PasskeyCeremony `gorm:"not null;size:20" json:"ceremony"`
+	UserID      *uint           `json:"user_id,omitempty"`
+	SessionData string          `gorm:"type:text;not null" json:"-"`
+	ExpiresAt   time.Time       `gorm:"not null" json:"expires_at"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// PersonalAccessToken is a long-lived, scoped credential for automation such as CI pipelines.
+// Only the hash of the secret is stored; Prefix is kept so users can recognise their tokens.
+type PersonalAccessToken struct {
+	ID         uint           `gorm:"primaryKey" json:"id" example:"1"`
+	UserID     uint           `gorm:"not null;index" json:"user_id" example:"1"`
+	Name       string         `gorm:"not null;size:100" json:"name" example:"Publish from CI"`
+	Prefix     string         `gorm:"not null;size:16" json:"prefix" example:"hbp_Zk3mP9c1"`
+	TokenHash  string         `gorm:"not null;size:64;uniqueIndex" json:"-"`
+	Scopes     pq.StringArray `gorm:"type:text[];not null" json:"scopes" swaggertype:"array,string" example:"posts:write,read"`
+	ExpiresAt  *time.Time     `json:"expires_at,omitempty" example:"2024-01-01T00:00:00Z"`
+	LastUsedAt *time.Time     `json:"last_used_at,omitempty" example:"2023-06-01T00:00:00Z"`
+	RevokedAt  *time.Time     `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time      `json:"created_at" example:"2023-01-01T00:00:00Z"`
+}
+
+// HasScope reports whether the token was granted the scope
+func (t PersonalAccessToken) HasScope(scope string) bool {
+	for _, granted := range t.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -7,15 +7,29 @@ type PostStatus string
 const (
 	Draft     PostStatus = "draft"
 	Published PostStatus = "published"
+	// Scheduled posts are published by the scheduler once PublishAt is reached
+	Scheduled PostStatus = "scheduled"
 )
 
+// IsValid reports whether s is one of the known post statuses
+func (s PostStatus) IsValid() bool {
+	switch s {
+	case Draft, Published, Scheduled:
+		return true
+	}
+	return false
+}
+
 type Post struct {
 	ID              uint       `gorm:"primaryKey" json:"id" example:"1"`
 	UserID          uint       `gorm:"not null" json:"user_id" example:"1"`
 	Title           string     `gorm:"not null" json:"title" example:"My First Post"`
+	Slug            string     `gorm:"not null;size:255;uniqueIndex" json:"slug" example:"my-first-post"`
 	ContentMarkdown string     `gorm:"column:content_markdown;type:text" json:"content_markdown" example:"# My First Post\n\nThis is **markdown** content"`
 	ContentJSON     string     `gorm:"column:content_json;type:text" json:"content_json" example:"{\"type\":\"doc\",\"content\":[]}"`
 	Status          PostStatus `gorm:"default:'draft';not null" json:"status" example:"draft"`
+	PublishAt       *time.Time `gorm:"index" json:"publish_at,omitempty" example:"2023-01-01T09:00:00Z"`
+	UnpublishAt     *time.Time `gorm:"index" json:"unpublish_at,omitempty" example:"2023-02-01T00:00:00Z"`
 	User            *User      `gorm:"foreignKey:UserID" json:"user,omitempty"`
 	Tags            []Tag      `gorm:"many2many:post_tags" json:"tags,omitempty"`
 	CreatedAt       time.Time  `json:"created_at" example:"2023-01-01T00:00:00Z"`
@@ -26,3 +40,16 @@ type Post struct {
 func (p Post) GetID() uint {
 	return p.ID
 }
+
+// EffectiveStatus returns the status the post has at the given time, which the scheduler may not
+// have caught up with yet: scheduled posts are published once PublishAt is reached, and posts go
+// back to draft once UnpublishAt is.
+func (p Post) EffectiveStatus(now time.Time) PostStatus {
+	if p.UnpublishAt != nil && !p.UnpublishAt.After(now) {
+		return Draft
+	}
+	if p.Status == Scheduled && p.PublishAt != nil && !p.PublishAt.After(now) {
+		return Published
+	}
+	return p.Status
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+type PostRedirectKind string
+
+const (
+	// RedirectHistory is recorded when a post's slug changes, so links to the old slug keep working
+	RedirectHistory PostRedirectKind = "history"
+	// RedirectManual is created by an editor, for paths that never were the post's slug
+	RedirectManual PostRedirectKind = "manual"
+)
+
+// PostRedirect sends lookups of a slug that is no longer in use to a post. It points at the post
+// rather than at its slug, so it always leads to the current one, and goes away with the post.
+type PostRedirect struct {
+	ID          uint             `gorm:"primaryKey" json:"id" example:"1"`
+	FromSlug    string           `gorm:"not null;size:255;uniqueIndex" json:"from_slug" example:"my-old-post"`
+	PostID      uint             `gorm:"not null;index" json:"post_id" example:"1"`
+	Kind        PostRedirectKind `gorm:"not null;size:16" json:"kind" example:"manual"`
+	CreatedByID *uint            `json:"created_by_id,omitempty" example:"1"`
+	Post        *Post            `gorm:"foreignKey:PostID;constraint:OnDelete:CASCADE" json:"-"`
+	CreatedAt   time.Time        `json:"created_at" example:"2023-01-01T00:00:00Z"`
+}
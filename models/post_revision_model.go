@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// PostRevision is an immutable snapshot of a post, recorded every time it is saved. Revisions are
+// numbered from 1 for each post; RestoredFrom is set on revisions that brought back an older one.
+type PostRevision struct {
+	ID              uint      `gorm:"primaryKey" json:"id" example:"1"`
+	PostID          uint      `gorm:"not null;uniqueIndex:idx_post_revisions_post_number" json:"post_id" example:"1"`
+	Number          int       `gorm:"not null;uniqueIndex:idx_post_revisions_post_number" json:"number" example:"3"`
+	AuthorID        *uint     `gorm:"index" json:"author_id,omitempty" example:"1"`
+	Title           string    `gorm:"not null" json:"title" example:"My First Post"`
+	ContentMarkdown string    `gorm:"column:content_markdown;type:text" json:"content_markdown,omitempty" example:"# My First Post\n\nThis is **markdown** content"`
+	ContentJSON     string    `gorm:"column:content_json;type:text" json:"content_json,omitempty" example:"{\"type\":\"doc\",\"content\":[]}"`
+	RestoredFrom    *int      `json:"restored_from,omitempty" example:"1"`
+	Post            *Post     `gorm:"foreignKey:PostID;constraint:OnDelete:CASCADE" json:"-"`
+	CreatedAt       time.Time `json:"created_at" example:"2023-01-01T00:00:00Z"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// RefreshToken is a long-lived, single-use credential exchanged for new access tokens.
+// Tokens issued from the same login share a FamilyID so that a reused (stolen)
+// token can revoke every descendant at once.
+type RefreshToken struct {
+	ID        uint       `gorm:"primaryKey" json:"id" example:"1"`
+	UserID    uint       `gorm:"not null;index" json:"user_id" example:"1"`
+	FamilyID  string     `gorm:"not null;size:64;index" json:"family_id"`
+	TokenHash string     `gorm:"not null;size:64;uniqueIndex" json:"-"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at" example:"2023-01-31T00:00:00Z"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at" example:"2023-01-01T00:00:00Z"`
+}
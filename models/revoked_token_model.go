@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// RevokedToken records an access token that must no longer be accepted.
+// When JTI is nil the entry revokes every token of the user issued before RevokedAt.
+// Rows are only kept until ExpiresAt, after which the tokens they cover have expired anyway.
+type RevokedToken struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	JTI       *string   `gorm:"column:jti;size:64;uniqueIndex" json:"jti,omitempty"`
+	UserID    uint      `gorm:"not null;index" json:"user_id"`
+	RevokedAt time.Time `gorm:"not null" json:"revoked_at"`
+	ExpiresAt time.Time `gorm:"not null;index" json:"expires_at"`
+}
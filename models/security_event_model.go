@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+type SecurityEventType string
+
+const (
+	AccountLockedEvent SecurityEventType = "account_locked"
+	IPLockedEvent      SecurityEventType = "ip_locked"
+	PasskeyClonedEvent SecurityEventType = "passkey_cloned"
+	PasskeyAddedEvent  SecurityEventType = "passkey_added"
+)
+
+// SecurityEvent is an append-only record of security relevant activity on an account
+type SecurityEvent struct {
+	ID        uint              `gorm:"primaryKey" json:"id" example:"1"`
+	UserID    *uint             `gorm:"index" json:"user_id,omitempty" example:"1"`
+	Type      SecurityEventType `gorm:"not null;size:50;index" json:"type" example:"account_locked"`
+	IP        string            `gorm:"size:45" json:"ip" example:"203.0.113.7"`
+	UserAgent string            `gorm:"size:512" json:"user_agent"`
+	Details   string            `gorm:"type:text" json:"details"`
+	CreatedAt time.Time         `json:"created_at" example:"2023-01-01T00:00:00Z"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// Session is one signed-in device. It spans the refresh token family issued at login,
+// and access tokens refer to it through their sid claim.
+type Session struct {
+	ID         uint       `gorm:"primaryKey" json:"id" example:"1"`
+	UserID     uint       `gorm:"not null;index" json:"user_id" example:"1"`
+	FamilyID   string     `gorm:"not null;size:64;uniqueIndex" json:"-"`
+	DeviceHash string     `gorm:"not null;size:64;index" json:"-"`
+	UserAgent  string     `gorm:"size:512" json:"user_agent" example:"Mozilla/5.0 (Macintosh; Intel Mac OS X 14_0)"`
+	IP         string     `gorm:"size:45" json:"ip" example:"203.0.113.7"`
+	CreatedAt  time.Time  `json:"created_at" example:"2023-01-01T00:00:00Z"`
+	LastSeenAt time.Time  `gorm:"not null" json:"last_seen_at" example:"2023-01-01T00:00:00Z"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// UserIdentity links a user to an account at an external OpenID Connect provider.
+// A user can have several identities, one per provider account.
+type UserIdentity struct {
+	ID          uint       `gorm:"primaryKey" json:"id" example:"1"`
+	UserID      uint       `gorm:"not null;index" json:"user_id" example:"1"`
+	Provider    string     `gorm:"not null;size:50;uniqueIndex:idx_user_identities_provider_subject" json:"provider" example:"google"`
+	Subject     string     `gorm:"not null;size:255;uniqueIndex:idx_user_identities_provider_subject" json:"subject" example:"110169484474386276334"`
+	Email       string     `gorm:"size:255" json:"email" example:"connortran@gmail.com"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty" example:"2023-01-01T00:00:00Z"`
+	CreatedAt   time.Time  `json:"created_at" example:"2023-01-01T00:00:00Z"`
+}
+
+// OAuthState holds the server-side half of an in-flight authorization code flow.
+// The state value itself is only stored hashed.
+type OAuthState struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	StateHash    string    `gorm:"not null;size:64;uniqueIndex" json:"-"`
+	Provider     string    `gorm:"not null;size:50" json:"provider"`
+	Nonce        string    `gorm:"not null;size:64" json:"-"`
+	CodeVerifier string    `gorm:"not null;size:128" json:"-"`
+	LinkUserID   *uint     `json:"link_user_id,omitempty"`
+	ExpiresAt    time.Time `gorm:"not null" json:"expires_at"`
+	CreatedAt    time.Time `json:"created_at"`
+}
@@ -2,15 +2,46 @@ package models
 
 import "time"
 
+// Role grants a fixed set of permissions, see services.RolePermissions
+type Role string
+
+const (
+	RoleReader Role = "reader"
+	RoleAuthor Role = "author"
+	RoleEditor Role = "editor"
+	RoleAdmin  Role = "admin"
+)
+
+// IsValid reports whether the role is one of the known roles
+func (r Role) IsValid() bool {
+	switch r {
+	case RoleReader, RoleAuthor, RoleEditor, RoleAdmin:
+		return true
+	}
+	return false
+}
+
 type User struct {
-	ID             uint      `gorm:"primaryKey" json:"id" example:"1"`
-	Name           string    `gorm:"not null" json:"name" example:"Connor Tran"`
-	Email          string    `gorm:"unique;not null" json:"email" example:"connortran@gmail.com"`
-	HashedPassword string    `gorm:"not null" json:"-"`
-	CreatedAt      time.Time `json:"created_at" example:"2023-01-01T00:00:00Z"`
-	UpdatedAt      time.Time `json:"updated_at" example:"2023-01-01T00:00:00Z"`
+	ID              uint       `gorm:"primaryKey" json:"id" example:"1"`
+	Name            string     `gorm:"not null" json:"name" example:"Connor Tran"`
+	Email           string     `gorm:"unique;not null" json:"email" example:"connortran@gmail.com"`
+	HashedPassword  string     `gorm:"not null" json:"-"`
+	Role            Role       `gorm:"default:'author';not null" json:"role" example:"author"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at" example:"2023-01-01T00:00:00Z"`
+	TOTPSecret      string     `gorm:"column:totp_secret;size:64" json:"-"`
+	TOTPEnabledAt   *time.Time `gorm:"column:totp_enabled_at" json:"totp_enabled_at" example:"2023-01-01T00:00:00Z"`
+	TOTPLastStep    int64      `gorm:"column:totp_last_step;default:0;not null" json:"-"`
+	InviteID        *uint      `gorm:"index" json:"-"`
+	IsPlaceholder   bool       `gorm:"not null;default:false;uniqueIndex:idx_users_placeholder,where:is_placeholder" json:"-"`
+	CreatedAt       time.Time  `json:"created_at" example:"2023-01-01T00:00:00Z"`
+	UpdatedAt       time.Time  `json:"updated_at" example:"2023-01-01T00:00:00Z"`
 }
 
 func (u User) GetID() uint {
 	return u.ID
 }
+
+// HasTOTPEnabled reports whether the user must present a TOTP code to log in
+func (u User) HasTOTPEnabled() bool {
+	return u.TOTPEnabledAt != nil
+}
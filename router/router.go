@@ -3,6 +3,7 @@ package router
 import (
 	"go-crud/initializers"
 	"go-crud/middleware"
+	"go-crud/services"
 	"go-crud/views"
 	"time"
 
@@ -16,6 +17,7 @@ func SetupRouter() *gin.Engine {
 	// Initialize dependencies
 	initializers.LoadEnvVariables()
 	initializers.ConnectToDB()
+	initializers.ConnectToMailer()
 
 	router := gin.Default()
 
@@ -46,15 +48,51 @@ func SetupRouter() *gin.Engine {
 		public.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 	}
 
+	// Rate limiting for authentication endpoints, on top of the login lockout
+	router.Use(middleware.RateLimitPrefixMiddleware("/auth/", services.AuthRateLimitInterval(), services.AuthRateLimitBurst()))
+
 	postViews := views.NewPostViews()
 	postViews.RegisterRoutes(router)
 
+	postRedirectViews := views.NewPostRedirectViews()
+	postRedirectViews.RegisterRoutes(router)
+
+	postRevisionViews := views.NewPostRevisionViews()
+	postRevisionViews.RegisterRoutes(router)
+
 	userViews := views.NewUserViews()
 	userViews.RegisterRoutes(router)
 
 	authViews := views.NewAuthViews()
 	authViews.RegisterRoutes(router)
 
+	mfaViews := views.NewMFAViews()
+	mfaViews.RegisterRoutes(router)
+
+	oidcViews := views.NewOIDCViews()
+	oidcViews.RegisterRoutes(router)
+
+	personalAccessTokenViews := views.NewPersonalAccessTokenViews()
+	personalAccessTokenViews.RegisterRoutes(router)
+
+	sessionViews := views.NewSessionViews()
+	sessionViews.RegisterRoutes(router)
+
+	passkeyViews := views.NewPasskeyViews()
+	passkeyViews.RegisterRoutes(router)
+
+	inviteViews := views.NewInviteViews()
+	inviteViews.RegisterRoutes(router)
+
+	dataExportViews := views.NewDataExportViews()
+	dataExportViews.RegisterRoutes(router)
+
+	impersonationViews := views.NewImpersonationViews()
+	impersonationViews.RegisterRoutes(router)
+
+	wellKnownViews := views.NewWellKnownViews()
+	wellKnownViews.RegisterRoutes(router)
+
 	tagViews := views.NewTagViews()
 	tagViews.RegisterRoutes(router)
 
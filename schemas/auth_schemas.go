@@ -5,7 +5,39 @@ type LoginInput struct {
 	Password string `json:"password" binding:"required" example:"password123"`
 }
 
+type RefreshTokenInput struct {
+	// Required unless the refresh token cookie is used
+	RefreshToken string `json:"refresh_token" example:"Zk3mP9c1Q2xW..."`
+}
+
+type LogoutInput struct {
+	RefreshToken string `json:"refresh_token" example:"Zk3mP9c1Q2xW..."`
+}
+
+type ForgotPasswordInput struct {
+	Email string `json:"email" binding:"required,email" example:"connor@example.com"`
+}
+
+type ResetPasswordInput struct {
+	Token       string `json:"token" binding:"required" example:"Zk3mP9c1Q2xW..."`
+	NewPassword string `json:"new_password" binding:"required" example:"n3w-s3cret-pass"`
+}
+
+type VerifyEmailInput struct {
+	Token string `json:"token" binding:"required" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+}
+
+type MagicLinkInput struct {
+	Email string `json:"email" binding:"required,email" example:"connor@example.com"`
+}
+
+type ConsumeMagicLinkInput struct {
+	Token string `json:"token" binding:"required" example:"Zk3mP9c1Q2xW..."`
+}
+
 type AuthResponse struct {
-	Token string `json:"token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	Token        string `json:"token,omitempty" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	RefreshToken string `json:"refresh_token,omitempty" example:"Zk3mP9c1Q2xW..."`
+	ExpiresIn    int64  `json:"expires_in,omitempty" example:"900"`
 	UserResponse
 }
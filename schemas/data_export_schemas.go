@@ -0,0 +1,15 @@
+package schemas
+
+import (
+	"go-crud/models"
+	"time"
+)
+
+// Output Schemas
+type DataExportResponse struct {
+	Data models.DataExport `json:"data"`
+	// Signed URL to download the archive, relative to the API. Only set once the export is ready.
+	DownloadURL       string     `json:"download_url,omitempty" example:"/exports/download?token=eyJhbGciOi..."`
+	DownloadExpiresAt *time.Time `json:"download_expires_at,omitempty" example:"2023-01-01T00:20:00Z"`
+	Message           string     `json:"message,omitempty" example:"Your data export is being prepared"`
+}
@@ -0,0 +1,25 @@
+package schemas
+
+import "go-crud/models"
+
+// Input Schemas
+type ImpersonateUserInput struct {
+	Reason string `json:"reason" binding:"required,min=3,max=500" example:"Reproducing ticket #123"`
+}
+
+// Output Schemas
+type ImpersonationTokenResponse struct {
+	Data      models.Impersonation `json:"data"`
+	Token     string               `json:"token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	ExpiresIn int64                `json:"expires_in" example:"900"`
+	Message   string               `json:"message" example:"Every request made with this token is audited"`
+}
+
+type ListImpersonationsResponse struct {
+	Data []models.Impersonation `json:"data"`
+}
+
+type ImpersonationResponse struct {
+	Data     models.Impersonation          `json:"data"`
+	Requests []models.ImpersonationRequest `json:"requests"`
+}
@@ -0,0 +1,21 @@
+package schemas
+
+import "go-crud/models"
+
+// Input Schemas
+type CreateInviteInput struct {
+	Role          string `json:"role" binding:"omitempty,oneof=reader author editor admin" example:"author"`
+	MaxUses       int    `json:"max_uses" binding:"omitempty,min=1,max=1000" example:"5"`
+	ExpiresInDays *int   `json:"expires_in_days" binding:"omitempty,min=1,max=365" example:"7"`
+}
+
+// Output Schemas
+type InviteCreatedResponse struct {
+	Data    models.Invite `json:"data"`
+	Code    string        `json:"code" example:"hbi_Zk3mP9c1Q2xW..."`
+	Message string        `json:"message" example:"Copy the invite code now, it will not be shown again"`
+}
+
+type ListInvitesResponse struct {
+	Data []models.Invite `json:"data"`
+}
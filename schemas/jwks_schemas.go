@@ -0,0 +1,18 @@
+package schemas
+
+// JWK is the JSON Web Key representation of a public signing key (RFC 7517)
+type JWK struct {
+	KeyType   string `json:"kty" example:"RSA"`
+	KeyID     string `json:"kid" example:"2025-01"`
+	Algorithm string `json:"alg" example:"RS256"`
+	Use       string `json:"use" example:"sig"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty" example:"AQAB"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+}
+
+// Output Schemas
+type JWKSResponse struct {
+	Keys []JWK `json:"keys"`
+}
@@ -0,0 +1,29 @@
+package schemas
+
+// Input Schemas
+type MFACodeInput struct {
+	Code string `json:"code" binding:"required" example:"123456"`
+}
+
+type MFAVerifyInput struct {
+	MFAToken string `json:"mfa_token" binding:"required" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	Code     string `json:"code" binding:"required" example:"123456"`
+}
+
+// Output Schemas
+type TOTPEnrollmentResponse struct {
+	Secret          string `json:"secret" example:"JBSWY3DPEHPK3PXP"`
+	ProvisioningURI string `json:"provisioning_uri" example:"otpauth://totp/Horizon%20Blog:connor@example.com?secret=JBSWY3DPEHPK3PXP&issuer=Horizon+Blog"`
+	Message         string `json:"message" example:"Scan the QR code with your authenticator app, then confirm with a code"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes" example:"k3m9p-2xq7t,8hd4w-n6c2z"`
+	Message       string   `json:"message" example:"Two-factor authentication enabled"`
+}
+
+type MFAChallengeResponse struct {
+	MFARequired bool   `json:"mfa_required" example:"true"`
+	MFAToken    string `json:"mfa_token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	Message     string `json:"message" example:"Two-factor authentication required"`
+}
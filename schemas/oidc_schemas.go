@@ -0,0 +1,16 @@
+package schemas
+
+import "go-crud/models"
+
+// Output Schemas
+type OIDCProvidersResponse struct {
+	Providers []string `json:"providers" example:"google,keycloak"`
+}
+
+type AuthorizationURLResponse struct {
+	AuthorizationURL string `json:"authorization_url" example:"https://accounts.google.com/o/oauth2/v2/auth?client_id=...&state=..."`
+}
+
+type ListIdentitiesResponse struct {
+	Data []models.UserIdentity `json:"data"`
+}
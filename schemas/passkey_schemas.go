@@ -0,0 +1,36 @@
+package schemas
+
+import (
+	"encoding/json"
+	"go-crud/models"
+)
+
+// Input Schemas
+
+// BeginPasskeyRegistrationInput confirms that the account holder is present: a passkey signs in on
+// its own, so adding one needs the current password, or a two-factor code when it is enabled.
+type BeginPasskeyRegistrationInput struct {
+	Password string `json:"password,omitempty" example:"plum-orbit-canvas-42"`
+	Code     string `json:"code,omitempty" example:"123456"`
+}
+
+type FinishPasskeyRegistrationInput struct {
+	Name string `json:"name" binding:"required,max=100" example:"MacBook Touch ID"`
+	// The PublicKeyCredential returned by navigator.credentials.create(), serialized with toJSON()
+	Credential json.RawMessage `json:"credential" binding:"required" swaggertype:"object"`
+}
+
+type FinishPasskeyLoginInput struct {
+	// The PublicKeyCredential returned by navigator.credentials.get(), serialized with toJSON()
+	Credential json.RawMessage `json:"credential" binding:"required" swaggertype:"object"`
+}
+
+// Output Schemas
+type PasskeyResponse struct {
+	Data    models.Passkey `json:"data"`
+	Message string         `json:"message" example:"Passkey registered successfully"`
+}
+
+type ListPasskeysResponse struct {
+	Data []models.Passkey `json:"data"`
+}
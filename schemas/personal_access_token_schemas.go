@@ -0,0 +1,21 @@
+package schemas
+
+import "go-crud/models"
+
+// Input Schemas
+type CreatePersonalAccessTokenInput struct {
+	Name          string   `json:"name" binding:"required,max=100" example:"Publish from CI"`
+	Scopes        []string `json:"scopes" binding:"required,min=1,dive,oneof=read posts:write tags:write" example:"posts:write,read"`
+	ExpiresInDays *int     `json:"expires_in_days" binding:"omitempty,min=1,max=3650" example:"90"`
+}
+
+// Output Schemas
+type PersonalAccessTokenCreatedResponse struct {
+	Data    models.PersonalAccessToken `json:"data"`
+	Token   string                     `json:"token" example:"hbp_Zk3mP9c1Q2xW..."`
+	Message string                     `json:"message" example:"Copy the token now, it will not be shown again"`
+}
+
+type ListPersonalAccessTokensResponse struct {
+	Data []models.PersonalAccessToken `json:"data"`
+}
@@ -0,0 +1,19 @@
+package schemas
+
+import "go-crud/models"
+
+// Input Schemas
+type CreatePostRedirectInput struct {
+	FromSlug string `json:"from_slug" binding:"required,min=1,max=100" example:"my-old-post"`
+	PostID   uint   `json:"post_id" binding:"required" example:"1"`
+}
+
+// Output Schemas
+type PostRedirectResponse struct {
+	Data    models.PostRedirect `json:"data"`
+	Message string              `json:"message,omitempty"`
+}
+
+type ListPostRedirectsResponse struct {
+	Data []models.PostRedirect `json:"data"`
+}
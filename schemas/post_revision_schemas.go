@@ -0,0 +1,35 @@
+package schemas
+
+import "go-crud/models"
+
+// Query Parameters
+type PostRevisionDiffQueryParams struct {
+	From int `form:"from" binding:"required,min=1" example:"1"`
+	To   int `form:"to" binding:"required,min=1" example:"3"`
+}
+
+// Output Schemas
+type ListPostRevisionsResponse struct {
+	Data []models.PostRevision `json:"data"`
+}
+
+type PostRevisionResponse struct {
+	Data models.PostRevision `json:"data"`
+}
+
+// PostRevisionDiffLine is a markdown line of either revision. Lines only in the older revision
+// are "delete", lines only in the newer one "insert"; line numbers start at 1.
+type PostRevisionDiffLine struct {
+	Type      string `json:"type" example:"insert"`
+	OldNumber int    `json:"old_number,omitempty" example:"4"`
+	NewNumber int    `json:"new_number,omitempty" example:"5"`
+	Text      string `json:"text" example:"This is **markdown**"`
+}
+
+type PostRevisionDiffResponse struct {
+	From      int                    `json:"from" example:"1"`
+	To        int                    `json:"to" example:"3"`
+	Additions int                    `json:"additions" example:"2"`
+	Deletions int                    `json:"deletions" example:"1"`
+	Lines     []PostRevisionDiffLine `json:"lines"`
+}
@@ -2,6 +2,7 @@ package schemas
 
 import (
 	"go-crud/models"
+	"time"
 )
 
 // Query Parameters
@@ -9,7 +10,7 @@ type ListPostsQueryParams struct {
 	Page     int                `form:"page" binding:"omitempty,min=0"`
 	Limit    int                `form:"limit" binding:"omitempty,min=0,max=100"`
 	UserID   *uint              `form:"user_id"`
-	Status   *models.PostStatus `form:"status"`
+	Status   *models.PostStatus `form:"status" binding:"omitempty,oneof=draft published scheduled"`
 	TagNames []string           `form:"tags"`
 }
 
@@ -30,9 +31,12 @@ func (q *ListPostsQueryParams) SetDefaults() {
 // Input Schemas
 type CreatePostRequest struct {
 	Title           string             `json:"title" binding:"required,min=1,max=255" example:"My New Post"`
+	Slug            string             `json:"slug,omitempty" binding:"omitempty,max=100" example:"my-new-post"`
 	ContentMarkdown string             `json:"content_markdown" binding:"required,min=1" example:"# My Post\n\nThis is **markdown**"`
 	ContentJSON     string             `json:"content_json" binding:"required,min=1" example:"{\"type\":\"doc\",\"content\":[]}"`
-	Status          *models.PostStatus `json:"status,omitempty" binding:"omitempty,oneof=draft published" example:"draft"`
+	Status          *models.PostStatus `json:"status,omitempty" binding:"omitempty,oneof=draft published scheduled" example:"draft"`
+	PublishAt       *time.Time         `json:"publish_at,omitempty" example:"2030-01-01T09:00:00Z"`
+	UnpublishAt     *time.Time         `json:"unpublish_at,omitempty" example:"2030-02-01T00:00:00Z"`
 	TagNames        []string           `json:"tag_names,omitempty" example:"golang,web-development,tutorial"`
 }
 
@@ -44,8 +48,11 @@ func (r CreatePostRequest) Validate() error {
 func (r CreatePostRequest) ToModel() models.Post {
 	post := models.Post{
 		Title:           r.Title,
+		Slug:            r.Slug,
 		ContentMarkdown: r.ContentMarkdown,
 		ContentJSON:     r.ContentJSON,
+		PublishAt:       r.PublishAt,
+		UnpublishAt:     r.UnpublishAt,
 	}
 
 	// If status is provided, use it; otherwise default to draft
@@ -62,7 +69,9 @@ type UpdatePostRequest struct {
 	Title           string            `json:"title" binding:"required,min=1,max=255" example:"Updated Post Title"`
 	ContentMarkdown string            `json:"content_markdown" binding:"required,min=1" example:"# Updated\n\nMarkdown content"`
 	ContentJSON     string            `json:"content_json" binding:"required,min=1" example:"{\"type\":\"doc\",\"content\":[]}"`
-	Status          models.PostStatus `json:"status" binding:"required,oneof=draft published" example:"published"`
+	Status          models.PostStatus `json:"status" binding:"required,oneof=draft published scheduled" example:"published"`
+	PublishAt       *time.Time        `json:"publish_at,omitempty" example:"2030-01-01T09:00:00Z"`
+	UnpublishAt     *time.Time        `json:"unpublish_at,omitempty" example:"2030-02-01T00:00:00Z"`
 }
 
 // Method for UpdatePostRequest struct
@@ -76,14 +85,19 @@ func (r UpdatePostRequest) ToModel() models.Post {
 		ContentMarkdown: r.ContentMarkdown,
 		ContentJSON:     r.ContentJSON,
 		Status:          r.Status,
+		PublishAt:       r.PublishAt,
+		UnpublishAt:     r.UnpublishAt,
 	}
 }
 
 type PatchPostRequest struct {
 	Title           *string            `json:"title,omitempty" binding:"omitempty,min=1,max=255" example:"Partially Updated Title"`
+	Slug            *string            `json:"slug,omitempty" binding:"omitempty,min=1,max=100" example:"partially-updated-title"`
 	ContentMarkdown *string            `json:"content_markdown,omitempty" binding:"omitempty,min=1" example:"# Updated\n\nPartial markdown"`
 	ContentJSON     *string            `json:"content_json,omitempty" binding:"omitempty,min=1" example:"{\"type\":\"doc\",\"content\":[]}"`
-	Status          *models.PostStatus `json:"status,omitempty" binding:"omitempty,oneof=draft published" example:"published"`
+	Status          *models.PostStatus `json:"status,omitempty" binding:"omitempty,oneof=draft published scheduled" example:"published"`
+	PublishAt       *time.Time         `json:"publish_at,omitempty" example:"2030-01-01T09:00:00Z"`
+	UnpublishAt     *time.Time         `json:"unpublish_at,omitempty" example:"2030-02-01T00:00:00Z"`
 }
 
 // Method for PatchPostRequest struct
@@ -92,7 +106,8 @@ func (r PatchPostRequest) Validate() error {
 }
 
 func (r PatchPostRequest) IsEmpty() bool {
-	return r.Title == nil && r.ContentMarkdown == nil && r.ContentJSON == nil && r.Status == nil
+	return r.Title == nil && r.Slug == nil && r.ContentMarkdown == nil && r.ContentJSON == nil && r.Status == nil &&
+		r.PublishAt == nil && r.UnpublishAt == nil
 }
 
 // Method for PatchPostRequest struct
@@ -101,6 +116,9 @@ func (r PatchPostRequest) ToMap() map[string]interface{} {
 	if r.Title != nil {
 		data["title"] = *r.Title
 	}
+	if r.Slug != nil {
+		data["slug"] = *r.Slug
+	}
 	if r.ContentMarkdown != nil {
 		data["content_markdown"] = *r.ContentMarkdown
 	}
@@ -110,6 +128,12 @@ func (r PatchPostRequest) ToMap() map[string]interface{} {
 	if r.Status != nil {
 		data["status"] = *r.Status
 	}
+	if r.PublishAt != nil {
+		data["publish_at"] = *r.PublishAt
+	}
+	if r.UnpublishAt != nil {
+		data["unpublish_at"] = *r.UnpublishAt
+	}
 	return data
 }
 
@@ -128,6 +152,7 @@ type ListPostsResponse struct {
 
 type ErrorResponse struct {
 	Error string `json:"error"`
+	Code  string `json:"code,omitempty" example:"email_not_verified"`
 }
 
 type MessageResponse struct {
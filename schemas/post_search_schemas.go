@@ -0,0 +1,28 @@
+package schemas
+
+import "go-crud/models"
+
+// Query Parameters
+type SearchPostsQueryParams struct {
+	Query string `form:"q" binding:"required,max=200"`
+	ListPostsQueryParams
+}
+
+// Output Schemas
+
+// PostSearchResult is a post matching a search. TitleHighlight and Snippet are HTML: the post's
+// text, markdown included, is escaped and matched words are wrapped in <mark></mark>.
+type PostSearchResult struct {
+	Post           models.Post `json:"post"`
+	Rank           float64     `json:"rank" example:"0.6079271"`
+	TitleHighlight string      `json:"title_highlight" example:"Getting started with <mark>Go</mark>"`
+	Snippet        string      `json:"snippet" example:"... install <mark>Go</mark> and write your first program ..."`
+}
+
+type SearchPostsResponse struct {
+	Data  []PostSearchResult `json:"data"`
+	Query string             `json:"query" example:"\"getting started\" go*"`
+	Limit int                `json:"limit"`
+	Page  int                `json:"page"`
+	Total int                `json:"total"`
+}
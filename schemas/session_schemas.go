@@ -0,0 +1,13 @@
+package schemas
+
+import "go-crud/models"
+
+// Output Schemas
+type SessionInfo struct {
+	models.Session
+	Current bool `json:"current" example:"true"`
+}
+
+type ListSessionsResponse struct {
+	Data []SessionInfo `json:"data"`
+}
@@ -3,18 +3,60 @@ package schemas
 import "go-crud/models"
 
 type CreateUserInput struct {
-	Name     string `json:"name" binding:"required" example:"Connor Tran"`
-	Email    string `json:"email" binding:"required,email" example:"connor@example.com"`
-	Password string `json:"password" binding:"required" example:"abcxyz123"`
+	Name       string `json:"name" binding:"required" example:"Connor Tran"`
+	Email      string `json:"email" binding:"required,email" example:"connor@example.com"`
+	Password   string `json:"password" binding:"required" example:"plum-orbit-canvas-42"`
+	InviteCode string `json:"invite_code" example:"hbi_Zk3mP9c1..."`
 }
 
 type PartialUpdateUserInput struct {
-	Name     *string `json:"name" binding:"omitempty,min=3" example:"Connor Tran"`
-	Email    *string `json:"email" binding:"omitempty,email" example:"connor@example.com"`
-	Password *string `json:"password" example:"abcxyz123"`
+	Name *string `json:"name" binding:"omitempty,min=3" example:"Connor Tran"`
+
+	// Credentials moved to the re-authenticated endpoints. They are only
+	// decoded to reject requests that still send them.
+	Email    *string `json:"email" swaggerignore:"true"`
+	Password *string `json:"password" swaggerignore:"true"`
+}
+
+type ChangePasswordInput struct {
+	CurrentPassword string `json:"current_password" binding:"required" example:"plum-orbit-canvas-42"`
+	NewPassword     string `json:"new_password" binding:"required" example:"n3w-s3cret-pass"`
+}
+
+type ChangeEmailInput struct {
+	Password string `json:"password" binding:"required" example:"plum-orbit-canvas-42"`
+	NewEmail string `json:"new_email" binding:"required,email" example:"connor@example.org"`
+}
+
+type UpdateRoleInput struct {
+	Role string `json:"role" binding:"required,oneof=reader author editor admin" example:"editor"`
 }
 
 type UserResponse struct {
 	Data    models.User `json:"data"`
 	Message string      `json:"message" example:"User created successfully"`
 }
+
+// PasswordViolation is one password policy rule a password breaks
+type PasswordViolation struct {
+	Code    string `json:"code" example:"too_short"`
+	Message string `json:"message" example:"must be at least 8 characters long"`
+}
+
+type PasswordPolicyErrorResponse struct {
+	Error      string              `json:"error" example:"Password does not meet the password policy"`
+	Code       string              `json:"code" example:"weak_password"`
+	Violations []PasswordViolation `json:"violations"`
+}
+
+type DeleteUserInput struct {
+	// What happens to the user's posts when the account is purged. Defaults to ACCOUNT_DELETION_POSTS;
+	// transfer is limited to admins.
+	Posts        string `json:"posts" binding:"omitempty,oneof=delete reassign transfer" example:"reassign"`
+	TransferToID *uint  `json:"transfer_to_id" example:"2"`
+}
+
+type AccountDeletionResponse struct {
+	Data    models.AccountDeletion `json:"data"`
+	Message string                 `json:"message,omitempty" example:"Account scheduled for deletion"`
+}
@@ -0,0 +1,232 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"go-crud/initializers"
+	"go-crud/models"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DeletedUserEmail is the address of the placeholder account that posts of purged accounts are
+// reassigned to. The account is found by its IsPlaceholder flag; the address is reserved so that
+// nobody else can sign up or switch to it.
+const DeletedUserEmail = "deleted-user@deleted.invalid"
+
+var (
+	ErrAccountDeletionNotFound  = errors.New("account deletion not found")
+	ErrAccountDeletionScheduled = errors.New("account deletion is already scheduled")
+	ErrInvalidPostsPolicy       = errors.New("invalid posts policy")
+	ErrInvalidTransferTarget    = errors.New("posts can only be transferred to another author")
+	ErrDeletedUserPlaceholder   = errors.New("the deleted user placeholder cannot be deleted")
+)
+
+// AccountDeletionGracePeriod returns how long a scheduled deletion can still be cancelled
+// (ACCOUNT_DELETION_GRACE_PERIOD, default 14 days)
+func AccountDeletionGracePeriod() time.Duration {
+	return durationFromEnv("ACCOUNT_DELETION_GRACE_PERIOD", 14*24*time.Hour)
+}
+
+// DefaultDeletedPostsPolicy returns what happens to the posts of a deleted account when the request
+// does not say (ACCOUNT_DELETION_POSTS, delete or reassign, default reassign). Transfers need a
+// target author, so they are always asked for explicitly. Any other value is logged and ignored.
+func DefaultDeletedPostsPolicy() models.DeletedPostsPolicy {
+	value := strings.ToLower(strings.TrimSpace(os.Getenv("ACCOUNT_DELETION_POSTS")))
+	switch policy := models.DeletedPostsPolicy(value); policy {
+	case models.DeletePosts, models.ReassignPosts:
+		return policy
+	case "":
+		return models.ReassignPosts
+	}
+	log.Printf("[DELETION] ACCOUNT_DELETION_POSTS=%q is not delete or reassign, reassigning posts", value)
+	return models.ReassignPosts
+}
+
+// AccountDeletionService schedules account deletions and purges accounts once their grace period is over
+type AccountDeletionService struct {
+	db *gorm.DB
+}
+
+// NewAccountDeletionService creates a new AccountDeletionService instance
+func NewAccountDeletionService() *AccountDeletionService {
+	return &AccountDeletionService{
+		db: initializers.DB,
+	}
+}
+
+// Schedule marks the account for deletion after the grace period. transferToID is only used, and
+// required, with the transfer policy.
+func (s *AccountDeletionService) Schedule(userID, requestedByID uint, policy models.DeletedPostsPolicy, transferToID *uint) (*models.AccountDeletion, error) {
+	if policy == "" {
+		policy = DefaultDeletedPostsPolicy()
+	}
+	if !policy.IsValid() {
+		return nil, ErrInvalidPostsPolicy
+	}
+
+	user, err := NewUserService().GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.IsPlaceholder {
+		return nil, ErrDeletedUserPlaceholder
+	}
+
+	if policy == models.TransferPosts {
+		if transferToID == nil || *transferToID == userID {
+			return nil, ErrInvalidTransferTarget
+		}
+		target, err := NewUserService().GetByID(*transferToID)
+		if err != nil || target.IsPlaceholder || !HasPermission(target.Role, PermPostsCreate) {
+			return nil, ErrInvalidTransferTarget
+		}
+	} else {
+		transferToID = nil
+	}
+
+	deletion := models.AccountDeletion{
+		UserID:        userID,
+		RequestedByID: requestedByID,
+		PostsPolicy:   policy,
+		TransferToID:  transferToID,
+		PurgeAt:       time.Now().Add(AccountDeletionGracePeriod()),
+	}
+	result := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&deletion)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrAccountDeletionScheduled
+	}
+
+	// The deletion stands even if the email cannot be sent
+	if err := sendAccountDeletionNotice(*user, deletion.PurgeAt); err != nil {
+		log.Printf("[MAILER] Failed to send account deletion notice to user %d: %v", user.ID, err)
+	}
+
+	return &deletion, nil
+}
+
+// Get returns the pending deletion of the account
+func (s *AccountDeletionService) Get(userID uint) (*models.AccountDeletion, error) {
+	var deletion models.AccountDeletion
+	result := s.db.Where("user_id = ?", userID).First(&deletion)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, ErrAccountDeletionNotFound
+		}
+		return nil, result.Error
+	}
+	return &deletion, nil
+}
+
+// Cancel keeps the account. It fails once the account has been purged.
+func (s *AccountDeletionService) Cancel(userID uint) error {
+	result := s.db.Where("user_id = ?", userID).Delete(&models.AccountDeletion{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrAccountDeletionNotFound
+	}
+	return nil
+}
+
+// PurgeDue purges every account whose grace period is over
+func (s *AccountDeletionService) PurgeDue() error {
+	var userIDs []uint
+	if err := s.db.Model(&models.AccountDeletion{}).
+		Where("purge_at <= ?", time.Now()).
+		Order("purge_at ASC").
+		Pluck("user_id", &userIDs).Error; err != nil {
+		return err
+	}
+
+	for _, userID := range userIDs {
+		if err := s.Purge(userID); err != nil {
+			log.Printf("[DELETION] Failed to purge user %d: %v", userID, err)
+		}
+	}
+	return nil
+}
+
+// Purge applies the posts policy of a due deletion and deletes the account. Deletions that were
+// cancelled, are not due yet or are being purged by another process are left alone.
+func (s *AccountDeletionService) Purge(userID uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var deletion models.AccountDeletion
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("user_id = ? AND purge_at <= ?", userID, time.Now()).
+			Limit(1).
+			Find(&deletion)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		switch {
+		case deletion.PostsPolicy == models.DeletePosts:
+			if err := tx.Where("user_id = ?", userID).Delete(&models.Post{}).Error; err != nil {
+				return err
+			}
+		case deletion.PostsPolicy == models.TransferPosts && deletion.TransferToID != nil:
+			if err := tx.Model(&models.Post{}).Where("user_id = ?", userID).
+				Update("user_id", *deletion.TransferToID).Error; err != nil {
+				return err
+			}
+		default:
+			// Reassign, which is also where transfers end up when their target was deleted meanwhile
+			placeholder, err := deletedUserPlaceholder(tx)
+			if err != nil {
+				return err
+			}
+			if err := tx.Model(&models.Post{}).Where("user_id = ?", userID).
+				Update("user_id", placeholder.ID).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Delete(&models.User{}, userID).Error
+	})
+}
+
+// deletedUserPlaceholder returns the account that stands in as the author of reassigned posts,
+// creating it on first use. It has no password and cannot sign in.
+func deletedUserPlaceholder(tx *gorm.DB) (*models.User, error) {
+	placeholder := models.User{
+		Name:          "Deleted user",
+		Email:         DeletedUserEmail,
+		Role:          models.RoleReader,
+		IsPlaceholder: true,
+	}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&placeholder).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Where("is_placeholder = ?", true).First(&placeholder).Error; err != nil {
+		return nil, err
+	}
+	return &placeholder, nil
+}
+
+// isReservedEmail reports whether an address is the placeholder's, which no account may take
+func isReservedEmail(email string) bool {
+	return strings.EqualFold(strings.TrimSpace(email), DeletedUserEmail)
+}
+
+func sendAccountDeletionNotice(user models.User, purgeAt time.Time) error {
+	body := fmt.Sprintf("Hi %s,\n\n"+
+		"Your account is scheduled to be deleted on %s. Until then you can sign in and cancel the "+
+		"deletion from your account settings: %s\n\n"+
+		"If you did not ask for this, cancel the deletion and reset your password right away.\n",
+		user.Name, purgeAt.Format("January 2, 2006 15:04 MST"), frontendURL())
+
+	return sendEmail(user.Email, "Your account is scheduled for deletion", body)
+}
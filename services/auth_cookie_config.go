@@ -0,0 +1,49 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// AuthCookiesEnabled reports whether clients may opt in to receiving their tokens as cookies
+// (AUTH_COOKIES_ENABLED, default false)
+func AuthCookiesEnabled() bool {
+	return boolFromEnv("AUTH_COOKIES_ENABLED", false)
+}
+
+// AuthCookieSecure reports whether auth cookies are only sent over HTTPS (AUTH_COOKIE_SECURE, default true).
+// Only turn it off for local development over plain HTTP.
+func AuthCookieSecure() bool {
+	return boolFromEnv("AUTH_COOKIE_SECURE", true)
+}
+
+// AuthCookieSameSite returns the SameSite attribute of auth cookies (AUTH_COOKIE_SAMESITE: lax, strict
+// or none, default lax). "none" is needed when the frontend is served from another site than the API.
+func AuthCookieSameSite() http.SameSite {
+	switch strings.ToLower(os.Getenv("AUTH_COOKIE_SAMESITE")) {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteLaxMode
+	}
+}
+
+// AuthCookieDomain returns the Domain attribute of auth cookies (AUTH_COOKIE_DOMAIN). When empty the
+// cookies are host-only.
+func AuthCookieDomain() string {
+	return os.Getenv("AUTH_COOKIE_DOMAIN")
+}
+
+// NewCSRFToken returns a random token for the double-submit CSRF cookie
+func NewCSRFToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
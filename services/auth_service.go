@@ -1,75 +1,216 @@
 package services
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"go-crud/models"
 	"os"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
-	"golang.org/x/crypto/bcrypt"
 )
 
-func HashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
-		return "This password is invalid, please try a new one", err
-	}
-	return string(bytes), nil
+// TokenPair is the set of credentials handed out after a successful authentication
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    int64
+}
+
+// AccessTokenTTL returns how long an access token stays valid (ACCESS_TOKEN_TTL, default 15 minutes)
+func AccessTokenTTL() time.Duration {
+	return durationFromEnv("ACCESS_TOKEN_TTL", 15*time.Minute)
 }
 
-func CheckHashedPassword(password, hash string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
-	return err == nil
+// AccessClaims are the claims carried by an access token
+type AccessClaims struct {
+	UserID uint        `json:"user_id"`
+	Email  string      `json:"email"`
+	Name   string      `json:"name"`
+	Role   models.Role `json:"role"`
+	// SessionID ties the token to the login it came from, so signing that session out revokes it
+	SessionID uint `json:"sid,omitempty"`
+	// ImpersonatorID is the admin acting as the user, set on impersonation tokens only
+	ImpersonatorID uint `json:"imp,omitempty"`
+	// IssuedAtMicros is iat to the microsecond, so that a user-wide revocation in the same second
+	// as the token is still told apart from it
+	IssuedAtMicros int64 `json:"iat_us,omitempty"`
+	jwt.RegisteredClaims
 }
 
 // GenerateToken generates a JWT token for a user
 func GenerateToken(user models.User) (string, error) {
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		return "", errors.New("JWT_SECRET not configured")
+	return generateSessionToken(user, 0)
+}
+
+// generateSessionToken generates a JWT token for a user bound to one of their sessions
+func generateSessionToken(user models.User, sessionID uint) (string, error) {
+	jti, err := newTokenID()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims := AccessClaims{
+		UserID:         user.ID,
+		Email:          user.Email,
+		Name:           user.Name,
+		Role:           user.Role,
+		SessionID:      sessionID,
+		IssuedAtMicros: now.UnixMicro(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			Issuer:    TokenIssuer(),
+			Audience:  jwt.ClaimStrings{TokenAudience()},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(AccessTokenTTL())),
+		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id": user.ID,
-		"email":   user.Email,
-		"name":    user.Name,
-		"exp":     time.Now().Add(time.Hour * 24).Unix(), // Token expires in 24 hours
-	})
+	return signToken(claims)
+}
 
-	tokenString, err := token.SignedString([]byte(jwtSecret))
+// IssueTokenPair starts a new session on the device: a refresh token family plus an access token bound to it
+func IssueTokenPair(user models.User, device Device) (*TokenPair, error) {
+	refreshToken, familyID, err := NewRefreshTokenService().Issue(user.ID)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	session, err := NewSessionService().Start(user, familyID, device)
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, err := generateSessionToken(user, session.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(AccessTokenTTL().Seconds()),
+	}, nil
+}
+
+// RefreshTokenPair rotates a refresh token and returns fresh credentials for its owner
+func RefreshTokenPair(refreshToken string) (*models.User, *TokenPair, error) {
+	newRefreshToken, consumed, err := NewRefreshTokenService().Rotate(refreshToken)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	user, err := NewUserService().GetByID(consumed.UserID)
+	if err != nil {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+
+	// Families issued before sessions were tracked have none; their tokens stay unbound
+	var sessionID uint
+	session, err := NewSessionService().ForFamily(consumed.FamilyID)
+	switch {
+	case err == nil:
+		sessionID = session.ID
+	case !errors.Is(err, ErrSessionNotFound):
+		return nil, nil, err
 	}
 
-	return tokenString, nil
+	accessToken, err := generateSessionToken(*user, sessionID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return user, &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: newRefreshToken,
+		ExpiresIn:    int64(AccessTokenTTL().Seconds()),
+	}, nil
+}
+
+// ParseToken validates a JWT token and returns its claims
+func ParseToken(tokenString string) (*AccessClaims, error) {
+	claims := &AccessClaims{}
+	if err := parseToken(tokenString, TokenAudience(), claims); err != nil {
+		return nil, err
+	}
+	if claims.UserID == 0 {
+		return nil, errors.New("user_id claim is missing")
+	}
+	return claims, nil
 }
 
 // ValidateToken validates a JWT token and returns the user ID
 func ValidateToken(tokenString string) (uint, error) {
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		return 0, errors.New("JWT_SECRET not configured")
+	claims, err := ParseToken(tokenString)
+	if err != nil {
+		return 0, err
+	}
+	return claims.UserID, nil
+}
+
+// TokenIssuer returns the iss claim of tokens minted by this API (JWT_ISSUER, default "go-crud-api")
+func TokenIssuer() string {
+	issuer := os.Getenv("JWT_ISSUER")
+	if issuer == "" {
+		issuer = "go-crud-api"
+	}
+	return issuer
+}
+
+// TokenAudience returns the aud claim of access tokens (JWT_AUDIENCE, defaults to the issuer)
+func TokenAudience() string {
+	audience := os.Getenv("JWT_AUDIENCE")
+	if audience == "" {
+		audience = TokenIssuer()
 	}
+	return audience
+}
 
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
-		}
-		return []byte(jwtSecret), nil
-	})
+// TokenClockSkew returns the leeway allowed on exp, nbf and iat (JWT_CLOCK_SKEW, default 30 seconds)
+func TokenClockSkew() time.Duration {
+	return durationFromEnv("JWT_CLOCK_SKEW", 30*time.Second)
+}
 
+// signToken signs the given claims with the active key of the key ring
+func signToken(claims jwt.Claims) (string, error) {
+	ring, err := CurrentKeyRing()
 	if err != nil {
-		return 0, err
+		return "", err
+	}
+	return ring.sign(claims)
+}
+
+// parseToken verifies a token signed by signToken for the given audience and decodes it into claims
+func parseToken(tokenString, audience string, claims jwt.Claims) error {
+	ring, err := CurrentKeyRing()
+	if err != nil {
+		return err
 	}
 
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		userID, ok := claims["user_id"].(float64)
-		if !ok {
-			return 0, errors.New("user_id claim is not a number")
-		}
-		return uint(userID), nil
+	token, err := jwt.ParseWithClaims(tokenString, claims, ring.keyFunc,
+		jwt.WithValidMethods(ring.validMethods()),
+		jwt.WithIssuer(TokenIssuer()),
+		jwt.WithAudience(audience),
+		jwt.WithLeeway(TokenClockSkew()),
+		jwt.WithIssuedAt(),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return err
+	}
+	if !token.Valid {
+		return errors.New("invalid token")
 	}
+	return nil
+}
 
-	return 0, errors.New("invalid token")
+// newTokenID returns a random identifier used as the jti claim
+func newTokenID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
 }
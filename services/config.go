@@ -0,0 +1,52 @@
+package services
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+// durationFromEnv reads a time.Duration (e.g. "15m", "720h") from the environment,
+// falling back to the given default when the variable is unset or invalid
+func durationFromEnv(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		return fallback
+	}
+	return duration
+}
+
+// intFromEnv reads an integer from the environment, falling back to the given
+// default when the variable is unset or invalid
+func intFromEnv(key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return fallback
+	}
+	return number
+}
+
+// boolFromEnv reads a boolean ("true", "1", ...) from the environment,
+// falling back to the given default when the variable is unset or invalid
+func boolFromEnv(key string, fallback bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return fallback
+	}
+	return parsed
+}
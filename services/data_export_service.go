@@ -0,0 +1,325 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-crud/initializers"
+	"go-crud/models"
+	"log"
+	"net/url"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const dataExportPurpose = "data_export"
+
+var (
+	ErrDataExportNotFound = errors.New("data export not found")
+	ErrDataExportNotReady = errors.New("data export is not ready")
+	// Exports stuck in processing this long are assumed to have died with their process and are retried
+	dataExportStaleAfter = 30 * time.Minute
+)
+
+// DataExportRetention returns how long a built archive is kept (DATA_EXPORT_RETENTION, default 7 days)
+func DataExportRetention() time.Duration {
+	return durationFromEnv("DATA_EXPORT_RETENTION", 7*24*time.Hour)
+}
+
+// DataExportURLTTL returns how long a signed download URL stays valid (DATA_EXPORT_URL_TTL, default 15 minutes)
+func DataExportURLTTL() time.Duration {
+	return durationFromEnv("DATA_EXPORT_URL_TTL", 15*time.Minute)
+}
+
+// DataExportService builds archives of everything the API stores about a user
+type DataExportService struct {
+	db *gorm.DB
+}
+
+// NewDataExportService creates a new DataExportService instance
+func NewDataExportService() *DataExportService {
+	return &DataExportService{
+		db: initializers.DB,
+	}
+}
+
+// Request queues an export of the user's data. While an export is still being built, it is
+// returned instead of queueing another one.
+func (s *DataExportService) Request(userID uint) (*models.DataExport, error) {
+	var export models.DataExport
+	result := s.db.Where("user_id = ? AND status IN ?", userID,
+		[]models.DataExportStatus{models.DataExportPending, models.DataExportProcessing}).
+		Order("created_at DESC").
+		First(&export)
+	if result.Error == nil {
+		return &export, nil
+	}
+	if !errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, result.Error
+	}
+
+	export = models.DataExport{
+		UserID: userID,
+		Status: models.DataExportPending,
+	}
+	if err := s.db.Create(&export).Error; err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+// Get returns one of the user's exports
+func (s *DataExportService) Get(userID, id uint) (*models.DataExport, error) {
+	var export models.DataExport
+	result := s.db.Omit("archive").Where("id = ? AND user_id = ?", id, userID).First(&export)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, ErrDataExportNotFound
+		}
+		return nil, result.Error
+	}
+	return &export, nil
+}
+
+// Process builds the archive of a pending export. It does nothing if another worker
+// already claimed the export.
+func (s *DataExportService) Process(id uint) error {
+	now := time.Now()
+	claimed := s.db.Model(&models.DataExport{}).
+		Where("id = ? AND status = ?", id, models.DataExportPending).
+		Updates(map[string]interface{}{"status": models.DataExportProcessing, "started_at": now})
+	if claimed.Error != nil {
+		return claimed.Error
+	}
+	if claimed.RowsAffected == 0 {
+		return nil
+	}
+
+	var export models.DataExport
+	if err := s.db.Omit("archive").First(&export, id).Error; err != nil {
+		return err
+	}
+
+	archive, err := s.buildArchive(export.UserID)
+	if err != nil {
+		s.db.Model(&export).Updates(map[string]interface{}{
+			"status": models.DataExportFailed,
+			"error":  err.Error(),
+		})
+		return err
+	}
+
+	completedAt := time.Now()
+	if err := s.db.Model(&export).Updates(map[string]interface{}{
+		"status":       models.DataExportReady,
+		"archive":      archive,
+		"size":         len(archive),
+		"completed_at": completedAt,
+		"expires_at":   completedAt.Add(DataExportRetention()),
+	}).Error; err != nil {
+		return err
+	}
+
+	s.notify(export.UserID, completedAt.Add(DataExportRetention()))
+	return nil
+}
+
+// ProcessPending builds the exports that are waiting, including ones whose worker died
+func (s *DataExportService) ProcessPending() error {
+	if err := s.db.Model(&models.DataExport{}).
+		Where("status = ? AND started_at < ?", models.DataExportProcessing, time.Now().Add(-dataExportStaleAfter)).
+		Update("status", models.DataExportPending).Error; err != nil {
+		return err
+	}
+
+	var ids []uint
+	if err := s.db.Model(&models.DataExport{}).
+		Where("status = ?", models.DataExportPending).
+		Order("created_at ASC").
+		Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if err := s.Process(id); err != nil {
+			log.Printf("[EXPORT] Failed to build data export %d: %v", id, err)
+		}
+	}
+	return nil
+}
+
+// PurgeExpired deletes exports whose archive is past its retention
+func (s *DataExportService) PurgeExpired() error {
+	return s.db.Where("expires_at < ?", time.Now()).Delete(&models.DataExport{}).Error
+}
+
+// DownloadURL returns a signed, expiring URL to download a ready export, relative to the API
+func (s *DataExportService) DownloadURL(export models.DataExport) (string, time.Time, error) {
+	if export.Status != models.DataExportReady {
+		return "", time.Time{}, ErrDataExportNotReady
+	}
+
+	expiresAt := time.Now().Add(DataExportURLTTL())
+	if export.ExpiresAt != nil && export.ExpiresAt.Before(expiresAt) {
+		expiresAt = *export.ExpiresAt
+	}
+
+	token, err := signPurposeToken(dataExportPurpose, export.UserID, strconv.FormatUint(uint64(export.ID), 10), time.Until(expiresAt))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return "/exports/download?token=" + url.QueryEscape(token), expiresAt, nil
+}
+
+// Download returns the export a signed download URL points to, with its archive
+func (s *DataExportService) Download(token string) (*models.DataExport, error) {
+	userID, data, err := parsePurposeToken(token, dataExportPurpose)
+	if err != nil {
+		return nil, err
+	}
+	id, err := strconv.ParseUint(data, 10, 32)
+	if err != nil {
+		return nil, ErrInvalidSignedToken
+	}
+
+	var export models.DataExport
+	result := s.db.Where("id = ? AND user_id = ? AND status = ? AND expires_at > ?",
+		id, userID, models.DataExportReady, time.Now()).First(&export)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, ErrDataExportNotFound
+		}
+		return nil, result.Error
+	}
+	return &export, nil
+}
+
+// exportedPostTag is a tag association of one of the user's posts
+type exportedPostTag struct {
+	PostID    uint      `json:"post_id"`
+	TagID     uint      `json:"tag_id"`
+	Tag       string    `json:"tag"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// buildArchive collects the user's data into a zip archive. Secrets (password hashes, TOTP secrets,
+// token hashes, passkey keys) are left out by the models' JSON tags.
+func (s *DataExportService) buildArchive(userID uint) ([]byte, error) {
+	var user models.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+
+	var posts []models.Post
+	if err := s.db.Preload("Tags").Where("user_id = ?", userID).Order("created_at ASC").Find(&posts).Error; err != nil {
+		return nil, err
+	}
+
+	var postTags []exportedPostTag
+	if err := s.db.Table("post_tags").
+		Select("post_tags.post_id, post_tags.tag_id, tags.name AS tag, post_tags.created_at").
+		Joins("JOIN tags ON tags.id = post_tags.tag_id").
+		Joins("JOIN posts ON posts.id = post_tags.post_id").
+		Where("posts.user_id = ?", userID).
+		Order("post_tags.post_id, post_tags.tag_id").
+		Scan(&postTags).Error; err != nil {
+		return nil, err
+	}
+
+	var identities []models.UserIdentity
+	var sessions []models.Session
+	var passkeys []models.Passkey
+	var tokens []models.PersonalAccessToken
+	var events []models.SecurityEvent
+	var invites []models.Invite
+	var revisions []models.PostRevision
+	for _, query := range []struct {
+		dest   interface{}
+		column string
+	}{
+		{&identities, "user_id"},
+		{&sessions, "user_id"},
+		{&passkeys, "user_id"},
+		{&tokens, "user_id"},
+		{&events, "user_id"},
+		{&invites, "created_by_id"},
+		{&revisions, "author_id"},
+	} {
+		if err := s.db.Where(query.column+" = ?", userID).Order("created_at ASC").Find(query.dest).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	var buffer bytes.Buffer
+	archive := zip.NewWriter(&buffer)
+
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", user},
+		{"posts.json", posts},
+		{"post_tags.json", postTags},
+		{"post_revisions.json", revisions},
+		{"identities.json", identities},
+		{"sessions.json", sessions},
+		{"passkeys.json", passkeys},
+		{"personal_access_tokens.json", tokens},
+		{"security_events.json", events},
+		{"invites.json", invites},
+	}
+	for _, file := range files {
+		content, err := json.MarshalIndent(file.data, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		if err := writeArchiveFile(archive, file.name, content); err != nil {
+			return nil, err
+		}
+	}
+
+	// Each post is also included on its own, as it was written
+	for _, post := range posts {
+		if err := writeArchiveFile(archive, fmt.Sprintf("posts/%d.md", post.ID), []byte(post.ContentMarkdown)); err != nil {
+			return nil, err
+		}
+		if err := writeArchiveFile(archive, fmt.Sprintf("posts/%d.json", post.ID), []byte(post.ContentJSON)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func writeArchiveFile(archive *zip.Writer, name string, content []byte) error {
+	file, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(content)
+	return err
+}
+
+// notify lets the user know their export can be downloaded. Failures are only logged: the
+// export is ready either way.
+func (s *DataExportService) notify(userID uint, expiresAt time.Time) {
+	user, err := NewUserService().GetByID(userID)
+	if err != nil {
+		return
+	}
+
+	body := fmt.Sprintf("Hi %s,\n\n"+
+		"The copy of your data you asked for is ready. Sign in and download it from your account "+
+		"settings before %s, after which it is deleted.\n",
+		user.Name, expiresAt.Format("January 2, 2006 15:04 MST"))
+	if err := sendEmail(user.Email, "Your data export is ready", body); err != nil {
+		log.Printf("[MAILER] Failed to send data export email to user %d: %v", user.ID, err)
+	}
+}
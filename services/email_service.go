@@ -0,0 +1,31 @@
+package services
+
+import (
+	"errors"
+	"go-crud/initializers"
+	"go-crud/mailer"
+	"os"
+	"strings"
+)
+
+// frontendURL returns the base URL of the web frontend used to build links in emails
+func frontendURL() string {
+	url := os.Getenv("FRONTEND_URL")
+	if url == "" {
+		url = "http://localhost:5173"
+	}
+	return strings.TrimRight(url, "/")
+}
+
+// sendEmail delivers a plain-text email through the configured mailer
+func sendEmail(to, subject, body string) error {
+	if initializers.Mailer == nil {
+		return errors.New("mailer not configured")
+	}
+
+	return initializers.Mailer.Send(mailer.Message{
+		To:      to,
+		Subject: subject,
+		Body:    body,
+	})
+}
@@ -0,0 +1,158 @@
+package services
+
+import (
+	"errors"
+	"go-crud/initializers"
+	"go-crud/models"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrCannotImpersonate       = errors.New("this user cannot be impersonated")
+	ErrImpersonationNotFound   = errors.New("impersonation not found")
+	ErrNestedImpersonation     = errors.New("impersonation tokens cannot start another impersonation")
+	errImpersonationNotAudited = errors.New("impersonation token has no audit record")
+)
+
+// ImpersonationTTL returns how long an impersonation token stays valid (IMPERSONATION_TTL, default
+// 15 minutes). It is capped at ACCESS_TOKEN_TTL, so that user-wide revocations always cover it.
+func ImpersonationTTL() time.Duration {
+	ttl := durationFromEnv("IMPERSONATION_TTL", 15*time.Minute)
+	if ttl > AccessTokenTTL() {
+		return AccessTokenTTL()
+	}
+	return ttl
+}
+
+// ImpersonationService issues impersonation tokens and keeps their audit trail
+type ImpersonationService struct {
+	db *gorm.DB
+}
+
+// NewImpersonationService creates a new ImpersonationService instance
+func NewImpersonationService() *ImpersonationService {
+	return &ImpersonationService{
+		db: initializers.DB,
+	}
+}
+
+// Start issues an access token that acts as the user on behalf of the admin. Admins cannot be
+// impersonated, and an impersonation token cannot be used to start another one.
+func (s *ImpersonationService) Start(admin *AccessClaims, userID uint, reason string, device Device) (*models.Impersonation, string, error) {
+	if admin.ImpersonatorID != 0 {
+		return nil, "", ErrNestedImpersonation
+	}
+	if admin.UserID == userID {
+		return nil, "", ErrCannotImpersonate
+	}
+
+	user, err := NewUserService().GetByID(userID)
+	if err != nil {
+		return nil, "", err
+	}
+	if user.Role == models.RoleAdmin || user.IsPlaceholder {
+		return nil, "", ErrCannotImpersonate
+	}
+
+	jti, err := newTokenID()
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(ImpersonationTTL())
+	impersonation := models.Impersonation{
+		AdminID:   &admin.UserID,
+		UserID:    &user.ID,
+		Reason:    reason,
+		TokenID:   jti,
+		IP:        device.IP,
+		UserAgent: truncate(device.UserAgent, 512),
+		ExpiresAt: expiresAt,
+	}
+	if err := s.db.Create(&impersonation).Error; err != nil {
+		return nil, "", err
+	}
+
+	// Not bound to a session, and never paired with a refresh token
+	token, err := signToken(AccessClaims{
+		UserID:         user.ID,
+		Email:          user.Email,
+		Name:           user.Name,
+		Role:           user.Role,
+		ImpersonatorID: admin.UserID,
+		IssuedAtMicros: now.UnixMicro(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			Issuer:    TokenIssuer(),
+			Audience:  jwt.ClaimStrings{TokenAudience()},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return &impersonation, token, nil
+}
+
+// RecordRequest appends a request made with an impersonation token to its audit trail, before it
+// is handled. Its status is filled in by CompleteRequest.
+func (s *ImpersonationService) RecordRequest(claims *AccessClaims, request models.ImpersonationRequest) (*models.ImpersonationRequest, error) {
+	var impersonation models.Impersonation
+	result := s.db.Where("token_id = ?", claims.ID).First(&impersonation)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, errImpersonationNotAudited
+		}
+		return nil, result.Error
+	}
+
+	request.ImpersonationID = impersonation.ID
+	request.Path = truncate(request.Path, 2048)
+	request.UserAgent = truncate(request.UserAgent, 512)
+	if err := s.db.Create(&request).Error; err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+// CompleteRequest records the status a request made with an impersonation token was answered with
+func (s *ImpersonationService) CompleteRequest(request *models.ImpersonationRequest, status int) error {
+	return s.db.Model(request).Update("status", status).Error
+}
+
+// List returns impersonations, most recent first, optionally only those of one user
+func (s *ImpersonationService) List(userID *uint) ([]models.Impersonation, error) {
+	query := s.db.Order("created_at DESC")
+	if userID != nil {
+		query = query.Where("user_id = ?", *userID)
+	}
+
+	var impersonations []models.Impersonation
+	if err := query.Find(&impersonations).Error; err != nil {
+		return nil, err
+	}
+	return impersonations, nil
+}
+
+// Get returns an impersonation with the requests made with its token, in order
+func (s *ImpersonationService) Get(id uint) (*models.Impersonation, []models.ImpersonationRequest, error) {
+	var impersonation models.Impersonation
+	result := s.db.First(&impersonation, id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrImpersonationNotFound
+		}
+		return nil, nil, result.Error
+	}
+
+	var requests []models.ImpersonationRequest
+	if err := s.db.Where("impersonation_id = ?", id).Order("created_at ASC, id ASC").Find(&requests).Error; err != nil {
+		return nil, nil, err
+	}
+	return &impersonation, requests, nil
+}
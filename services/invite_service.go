@@ -0,0 +1,196 @@
+package services
+
+import (
+	"errors"
+	"go-crud/initializers"
+	"go-crud/models"
+	"os"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// InviteCodePrefix marks invite codes so they can be told apart from other secrets
+const InviteCodePrefix = "hbi_"
+
+// Registration modes, as accepted by REGISTRATION_MODE
+const (
+	RegistrationOpen            = "open"
+	RegistrationClosed          = "closed"
+	RegistrationInviteOnly      = "invite_only"
+	RegistrationDomainAllowlist = "domain_allowlist"
+)
+
+var (
+	ErrRegistrationClosed    = errors.New("registration is closed")
+	ErrInviteRequired        = errors.New("an invite is required to register")
+	ErrEmailDomainNotAllowed = errors.New("email domain is not allowed to register")
+	ErrInvalidInvite         = errors.New("invalid, expired or used up invite")
+	ErrInviteNotFound        = errors.New("invite not found")
+)
+
+// RegistrationMode returns who may create an account (REGISTRATION_MODE, default open):
+//
+//	open              anyone
+//	closed            nobody, not even with an invite
+//	invite_only       holders of a valid invite code
+//	domain_allowlist  emails from REGISTRATION_ALLOWED_DOMAINS, or holders of an invite code
+//
+// Unknown values close registration rather than opening it by mistake.
+func RegistrationMode() string {
+	switch mode := strings.ToLower(os.Getenv("REGISTRATION_MODE")); mode {
+	case "", RegistrationOpen:
+		return RegistrationOpen
+	case RegistrationInviteOnly, RegistrationDomainAllowlist:
+		return mode
+	default:
+		return RegistrationClosed
+	}
+}
+
+// RegistrationAllowedDomains returns the email domains that may sign up in domain_allowlist mode
+// (REGISTRATION_ALLOWED_DOMAINS, comma separated)
+func RegistrationAllowedDomains() []string {
+	var domains []string
+	for _, domain := range strings.Split(os.Getenv("REGISTRATION_ALLOWED_DOMAINS"), ",") {
+		if domain = strings.ToLower(strings.TrimSpace(domain)); domain != "" {
+			domains = append(domains, strings.TrimPrefix(domain, "@"))
+		}
+	}
+	return domains
+}
+
+// InviteDefaultTTL returns how long invites stay valid when no expiry is given (INVITE_TTL, default 7 days)
+func InviteDefaultTTL() time.Duration {
+	return durationFromEnv("INVITE_TTL", 7*24*time.Hour)
+}
+
+// CheckRegistrationPolicy reports whether an account may be created for the email. An invite
+// code, when given, is checked separately when it is redeemed.
+func CheckRegistrationPolicy(email string, hasInvite bool) error {
+	switch RegistrationMode() {
+	case RegistrationOpen:
+		return nil
+	case RegistrationInviteOnly:
+		if !hasInvite {
+			return ErrInviteRequired
+		}
+		return nil
+	case RegistrationDomainAllowlist:
+		if hasInvite {
+			return nil
+		}
+		_, domain, _ := strings.Cut(strings.ToLower(strings.TrimSpace(email)), "@")
+		for _, allowed := range RegistrationAllowedDomains() {
+			if domain == allowed {
+				return nil
+			}
+		}
+		return ErrEmailDomainNotAllowed
+	default:
+		return ErrRegistrationClosed
+	}
+}
+
+// InviteService manages the invite codes admins hand out
+type InviteService struct {
+	db *gorm.DB
+}
+
+// NewInviteService creates a new InviteService instance
+func NewInviteService() *InviteService {
+	return &InviteService{
+		db: initializers.DB,
+	}
+}
+
+// Create issues a new invite and returns it with its code, which is never retrievable again
+func (s *InviteService) Create(createdByID uint, role models.Role, maxUses int, expiresAt time.Time) (*models.Invite, string, error) {
+	if !role.IsValid() {
+		return nil, "", ErrInvalidRole
+	}
+
+	secret, _, err := newOpaqueToken()
+	if err != nil {
+		return nil, "", err
+	}
+	code := InviteCodePrefix + secret
+
+	invite := models.Invite{
+		Prefix:      code[:len(InviteCodePrefix)+8],
+		CodeHash:    hashOpaqueToken(code),
+		Role:        role,
+		MaxUses:     maxUses,
+		ExpiresAt:   &expiresAt,
+		CreatedByID: &createdByID,
+	}
+	if err := s.db.Create(&invite).Error; err != nil {
+		return nil, "", err
+	}
+
+	return &invite, code, nil
+}
+
+// List returns the invites that have not been revoked, newest first
+func (s *InviteService) List() ([]models.Invite, error) {
+	var invites []models.Invite
+	result := s.db.Where("revoked_at IS NULL").Order("created_at DESC").Find(&invites)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return invites, nil
+}
+
+// Revoke stops an invite from being used again. Accounts already created with it are unaffected.
+func (s *InviteService) Revoke(id uint) error {
+	result := s.db.Model(&models.Invite{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInviteNotFound
+	}
+	return nil
+}
+
+// checkUsable returns ErrInvalidInvite unless the invite code exists and has uses left. It does not
+// reserve a use: redeem still decides when the account is created.
+func (s *InviteService) checkUsable(code string) error {
+	var count int64
+	result := s.db.Model(&models.Invite{}).
+		Where("code_hash = ? AND revoked_at IS NULL AND uses < max_uses AND (expires_at IS NULL OR expires_at > ?)", hashOpaqueToken(code), time.Now()).
+		Count(&count)
+	if result.Error != nil {
+		return result.Error
+	}
+	if count == 0 {
+		return ErrInvalidInvite
+	}
+	return nil
+}
+
+// redeem uses up one use of an invite within the signup transaction, so a failed signup
+// gives the use back
+func (s *InviteService) redeem(tx *gorm.DB, code string) (*models.Invite, error) {
+	codeHash := hashOpaqueToken(code)
+
+	// The conditional update lets only as many concurrent signups through as the invite has uses left
+	result := tx.Model(&models.Invite{}).
+		Where("code_hash = ? AND revoked_at IS NULL AND uses < max_uses AND (expires_at IS NULL OR expires_at > ?)", codeHash, time.Now()).
+		Update("uses", gorm.Expr("uses + 1"))
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrInvalidInvite
+	}
+
+	var invite models.Invite
+	if err := tx.Where("code_hash = ?", codeHash).First(&invite).Error; err != nil {
+		return nil, err
+	}
+	return &invite, nil
+}
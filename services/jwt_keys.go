@@ -0,0 +1,235 @@
+package services
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"go-crud/schemas"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var ErrUnknownSigningKey = errors.New("token was signed with an unknown key")
+
+// SigningKey is one entry of the key ring. Keys without a private half are kept
+// for verification only, which is how retired keys are phased out.
+type SigningKey struct {
+	ID         string
+	Method     jwt.SigningMethod
+	PrivateKey crypto.Signer
+	PublicKey  crypto.PublicKey
+}
+
+// KeyRing holds the keys used to sign and verify JWTs
+type KeyRing struct {
+	active *SigningKey
+	keys   map[string]*SigningKey
+	secret []byte
+}
+
+var keyRingCache struct {
+	sync.Mutex
+	signature string
+	ring      *KeyRing
+}
+
+// CurrentKeyRing returns the key ring described by the environment:
+//
+//   - JWT_KEYS_DIR: directory of PEM files named <kid>.pem. RSA keys sign with RS256,
+//     Ed25519 keys with EdDSA. Public-key-only files are accepted for verification.
+//   - JWT_ACTIVE_KID: the key used to sign new tokens (optional with a single private key)
+//
+// Without JWT_KEYS_DIR tokens are signed with HS256 and JWT_SECRET. The ring is
+// cached until one of these variables changes; failed loads are retried.
+func CurrentKeyRing() (*KeyRing, error) {
+	dir := os.Getenv("JWT_KEYS_DIR")
+	activeKID := os.Getenv("JWT_ACTIVE_KID")
+	secret := os.Getenv("JWT_SECRET")
+	signature := strings.Join([]string{dir, activeKID, secret}, "\x00")
+
+	keyRingCache.Lock()
+	defer keyRingCache.Unlock()
+
+	if keyRingCache.signature != signature || keyRingCache.ring == nil {
+		ring, err := loadKeyRing(dir, activeKID, secret)
+		if err != nil {
+			return nil, err
+		}
+		keyRingCache.ring = ring
+		keyRingCache.signature = signature
+	}
+	return keyRingCache.ring, nil
+}
+
+func loadKeyRing(dir, activeKID, secret string) (*KeyRing, error) {
+	if dir == "" {
+		if secret == "" {
+			return nil, errors.New("JWT_SECRET not configured")
+		}
+		return &KeyRing{secret: []byte(secret)}, nil
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	ring := &KeyRing{keys: map[string]*SigningKey{}}
+	var privateKeys []*SigningKey
+	for _, path := range paths {
+		kid := strings.TrimSuffix(filepath.Base(path), ".pem")
+		key, err := loadSigningKey(kid, path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load JWT key %s: %w", kid, err)
+		}
+		ring.keys[kid] = key
+		if key.PrivateKey != nil {
+			privateKeys = append(privateKeys, key)
+		}
+	}
+
+	switch {
+	case activeKID != "":
+		key, ok := ring.keys[activeKID]
+		if !ok || key.PrivateKey == nil {
+			return nil, fmt.Errorf("JWT_ACTIVE_KID %q has no private key in %s", activeKID, dir)
+		}
+		ring.active = key
+	case len(privateKeys) == 1:
+		ring.active = privateKeys[0]
+	default:
+		return nil, fmt.Errorf("set JWT_ACTIVE_KID to choose one of the %d private keys in %s", len(privateKeys), dir)
+	}
+
+	return ring, nil
+}
+
+func loadSigningKey(kid, path string) (*SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := &SigningKey{ID: kid}
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.Method, key.PrivateKey, key.PublicKey = jwt.SigningMethodRS256, k, &k.PublicKey
+	case *rsa.PublicKey:
+		key.Method, key.PublicKey = jwt.SigningMethodRS256, k
+	case ed25519.PrivateKey:
+		key.Method, key.PrivateKey, key.PublicKey = jwt.SigningMethodEdDSA, k, k.Public()
+	case ed25519.PublicKey:
+		key.Method, key.PublicKey = jwt.SigningMethodEdDSA, k
+	default:
+		return nil, fmt.Errorf("unsupported key type %T, use RSA or Ed25519", parsed)
+	}
+
+	if rsaKey, ok := key.PublicKey.(*rsa.PublicKey); ok && rsaKey.N.BitLen() < 2048 {
+		return nil, errors.New("RSA keys must be at least 2048 bits")
+	}
+	return key, nil
+}
+
+// sign signs claims with the active key, or with the shared secret in HS256 mode
+func (r *KeyRing) sign(claims jwt.Claims) (string, error) {
+	if r.active == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(r.secret)
+	}
+
+	token := jwt.NewWithClaims(r.active.Method, claims)
+	token.Header["kid"] = r.active.ID
+	return token.SignedString(r.active.PrivateKey)
+}
+
+// keyFunc resolves the verification key from the token's kid, refusing any algorithm
+// other than the one the key was loaded for
+func (r *KeyRing) keyFunc(token *jwt.Token) (interface{}, error) {
+	if r.keys == nil {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		return r.secret, nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	key, ok := r.keys[kid]
+	if !ok {
+		return nil, ErrUnknownSigningKey
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, errors.New("unexpected signing method")
+	}
+	return key.PublicKey, nil
+}
+
+// validMethods lists the algorithms the ring accepts
+func (r *KeyRing) validMethods() []string {
+	if r.keys == nil {
+		return []string{jwt.SigningMethodHS256.Alg()}
+	}
+
+	var methods []string
+	seen := map[string]bool{}
+	for _, key := range r.keys {
+		if alg := key.Method.Alg(); !seen[alg] {
+			seen[alg] = true
+			methods = append(methods, alg)
+		}
+	}
+	return methods
+}
+
+// JWKS returns the public keys of the ring, sorted by kid. It is empty in HS256 mode
+// since a shared secret must never be published.
+func (r *KeyRing) JWKS() []schemas.JWK {
+	jwks := []schemas.JWK{}
+	for _, key := range r.keys {
+		jwk := schemas.JWK{KeyID: key.ID, Algorithm: key.Method.Alg(), Use: "sig"}
+		switch k := key.PublicKey.(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(k.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(k)
+		}
+		jwks = append(jwks, jwk)
+	}
+
+	sort.Slice(jwks, func(i, j int) bool { return jwks[i].KeyID < jwks[j].KeyID })
+	return jwks
+}
@@ -0,0 +1,277 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"go-crud/initializers"
+	"go-crud/models"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LoginMaxFailures is the number of consecutive failed logins after which an account is locked
+func LoginMaxFailures() int {
+	return intFromEnv("LOGIN_MAX_FAILURES", 5)
+}
+
+// LoginMaxFailuresPerIP is the number of consecutive failed logins after which a client IP is locked
+func LoginMaxFailuresPerIP() int {
+	return intFromEnv("LOGIN_MAX_FAILURES_PER_IP", 20)
+}
+
+// LoginLockoutDuration is the length of the first lockout. Every further lockout doubles it.
+func LoginLockoutDuration() time.Duration {
+	return durationFromEnv("LOGIN_LOCKOUT_DURATION", time.Minute)
+}
+
+// LoginLockoutMaxDuration caps the exponential backoff
+func LoginLockoutMaxDuration() time.Duration {
+	return durationFromEnv("LOGIN_LOCKOUT_MAX_DURATION", time.Hour)
+}
+
+// LoginFailureWindow is how long a quiet period has to last before the failure count starts over
+func LoginFailureWindow() time.Duration {
+	return durationFromEnv("LOGIN_FAILURE_WINDOW", 15*time.Minute)
+}
+
+// AuthRateLimitInterval and AuthRateLimitBurst configure the per-IP rate limiter in front of /auth
+func AuthRateLimitInterval() time.Duration {
+	return durationFromEnv("AUTH_RATE_LIMIT_INTERVAL", 100*time.Millisecond)
+}
+
+func AuthRateLimitBurst() int {
+	return intFromEnv("AUTH_RATE_LIMIT_BURST", 10)
+}
+
+// LockoutError is returned while an account or client IP is locked out
+type LockoutError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockoutError) Error() string {
+	return fmt.Sprintf("too many failed login attempts, retry in %s", e.RetryAfter.Round(time.Second))
+}
+
+// LoginAttempt describes who is trying to sign in. UserID is nil when the email is unknown.
+type LoginAttempt struct {
+	Email     string
+	UserID    *uint
+	IP        string
+	UserAgent string
+}
+
+// throttleKey is one of the counters a login attempt is tracked under
+type throttleKey struct {
+	key         string
+	maxFailures int
+	eventType   models.SecurityEventType
+}
+
+func (a LoginAttempt) keys() []throttleKey {
+	var keys []throttleKey
+	if a.Email != "" {
+		keys = append(keys, throttleKey{accountThrottleKey(a.Email), LoginMaxFailures(), models.AccountLockedEvent})
+	}
+	if a.IP != "" {
+		keys = append(keys, throttleKey{"ip:" + a.IP, LoginMaxFailuresPerIP(), models.IPLockedEvent})
+	}
+	return keys
+}
+
+// accountThrottleKey normalizes the email so that case variants share one counter
+func accountThrottleKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+// LoginThrottleService counts failed logins per account and per client IP in the database,
+// so lockouts hold across restarts and across instances
+type LoginThrottleService struct {
+	db *gorm.DB
+}
+
+// NewLoginThrottleService creates a new LoginThrottleService instance
+func NewLoginThrottleService() *LoginThrottleService {
+	return &LoginThrottleService{
+		db: initializers.DB,
+	}
+}
+
+// Check returns a *LockoutError when the account or the client IP is currently locked
+func (s *LoginThrottleService) Check(attempt LoginAttempt) error {
+	var keys []string
+	for _, key := range attempt.keys() {
+		keys = append(keys, key.key)
+	}
+	return s.checkKeys(keys)
+}
+
+// checkKeys returns a *LockoutError when any of the keys is currently locked
+func (s *LoginThrottleService) checkKeys(keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	var throttles []models.AuthThrottle
+	if err := s.db.Where("key IN ? AND locked_until > ?", keys, now).Find(&throttles).Error; err != nil {
+		return err
+	}
+
+	var retryAfter time.Duration
+	for _, throttle := range throttles {
+		if remaining := throttle.LockedUntil.Sub(now); remaining > retryAfter {
+			retryAfter = remaining
+		}
+	}
+	if retryAfter > 0 {
+		return &LockoutError{RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// RecordFailure counts a failed attempt. When it pushes a counter over its threshold the key is
+// locked, a security event is recorded and a *LockoutError is returned.
+func (s *LoginThrottleService) RecordFailure(attempt LoginAttempt) error {
+	var lockout *LockoutError
+	for _, key := range attempt.keys() {
+		lockedFor, err := s.recordFailure(key.key, key.maxFailures)
+		if err != nil {
+			return err
+		}
+		if lockedFor == 0 {
+			continue
+		}
+
+		event := models.SecurityEvent{
+			Type:      key.eventType,
+			IP:        attempt.IP,
+			UserAgent: truncate(attempt.UserAgent, 512),
+			Details:   fmt.Sprintf("%s locked for %s after repeated failed logins", key.key, lockedFor),
+		}
+		if key.eventType == models.AccountLockedEvent {
+			event.UserID = attempt.UserID
+		}
+		if err := NewSecurityEventService().Record(&event); err != nil {
+			return err
+		}
+
+		if lockout == nil || lockedFor > lockout.RetryAfter {
+			lockout = &LockoutError{RetryAfter: lockedFor}
+		}
+	}
+
+	if lockout != nil {
+		return lockout
+	}
+	return nil
+}
+
+// recordFailure increments one counter and returns how long it got locked for, if at all
+func (s *LoginThrottleService) recordFailure(key string, maxFailures int) (time.Duration, error) {
+	var lockedFor time.Duration
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		// Make sure the row exists, then lock it so concurrent failures are all counted
+		seed := models.AuthThrottle{Key: key, LastFailureAt: now}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&seed).Error; err != nil {
+			return err
+		}
+
+		var throttle models.AuthThrottle
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("key = ?", key).First(&throttle).Error; err != nil {
+			return err
+		}
+
+		// Start over after a quiet period, measured from the end of the last lockout
+		lastActivity := throttle.LastFailureAt
+		if throttle.LockedUntil != nil && throttle.LockedUntil.After(lastActivity) {
+			lastActivity = *throttle.LockedUntil
+		}
+		if now.Sub(lastActivity) > LoginFailureWindow() {
+			throttle.Failures = 0
+		}
+
+		throttle.Failures++
+		throttle.LastFailureAt = now
+		if throttle.Failures >= maxFailures {
+			lockedFor = lockoutDuration(throttle.Failures - maxFailures)
+			lockedUntil := now.Add(lockedFor)
+			throttle.LockedUntil = &lockedUntil
+		}
+
+		return tx.Model(&throttle).Updates(map[string]interface{}{
+			"failures":        throttle.Failures,
+			"last_failure_at": throttle.LastFailureAt,
+			"locked_until":    throttle.LockedUntil,
+		}).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return lockedFor, nil
+}
+
+// lockoutDuration doubles the base lockout for every failure past the threshold, up to the cap
+func lockoutDuration(excess int) time.Duration {
+	duration, max := LoginLockoutDuration(), LoginLockoutMaxDuration()
+	for i := 0; i < excess && duration < max; i++ {
+		duration *= 2
+	}
+	if duration > max {
+		duration = max
+	}
+	return duration
+}
+
+// MagicLinkMaxRequests is the number of magic links an address can be sent before further requests
+// are refused for a while (MAGIC_LINK_MAX_REQUESTS, default 3)
+func MagicLinkMaxRequests() int {
+	return intFromEnv("MAGIC_LINK_MAX_REQUESTS", 3)
+}
+
+// magicLinkThrottleKey is counted for every magic link request, whether or not the account exists
+func magicLinkThrottleKey(email string) string {
+	return "magic_link:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+// CheckMagicLinkRequest returns a *LockoutError when too many magic links were requested for the email
+func (s *LoginThrottleService) CheckMagicLinkRequest(email string) error {
+	return s.checkKeys([]string{magicLinkThrottleKey(email)})
+}
+
+// RecordMagicLinkRequest counts a magic link request. Requests back off like failed logins: once
+// the limit is reached the address is locked, for longer each time, until the window has passed.
+func (s *LoginThrottleService) RecordMagicLinkRequest(email string) error {
+	_, err := s.recordFailure(magicLinkThrottleKey(email), MagicLinkMaxRequests())
+	return err
+}
+
+// RecordSuccess clears the account counter after a successful login. The IP counter is left
+// alone, otherwise an attacker could reset it by signing in to an account of their own.
+func (s *LoginThrottleService) RecordSuccess(attempt LoginAttempt) error {
+	if attempt.Email == "" {
+		return nil
+	}
+	return s.db.Where("key = ?", accountThrottleKey(attempt.Email)).Delete(&models.AuthThrottle{}).Error
+}
+
+// PurgeStale deletes counters that would start over on their next failure anyway
+func (s *LoginThrottleService) PurgeStale() error {
+	cutoff := time.Now().Add(-LoginFailureWindow())
+	return s.db.Where("last_failure_at < ? AND (locked_until IS NULL OR locked_until < ?)", cutoff, cutoff).
+		Delete(&models.AuthThrottle{}).Error
+}
+
+// IsLockout reports whether err is a *LockoutError and returns it
+func IsLockout(err error) (*LockoutError, bool) {
+	var lockout *LockoutError
+	if errors.As(err, &lockout) {
+		return lockout, true
+	}
+	return nil, false
+}
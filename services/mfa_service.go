@@ -0,0 +1,265 @@
+package services
+
+import (
+	"crypto/rand"
+	"errors"
+	"go-crud/initializers"
+	"go-crud/models"
+	"os"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	mfaPendingPurpose = "mfa_pending"
+	recoveryCodeCount = 10
+)
+
+var (
+	ErrMFAAlreadyEnabled     = errors.New("two-factor authentication is already enabled")
+	ErrMFANotEnabled         = errors.New("two-factor authentication is not enabled")
+	ErrMFAEnrollmentNotFound = errors.New("no pending two-factor enrollment")
+	ErrInvalidMFACode        = errors.New("invalid two-factor code")
+	ErrInvalidMFAToken       = errors.New("invalid or expired mfa token")
+)
+
+// TOTPEnrollment is the secret handed to the user when they start enrolling an authenticator app
+type TOTPEnrollment struct {
+	Secret          string
+	ProvisioningURI string
+}
+
+// MFAService handles TOTP two-factor authentication and recovery codes
+type MFAService struct {
+	db *gorm.DB
+}
+
+// NewMFAService creates a new MFAService instance
+func NewMFAService() *MFAService {
+	return &MFAService{
+		db: initializers.DB,
+	}
+}
+
+// MFAPendingTTL returns how long a user has to enter their code after the password step (MFA_PENDING_TTL, default 5 minutes)
+func MFAPendingTTL() time.Duration {
+	return durationFromEnv("MFA_PENDING_TTL", 5*time.Minute)
+}
+
+func totpIssuer() string {
+	issuer := os.Getenv("TOTP_ISSUER")
+	if issuer == "" {
+		issuer = "Horizon Blog"
+	}
+	return issuer
+}
+
+// BeginEnrollment generates a new TOTP secret for the user. It is not enforced until confirmed.
+func (s *MFAService) BeginEnrollment(userID uint) (*TOTPEnrollment, error) {
+	user, err := NewUserService().GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.HasTOTPEnabled() {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := newTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Model(user).Updates(map[string]interface{}{
+		"totp_secret":    secret,
+		"totp_last_step": 0,
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	return &TOTPEnrollment{
+		Secret:          secret,
+		ProvisioningURI: totpProvisioningURI(totpIssuer(), user.Email, secret),
+	}, nil
+}
+
+// ConfirmEnrollment enables TOTP once the user proves their app produces valid codes,
+// and returns a fresh set of recovery codes to be shown once
+func (s *MFAService) ConfirmEnrollment(userID uint, code string) ([]string, error) {
+	user, err := NewUserService().GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.HasTOTPEnabled() {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrMFAEnrollmentNotFound
+	}
+
+	step, ok := matchTOTPCode(user.TOTPSecret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	var codes []string
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Updates(map[string]interface{}{
+			"totp_enabled_at": time.Now(),
+			"totp_last_step":  step,
+		}).Error; err != nil {
+			return err
+		}
+
+		var err error
+		codes, err = s.replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// Disable turns two-factor authentication off after checking a TOTP or recovery code
+func (s *MFAService) Disable(userID uint, code string) error {
+	user, err := NewUserService().GetByID(userID)
+	if err != nil {
+		return err
+	}
+	if !user.HasTOTPEnabled() {
+		return ErrMFANotEnabled
+	}
+
+	if err := s.VerifyCode(user, code); err != nil {
+		return err
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Updates(map[string]interface{}{
+			"totp_secret":     "",
+			"totp_enabled_at": nil,
+			"totp_last_step":  0,
+		}).Error; err != nil {
+			return err
+		}
+
+		return tx.Where("user_id = ?", user.ID).Delete(&models.RecoveryCode{}).Error
+	})
+}
+
+// VerifyCode checks a TOTP code or, failing that, consumes a recovery code.
+// A TOTP code is accepted only once so that an observed code cannot be replayed.
+func (s *MFAService) VerifyCode(user *models.User, code string) error {
+	code = strings.TrimSpace(code)
+
+	if step, ok := matchTOTPCode(user.TOTPSecret, code, time.Now()); ok {
+		update := s.db.Model(&models.User{}).
+			Where("id = ? AND totp_last_step < ?", user.ID, step).
+			Update("totp_last_step", step)
+		if update.Error != nil {
+			return update.Error
+		}
+		if update.RowsAffected == 0 {
+			return ErrInvalidMFACode
+		}
+		return nil
+	}
+
+	return s.consumeRecoveryCode(user.ID, code)
+}
+
+// IssuePendingToken returns a short-lived token proving the password step succeeded
+func (s *MFAService) IssuePendingToken(user models.User) (string, error) {
+	return signPurposeToken(mfaPendingPurpose, user.ID, "", MFAPendingTTL())
+}
+
+// PendingUser resolves the user a pending token was issued to. The caller then checks the
+// second factor with VerifyCode.
+func (s *MFAService) PendingUser(mfaToken string) (*models.User, error) {
+	userID, _, err := parsePurposeToken(mfaToken, mfaPendingPurpose)
+	if err != nil {
+		return nil, ErrInvalidMFAToken
+	}
+
+	user, err := NewUserService().GetByID(userID)
+	if err != nil {
+		return nil, ErrInvalidMFAToken
+	}
+	if !user.HasTOTPEnabled() {
+		return nil, ErrMFANotEnabled
+	}
+
+	return user, nil
+}
+
+func (s *MFAService) consumeRecoveryCode(userID uint, code string) error {
+	normalized := normalizeRecoveryCode(code)
+	if normalized == "" {
+		return ErrInvalidMFACode
+	}
+
+	update := s.db.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hashOpaqueToken(normalized)).
+		Update("used_at", time.Now())
+	if update.Error != nil {
+		return update.Error
+	}
+	if update.RowsAffected == 0 {
+		return ErrInvalidMFACode
+	}
+	return nil
+}
+
+func (s *MFAService) replaceRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+
+		recoveryCode := models.RecoveryCode{
+			UserID:   userID,
+			CodeHash: hashOpaqueToken(normalizeRecoveryCode(code)),
+		}
+		if err := tx.Create(&recoveryCode).Error; err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+
+	return codes, nil
+}
+
+// newRecoveryCode returns a code formatted as "xxxxx-xxxxx" (50 bits of entropy)
+func newRecoveryCode() (string, error) {
+	const alphabet = "abcdefghijkmnpqrstuvwxyz23456789"
+
+	buf := make([]byte, 10)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	code := make([]byte, 0, 11)
+	for i, b := range buf {
+		if i == 5 {
+			code = append(code, '-')
+		}
+		code = append(code, alphabet[int(b)%len(alphabet)])
+	}
+	return string(code), nil
+}
+
+// normalizeRecoveryCode lowercases a code and strips separators the user may have typed
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
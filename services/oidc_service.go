@@ -0,0 +1,416 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-crud/initializers"
+	"go-crud/models"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrOIDCProviderNotFound     = errors.New("oidc provider not configured")
+	ErrInvalidOAuthState        = errors.New("invalid or expired oauth state")
+	ErrOIDCEmailNotVerified     = errors.New("email address is not verified by the provider or on the existing account")
+	ErrIdentityLinkedElsewhere  = errors.New("identity is already linked to another account")
+	ErrIdentityNotFound         = errors.New("identity not found")
+	ErrLastLoginMethod          = errors.New("cannot remove the only way to sign in to this account")
+	oidcProviderCache           = map[string]*oidc.Provider{}
+	oidcProviderCacheMu         sync.Mutex
+	defaultOIDCScopes           = []string{oidc.ScopeOpenID, "profile", "email"}
+	oauthStateTTL               = 10 * time.Minute
+	oidcHTTPTimeout             = 10 * time.Second
+	oidcProviderNameReplacement = strings.NewReplacer("-", "_")
+)
+
+// OIDCProviderConfig describes an external OpenID Connect provider configured through the environment:
+//
+//	OIDC_PROVIDERS=google,keycloak
+//	OIDC_GOOGLE_ISSUER=https://accounts.google.com
+//	OIDC_GOOGLE_CLIENT_ID=...
+//	OIDC_GOOGLE_CLIENT_SECRET=...
+//	OIDC_GOOGLE_REDIRECT_URL=https://blog.example.com/auth/callback/google
+//	OIDC_GOOGLE_SCOPES=openid,profile,email (optional)
+type OIDCProviderConfig struct {
+	Name         string
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+// OIDCClaims are the ID token claims used to resolve a local account
+type OIDCClaims struct {
+	Subject       string `json:"sub"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Name          string `json:"name"`
+	Nonce         string `json:"nonce"`
+}
+
+// OIDCService implements login through external OpenID Connect providers
+// using the authorization code flow with PKCE
+type OIDCService struct {
+	db *gorm.DB
+}
+
+// NewOIDCService creates a new OIDCService instance
+func NewOIDCService() *OIDCService {
+	return &OIDCService{
+		db: initializers.DB,
+	}
+}
+
+// EnabledOIDCProviders returns the names of the configured providers
+func EnabledOIDCProviders() []string {
+	var names []string
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func oidcProviderConfig(name string) (*OIDCProviderConfig, error) {
+	name = strings.ToLower(name)
+
+	enabled := false
+	for _, provider := range EnabledOIDCProviders() {
+		if provider == name {
+			enabled = true
+			break
+		}
+	}
+	if !enabled {
+		return nil, ErrOIDCProviderNotFound
+	}
+
+	prefix := "OIDC_" + strings.ToUpper(oidcProviderNameReplacement.Replace(name)) + "_"
+	config := &OIDCProviderConfig{
+		Name:         name,
+		IssuerURL:    os.Getenv(prefix + "ISSUER"),
+		ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+		ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+		RedirectURL:  os.Getenv(prefix + "REDIRECT_URL"),
+		Scopes:       defaultOIDCScopes,
+	}
+	if scopes := os.Getenv(prefix + "SCOPES"); scopes != "" {
+		config.Scopes = strings.Split(scopes, ",")
+	}
+
+	if config.IssuerURL == "" || config.ClientID == "" || config.RedirectURL == "" {
+		return nil, fmt.Errorf("oidc provider %q is missing its issuer, client id or redirect url", name)
+	}
+	return config, nil
+}
+
+// discoverProvider fetches (and caches) the provider's discovery document
+func discoverProvider(ctx context.Context, issuerURL string) (*oidc.Provider, error) {
+	oidcProviderCacheMu.Lock()
+	defer oidcProviderCacheMu.Unlock()
+
+	if provider, ok := oidcProviderCache[issuerURL]; ok {
+		return provider, nil
+	}
+
+	provider, err := oidc.NewProvider(ctx, issuerURL)
+	if err != nil {
+		return nil, err
+	}
+	oidcProviderCache[issuerURL] = provider
+	return provider, nil
+}
+
+func (s *OIDCService) oauth2Config(ctx context.Context, config *OIDCProviderConfig) (*oauth2.Config, *oidc.Provider, error) {
+	provider, err := discoverProvider(ctx, config.IssuerURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to discover oidc provider: %w", err)
+	}
+
+	return &oauth2.Config{
+		ClientID:     config.ClientID,
+		ClientSecret: config.ClientSecret,
+		RedirectURL:  config.RedirectURL,
+		Endpoint:     provider.Endpoint(),
+		Scopes:       config.Scopes,
+	}, provider, nil
+}
+
+// AuthorizationURL starts an authorization code flow and returns the URL to send the browser to.
+// When linkUserID is set, the resulting identity is attached to that (already signed-in) user.
+func (s *OIDCService) AuthorizationURL(ctx context.Context, providerName string, linkUserID *uint) (string, error) {
+	config, err := oidcProviderConfig(providerName)
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, oidcHTTPTimeout)
+	defer cancel()
+
+	oauthConfig, _, err := s.oauth2Config(ctx, config)
+	if err != nil {
+		return "", err
+	}
+
+	state, stateHash, err := newOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	nonce, err := newTokenID()
+	if err != nil {
+		return "", err
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	oauthState := models.OAuthState{
+		StateHash:    stateHash,
+		Provider:     config.Name,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		LinkUserID:   linkUserID,
+		ExpiresAt:    time.Now().Add(oauthStateTTL),
+	}
+	if err := s.db.Create(&oauthState).Error; err != nil {
+		return "", err
+	}
+
+	return oauthConfig.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)), nil
+}
+
+// HandleCallback exchanges the authorization code and resolves (or creates) the local account
+func (s *OIDCService) HandleCallback(ctx context.Context, providerName, code, state string) (*models.User, error) {
+	config, err := oidcProviderConfig(providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	oauthState, err := s.consumeState(config.Name, state)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, oidcHTTPTimeout)
+	defer cancel()
+
+	oauthConfig, provider, err := s.oauth2Config(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := oauthConfig.Exchange(ctx, code, oauth2.VerifierOption(oauthState.CodeVerifier))
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange authorization code: %w", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return nil, errors.New("provider did not return an id_token")
+	}
+
+	idToken, err := provider.Verifier(&oidc.Config{ClientID: config.ClientID}).Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify id_token: %w", err)
+	}
+
+	var claims OIDCClaims
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, err
+	}
+	if claims.Nonce != oauthState.Nonce {
+		return nil, ErrInvalidOAuthState
+	}
+
+	return s.resolveUser(config.Name, claims, oauthState.LinkUserID)
+}
+
+// consumeState loads and deletes a pending state so that it can only be used once
+func (s *OIDCService) consumeState(provider, state string) (*models.OAuthState, error) {
+	var oauthState models.OAuthState
+	result := s.db.Where("state_hash = ? AND provider = ?", hashOpaqueToken(state), provider).First(&oauthState)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidOAuthState
+		}
+		return nil, result.Error
+	}
+
+	deleted := s.db.Delete(&models.OAuthState{}, oauthState.ID)
+	if deleted.Error != nil {
+		return nil, deleted.Error
+	}
+	if deleted.RowsAffected == 0 || time.Now().After(oauthState.ExpiresAt) {
+		return nil, ErrInvalidOAuthState
+	}
+
+	return &oauthState, nil
+}
+
+// resolveUser finds the account for a provider identity, linking or creating it when needed
+func (s *OIDCService) resolveUser(provider string, claims OIDCClaims, linkUserID *uint) (*models.User, error) {
+	userService := NewUserService()
+	now := time.Now()
+
+	var identity models.UserIdentity
+	result := s.db.Where("provider = ? AND subject = ?", provider, claims.Subject).First(&identity)
+	if result.Error == nil {
+		if linkUserID != nil && *linkUserID != identity.UserID {
+			return nil, ErrIdentityLinkedElsewhere
+		}
+		if err := s.db.Model(&identity).Update("last_login_at", now).Error; err != nil {
+			return nil, err
+		}
+		return userService.GetByID(identity.UserID)
+	}
+	if !errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, result.Error
+	}
+
+	var user *models.User
+	var err error
+	switch {
+	case linkUserID != nil:
+		// Explicit linking from a signed-in session
+		user, err = userService.GetByID(*linkUserID)
+	case isReservedEmail(claims.Email):
+		// Never hand the deleted user placeholder to a provider identity
+		return nil, ErrEmailTaken
+	default:
+		user, err = userService.FindByEmail(claims.Email)
+		if err == nil && (!claims.EmailVerified || user.EmailVerifiedAt == nil) {
+			// Never attach an identity to an existing account on an unverified email, on either side:
+			// whoever registered an address they never verified may not own it, and would keep their
+			// password on the account. Linking has to be done from a signed-in session instead.
+			return nil, ErrOIDCEmailNotVerified
+		}
+		if err != nil && err.Error() == "user not found" {
+			user, err = s.createUser(claims)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	identity = models.UserIdentity{
+		UserID:      user.ID,
+		Provider:    provider,
+		Subject:     claims.Subject,
+		Email:       claims.Email,
+		LastLoginAt: &now,
+	}
+	if err := s.db.Create(&identity).Error; err != nil {
+		return nil, err
+	}
+
+	// The provider vouched for the address, so it counts as verified locally too
+	if user.EmailVerifiedAt == nil && claims.EmailVerified && strings.EqualFold(user.Email, claims.Email) {
+		if err := s.db.Model(user).Update("email_verified_at", now).Error; err != nil {
+			return nil, err
+		}
+		user.EmailVerifiedAt = &now
+	}
+
+	return user, nil
+}
+
+// createUser registers a new account that signs in only through the provider (no password)
+func (s *OIDCService) createUser(claims OIDCClaims) (*models.User, error) {
+	if claims.Email == "" {
+		return nil, errors.New("provider did not return an email address")
+	}
+	if isReservedEmail(claims.Email) {
+		return nil, ErrEmailTaken
+	}
+	if err := CheckRegistrationPolicy(claims.Email, false); err != nil {
+		return nil, err
+	}
+
+	name := claims.Name
+	if name == "" {
+		name = strings.Split(claims.Email, "@")[0]
+	}
+
+	user := models.User{
+		Name:  name,
+		Email: claims.Email,
+	}
+	if claims.EmailVerified {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+
+	if err := s.db.Create(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// ListIdentities returns the provider identities linked to a user
+func (s *OIDCService) ListIdentities(userID uint) ([]models.UserIdentity, error) {
+	var identities []models.UserIdentity
+	result := s.db.Where("user_id = ?", userID).Order("created_at ASC").Find(&identities)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return identities, nil
+}
+
+// Unlink removes a provider identity, refusing to lock a passwordless user out of their account
+func (s *OIDCService) Unlink(userID, identityID uint) error {
+	var identity models.UserIdentity
+	result := s.db.Where("id = ? AND user_id = ?", identityID, userID).First(&identity)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return ErrIdentityNotFound
+		}
+		return result.Error
+	}
+
+	user, err := NewUserService().GetByID(userID)
+	if err != nil {
+		return err
+	}
+
+	methods, err := countLoginMethods(s.db, *user)
+	if err != nil {
+		return err
+	}
+	if methods <= 1 {
+		return ErrLastLoginMethod
+	}
+
+	return s.db.Delete(&identity).Error
+}
+
+// countLoginMethods returns how many ways the user has to sign in: their password, linked
+// identities and passkeys
+func countLoginMethods(db *gorm.DB, user models.User) (int64, error) {
+	var identities, passkeys int64
+	if err := db.Model(&models.UserIdentity{}).Where("user_id = ?", user.ID).Count(&identities).Error; err != nil {
+		return 0, err
+	}
+	if err := db.Model(&models.Passkey{}).Where("user_id = ?", user.ID).Count(&passkeys).Error; err != nil {
+		return 0, err
+	}
+
+	methods := identities + passkeys
+	if user.HashedPassword != "" {
+		methods++
+	}
+	return methods, nil
+}
+
+// PurgeExpiredStates deletes abandoned authorization flows
+func (s *OIDCService) PurgeExpiredStates() error {
+	return s.db.Where("expires_at < ?", time.Now()).Delete(&models.OAuthState{}).Error
+}
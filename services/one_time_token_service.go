@@ -0,0 +1,105 @@
+package services
+
+import (
+	"errors"
+	"go-crud/initializers"
+	"go-crud/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrInvalidOneTimeToken = errors.New("invalid or expired token")
+
+// OneTimeTokenService issues and consumes single-use tokens
+type OneTimeTokenService struct {
+	db *gorm.DB
+}
+
+// NewOneTimeTokenService creates a new OneTimeTokenService instance
+func NewOneTimeTokenService() *OneTimeTokenService {
+	return &OneTimeTokenService{
+		db: initializers.DB,
+	}
+}
+
+// Issue creates a token for the given purpose, invalidating any older unused one
+func (s *OneTimeTokenService) Issue(userID uint, purpose models.OneTimeTokenPurpose, ttl time.Duration) (string, error) {
+	token, tokenHash, err := newOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.OneTimeToken{}).
+			Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+			Update("used_at", time.Now()).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Create(&models.OneTimeToken{
+			UserID:    userID,
+			Purpose:   purpose,
+			TokenHash: tokenHash,
+			ExpiresAt: time.Now().Add(ttl),
+		}).Error
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// Invalidate uses up every outstanding token of the user for the given purpose
+func (s *OneTimeTokenService) Invalidate(userID uint, purpose models.OneTimeTokenPurpose) error {
+	return s.db.Model(&models.OneTimeToken{}).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+		Update("used_at", time.Now()).Error
+}
+
+// Lookup returns the ID of the user a token was issued to, without using it up
+func (s *OneTimeTokenService) Lookup(token string, purpose models.OneTimeTokenPurpose) (uint, error) {
+	var oneTimeToken models.OneTimeToken
+	result := s.db.Where("token_hash = ? AND purpose = ? AND used_at IS NULL AND expires_at > ?",
+		hashOpaqueToken(token), purpose, time.Now()).First(&oneTimeToken)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return 0, ErrInvalidOneTimeToken
+		}
+		return 0, result.Error
+	}
+
+	return oneTimeToken.UserID, nil
+}
+
+// Consume marks a token as used and returns the ID of the user it was issued to
+func (s *OneTimeTokenService) Consume(token string, purpose models.OneTimeTokenPurpose) (uint, error) {
+	var oneTimeToken models.OneTimeToken
+	result := s.db.Where("token_hash = ? AND purpose = ?", hashOpaqueToken(token), purpose).First(&oneTimeToken)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return 0, ErrInvalidOneTimeToken
+		}
+		return 0, result.Error
+	}
+
+	// Only the first concurrent consumer wins
+	update := s.db.Model(&models.OneTimeToken{}).
+		Where("id = ? AND used_at IS NULL AND expires_at > ?", oneTimeToken.ID, time.Now()).
+		Update("used_at", time.Now())
+	if update.Error != nil {
+		return 0, update.Error
+	}
+	if update.RowsAffected == 0 {
+		return 0, ErrInvalidOneTimeToken
+	}
+
+	return oneTimeToken.UserID, nil
+}
+
+// PurgeExpired deletes tokens that can no longer be used
+func (s *OneTimeTokenService) PurgeExpired() error {
+	return s.db.Where("expires_at < ?", time.Now()).Delete(&models.OneTimeToken{}).Error
+}
@@ -0,0 +1,416 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-crud/initializers"
+	"go-crud/models"
+	"log"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidPasskeyChallenge   = errors.New("invalid or expired passkey challenge")
+	ErrPasskeyVerificationFailed = errors.New("passkey verification failed")
+	ErrPasskeyAlreadyRegistered  = errors.New("passkey is already registered")
+	ErrPasskeyNotFound           = errors.New("passkey not found")
+	ErrPasskeyCloned             = errors.New("passkey signature counter did not increase")
+	passkeyChallengeTTL          = 5 * time.Minute
+)
+
+// WebAuthnRPID returns the relying party ID passkeys are bound to (WEBAUTHN_RP_ID, default the
+// host of FRONTEND_URL). It must be the frontend's domain or a parent of it.
+func WebAuthnRPID() string {
+	if rpID := os.Getenv("WEBAUTHN_RP_ID"); rpID != "" {
+		return rpID
+	}
+	parsed, err := url.Parse(frontendURL())
+	if err != nil {
+		return "localhost"
+	}
+	return parsed.Hostname()
+}
+
+// WebAuthnOrigins returns the origins ceremonies may come from (WEBAUTHN_ORIGINS, comma separated,
+// default FRONTEND_URL)
+func WebAuthnOrigins() []string {
+	var origins []string
+	for _, origin := range strings.Split(os.Getenv("WEBAUTHN_ORIGINS"), ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			origins = append(origins, strings.TrimRight(origin, "/"))
+		}
+	}
+	if len(origins) == 0 {
+		origins = []string{frontendURL()}
+	}
+	return origins
+}
+
+func newWebAuthn() (*webauthn.WebAuthn, error) {
+	return webauthn.New(&webauthn.Config{
+		RPID:          WebAuthnRPID(),
+		RPDisplayName: totpIssuer(),
+		RPOrigins:     WebAuthnOrigins(),
+	})
+}
+
+// passkeyUserHandle is the opaque user ID stored on the authenticator. Discoverable logins
+// return it to tell which account the passkey belongs to.
+func passkeyUserHandle(userID uint) []byte {
+	return []byte(strconv.FormatUint(uint64(userID), 10))
+}
+
+// webauthnUser adapts a user and their passkeys to the webauthn library
+type webauthnUser struct {
+	user     models.User
+	passkeys []models.Passkey
+}
+
+func (u webauthnUser) WebAuthnID() []byte          { return passkeyUserHandle(u.user.ID) }
+func (u webauthnUser) WebAuthnName() string        { return u.user.Email }
+func (u webauthnUser) WebAuthnDisplayName() string { return u.user.Name }
+
+func (u webauthnUser) WebAuthnCredentials() []webauthn.Credential {
+	credentials := make([]webauthn.Credential, 0, len(u.passkeys))
+	for _, passkey := range u.passkeys {
+		var transports []protocol.AuthenticatorTransport
+		for _, transport := range strings.Split(passkey.Transports, ",") {
+			if transport != "" {
+				transports = append(transports, protocol.AuthenticatorTransport(transport))
+			}
+		}
+
+		credentials = append(credentials, webauthn.Credential{
+			ID:              passkey.CredentialID,
+			PublicKey:       passkey.PublicKey,
+			AttestationType: passkey.AttestationType,
+			Transport:       transports,
+			Flags: webauthn.CredentialFlags{
+				BackupEligible: passkey.BackupEligible,
+				BackupState:    passkey.BackupState,
+			},
+			Authenticator: webauthn.Authenticator{
+				AAGUID:    passkey.AAGUID,
+				SignCount: passkey.SignCount,
+			},
+		})
+	}
+	return credentials
+}
+
+// PasskeyService runs the WebAuthn registration and login ceremonies and manages stored passkeys
+type PasskeyService struct {
+	db *gorm.DB
+}
+
+// NewPasskeyService creates a new PasskeyService instance
+func NewPasskeyService() *PasskeyService {
+	return &PasskeyService{
+		db: initializers.DB,
+	}
+}
+
+// BeginRegistration returns the options for navigator.credentials.create(). Passkeys must be
+// discoverable and verify the user, so they can replace the password on their own.
+func (s *PasskeyService) BeginRegistration(user models.User) (*protocol.CredentialCreation, error) {
+	wa, err := newWebAuthn()
+	if err != nil {
+		return nil, err
+	}
+
+	passkeys, err := s.List(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	owner := webauthnUser{user: user, passkeys: passkeys}
+
+	creation, session, err := wa.BeginRegistration(owner,
+		webauthn.WithAuthenticatorSelection(protocol.AuthenticatorSelection{
+			ResidentKey:        protocol.ResidentKeyRequirementRequired,
+			RequireResidentKey: protocol.ResidentKeyRequired(),
+			UserVerification:   protocol.VerificationRequired,
+		}),
+		// Keep the same authenticator from being registered twice
+		webauthn.WithExclusions(webauthn.Credentials(owner.WebAuthnCredentials()).CredentialDescriptors()),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.saveChallenge(session, models.PasskeyRegistration, &user.ID); err != nil {
+		return nil, err
+	}
+	return creation, nil
+}
+
+// FinishRegistration verifies the authenticator's attestation response and stores the new passkey.
+// The addition is recorded as a security event and the user is told about it by email.
+func (s *PasskeyService) FinishRegistration(user models.User, name string, response []byte, device Device) (*models.Passkey, error) {
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(response)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrPasskeyVerificationFailed, err)
+	}
+
+	session, err := s.consumeChallenge(parsed.Response.CollectedClientData.Challenge, models.PasskeyRegistration, &user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	wa, err := newWebAuthn()
+	if err != nil {
+		return nil, err
+	}
+
+	passkeys, err := s.List(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	credential, err := wa.CreateCredential(webauthnUser{user: user, passkeys: passkeys}, *session, parsed)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrPasskeyVerificationFailed, err)
+	}
+
+	var registered int64
+	if err := s.db.Model(&models.Passkey{}).Where("credential_id = ?", credential.ID).Count(&registered).Error; err != nil {
+		return nil, err
+	}
+	if registered > 0 {
+		return nil, ErrPasskeyAlreadyRegistered
+	}
+
+	transports := make([]string, 0, len(credential.Transport))
+	for _, transport := range credential.Transport {
+		transports = append(transports, string(transport))
+	}
+
+	passkey := models.Passkey{
+		UserID:          user.ID,
+		Name:            name,
+		CredentialID:    credential.ID,
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		Transports:      strings.Join(transports, ","),
+		AAGUID:          credential.Authenticator.AAGUID,
+		SignCount:       credential.Authenticator.SignCount,
+		BackupEligible:  credential.Flags.BackupEligible,
+		BackupState:     credential.Flags.BackupState,
+	}
+	if err := s.db.Create(&passkey).Error; err != nil {
+		return nil, err
+	}
+
+	event := models.SecurityEvent{
+		UserID:    &user.ID,
+		Type:      models.PasskeyAddedEvent,
+		IP:        device.IP,
+		UserAgent: truncate(device.UserAgent, 512),
+		Details:   fmt.Sprintf("passkey %d (%s) added", passkey.ID, passkey.Name),
+	}
+	if err := NewSecurityEventService().Record(&event); err != nil {
+		return nil, err
+	}
+	if err := sendPasskeyAddedAlert(user, passkey, device); err != nil {
+		log.Printf("[MAILER] Failed to send new passkey alert to user %d: %v", user.ID, err)
+	}
+	return &passkey, nil
+}
+
+func sendPasskeyAddedAlert(user models.User, passkey models.Passkey, device Device) error {
+	body := fmt.Sprintf("Hi %s,\n\n"+
+		"A passkey named \"%s\" was added to your account. It can sign in without your password.\n\n"+
+		"Device: %s\n"+
+		"IP address: %s\n"+
+		"Time: %s\n\n"+
+		"If this was not you, remove the passkey at %s/settings/passkeys and change your password.\n",
+		user.Name, passkey.Name, device.UserAgent, device.IP, passkey.CreatedAt.UTC().Format(time.RFC1123), frontendURL())
+
+	return sendEmail(user.Email, "A passkey was added to your account", body)
+}
+
+// BeginLogin returns the options for navigator.credentials.get(). The login is discoverable:
+// the authenticator picks the account, so the client doesn't need to send an email first.
+func (s *PasskeyService) BeginLogin() (*protocol.CredentialAssertion, error) {
+	wa, err := newWebAuthn()
+	if err != nil {
+		return nil, err
+	}
+
+	assertion, session, err := wa.BeginDiscoverableLogin(webauthn.WithUserVerification(protocol.VerificationRequired))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.saveChallenge(session, models.PasskeyLogin, nil); err != nil {
+		return nil, err
+	}
+	return assertion, nil
+}
+
+// FinishLogin verifies the authenticator's assertion and returns the user it signs in.
+// A signature counter that did not increase points to a cloned authenticator: the login is
+// refused and a security event is recorded.
+func (s *PasskeyService) FinishLogin(response []byte, device Device) (*models.User, error) {
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(response)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrPasskeyVerificationFailed, err)
+	}
+
+	session, err := s.consumeChallenge(parsed.Response.CollectedClientData.Challenge, models.PasskeyLogin, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	wa, err := newWebAuthn()
+	if err != nil {
+		return nil, err
+	}
+
+	var owner webauthnUser
+	handler := func(rawID, userHandle []byte) (webauthn.User, error) {
+		userID, err := strconv.ParseUint(string(userHandle), 10, 32)
+		if err != nil {
+			return nil, ErrPasskeyNotFound
+		}
+		user, err := NewUserService().GetByID(uint(userID))
+		if err != nil {
+			return nil, ErrPasskeyNotFound
+		}
+		passkeys, err := s.List(user.ID)
+		if err != nil {
+			return nil, err
+		}
+		owner = webauthnUser{user: *user, passkeys: passkeys}
+		return owner, nil
+	}
+
+	_, credential, err := wa.ValidatePasskeyLogin(handler, *session, parsed)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrPasskeyVerificationFailed, err)
+	}
+
+	var passkey models.Passkey
+	if err := s.db.Where("user_id = ? AND credential_id = ?", owner.user.ID, credential.ID).First(&passkey).Error; err != nil {
+		return nil, err
+	}
+
+	if credential.Authenticator.CloneWarning {
+		event := models.SecurityEvent{
+			UserID:    &owner.user.ID,
+			Type:      models.PasskeyClonedEvent,
+			IP:        device.IP,
+			UserAgent: truncate(device.UserAgent, 512),
+			Details:   fmt.Sprintf("passkey %d presented sign count %d, stored %d", passkey.ID, parsed.Response.AuthenticatorData.Counter, passkey.SignCount),
+		}
+		if err := NewSecurityEventService().Record(&event); err != nil {
+			return nil, err
+		}
+		return nil, ErrPasskeyCloned
+	}
+
+	now := time.Now()
+	if err := s.db.Model(&passkey).Updates(map[string]interface{}{
+		"sign_count":   credential.Authenticator.SignCount,
+		"backup_state": credential.Flags.BackupState,
+		"last_used_at": now,
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	return &owner.user, nil
+}
+
+// List returns the user's passkeys, oldest first
+func (s *PasskeyService) List(userID uint) ([]models.Passkey, error) {
+	var passkeys []models.Passkey
+	if err := s.db.Where("user_id = ?", userID).Order("created_at ASC").Find(&passkeys).Error; err != nil {
+		return nil, err
+	}
+	return passkeys, nil
+}
+
+// Delete removes one of the user's passkeys, unless it is the only way left to sign in
+func (s *PasskeyService) Delete(userID, id uint) error {
+	var passkey models.Passkey
+	result := s.db.Where("id = ? AND user_id = ?", id, userID).First(&passkey)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return ErrPasskeyNotFound
+		}
+		return result.Error
+	}
+
+	user, err := NewUserService().GetByID(userID)
+	if err != nil {
+		return err
+	}
+	methods, err := countLoginMethods(s.db, *user)
+	if err != nil {
+		return err
+	}
+	if methods <= 1 {
+		return ErrLastLoginMethod
+	}
+
+	return s.db.Delete(&passkey).Error
+}
+
+// PurgeExpiredChallenges deletes abandoned ceremonies
+func (s *PasskeyService) PurgeExpiredChallenges() error {
+	return s.db.Where("expires_at < ?", time.Now()).Delete(&models.PasskeyChallenge{}).Error
+}
+
+func (s *PasskeyService) saveChallenge(session *webauthn.SessionData, ceremony models.PasskeyCeremony, userID *uint) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Create(&models.PasskeyChallenge{
+		Challenge:   session.Challenge,
+		Ceremony:    ceremony,
+		UserID:      userID,
+		SessionData: string(data),
+		ExpiresAt:   time.Now().Add(passkeyChallengeTTL),
+	}).Error
+}
+
+// consumeChallenge loads and deletes the ceremony a response answers, so that it can only be used once
+func (s *PasskeyService) consumeChallenge(challenge string, ceremony models.PasskeyCeremony, userID *uint) (*webauthn.SessionData, error) {
+	var pending models.PasskeyChallenge
+	result := s.db.Where("challenge = ? AND ceremony = ?", challenge, ceremony).First(&pending)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidPasskeyChallenge
+		}
+		return nil, result.Error
+	}
+
+	deleted := s.db.Delete(&models.PasskeyChallenge{}, pending.ID)
+	if deleted.Error != nil {
+		return nil, deleted.Error
+	}
+	if deleted.RowsAffected == 0 || time.Now().After(pending.ExpiresAt) {
+		return nil, ErrInvalidPasskeyChallenge
+	}
+
+	// A registration challenge only completes for the account that started it
+	if userID != nil && (pending.UserID == nil || *pending.UserID != *userID) {
+		return nil, ErrInvalidPasskeyChallenge
+	}
+
+	var session webauthn.SessionData
+	if err := json.Unmarshal([]byte(pending.SessionData), &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
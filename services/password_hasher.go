@@ -0,0 +1,264 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Password hashing algorithms, as accepted by PASSWORD_HASH_ALGORITHM
+const (
+	HashAlgorithmArgon2id = "argon2id"
+	HashAlgorithmBcrypt   = "bcrypt"
+)
+
+var ErrUnknownPasswordHash = errors.New("unknown password hash format")
+
+// PasswordHasher is one password hashing scheme. Hashes are self-describing strings
+// (PHC format for Argon2id, modular crypt format for bcrypt) that carry their parameters,
+// so hashes made with older settings keep verifying after the configuration changes.
+type PasswordHasher interface {
+	// Algorithm is the identifier used in PASSWORD_HASH_ALGORITHM
+	Algorithm() string
+	// Recognizes reports whether an encoded hash was produced by this algorithm
+	Recognizes(encoded string) bool
+	Hash(password string) (string, error)
+	Verify(password, encoded string) (bool, error)
+	// IsCurrent reports whether an encoded hash of this algorithm uses the configured parameters
+	IsCurrent(encoded string) bool
+	// MaxPasswordBytes is the longest password the algorithm hashes without truncating it
+	MaxPasswordBytes() int
+}
+
+// passwordHashers lists every supported algorithm, used to verify hashes of any of them
+func passwordHashers() []PasswordHasher {
+	return []PasswordHasher{newArgon2idHasher(), newBcryptHasher()}
+}
+
+// currentPasswordHasher returns the algorithm new hashes are made with (PASSWORD_HASH_ALGORITHM, default argon2id)
+func currentPasswordHasher() PasswordHasher {
+	if os.Getenv("PASSWORD_HASH_ALGORITHM") == HashAlgorithmBcrypt {
+		return newBcryptHasher()
+	}
+	return newArgon2idHasher()
+}
+
+func hasherFor(encoded string) (PasswordHasher, error) {
+	for _, hasher := range passwordHashers() {
+		if hasher.Recognizes(encoded) {
+			return hasher, nil
+		}
+	}
+	return nil, ErrUnknownPasswordHash
+}
+
+// HashPassword hashes a password with the configured algorithm and parameters
+func HashPassword(password string) (string, error) {
+	return currentPasswordHasher().Hash(password)
+}
+
+// CheckHashedPassword reports whether the password matches a hash of any supported algorithm
+func CheckHashedPassword(password, hash string) bool {
+	hasher, err := hasherFor(hash)
+	if err != nil {
+		return false
+	}
+
+	ok, err := hasher.Verify(password, hash)
+	return err == nil && ok
+}
+
+// PasswordNeedsRehash reports whether a hash was made with another algorithm or other parameters
+// than the configured ones. It should be replaced the next time the plain password is known.
+func PasswordNeedsRehash(hash string) bool {
+	current := currentPasswordHasher()
+	return !current.Recognizes(hash) || !current.IsCurrent(hash)
+}
+
+var (
+	dummyHashMu sync.Mutex
+	dummyHash   string
+)
+
+// CheckDummyPassword verifies a password against a throwaway hash made with the configured
+// algorithm and parameters, so rejecting an unknown account costs as much as a wrong password.
+// The hash is made on first use and again whenever the configuration changes.
+func CheckDummyPassword(password string) {
+	dummyHashMu.Lock()
+	if dummyHash == "" || PasswordNeedsRehash(dummyHash) {
+		hash, err := HashPassword(rand.Text())
+		if err != nil {
+			dummyHashMu.Unlock()
+			return
+		}
+		dummyHash = hash
+	}
+	hash := dummyHash
+	dummyHashMu.Unlock()
+
+	CheckHashedPassword(password, hash)
+}
+
+// bcryptHasher hashes with bcrypt at BCRYPT_COST (default bcrypt.DefaultCost)
+type bcryptHasher struct {
+	cost int
+}
+
+func newBcryptHasher() bcryptHasher {
+	cost := intFromEnv("BCRYPT_COST", bcrypt.DefaultCost)
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		cost = bcrypt.DefaultCost
+	}
+	return bcryptHasher{cost: cost}
+}
+
+func (h bcryptHasher) Algorithm() string { return HashAlgorithmBcrypt }
+
+func (h bcryptHasher) Recognizes(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$")
+}
+
+func (h bcryptHasher) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+func (h bcryptHasher) Verify(password, encoded string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (h bcryptHasher) IsCurrent(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err == nil && cost == h.cost
+}
+
+func (h bcryptHasher) MaxPasswordBytes() int { return 72 }
+
+// argon2Params are the Argon2id settings, encoded into every hash
+type argon2Params struct {
+	memory      uint32 // KiB
+	iterations  uint32
+	parallelism uint8
+	saltLength  int
+	keyLength   uint32
+}
+
+// argon2idHasher hashes with Argon2id. The defaults follow the OWASP recommendation of
+// 64 MiB, 3 iterations and parallelism 2 (ARGON2_MEMORY_KIB, ARGON2_ITERATIONS, ARGON2_PARALLELISM).
+type argon2idHasher struct {
+	params argon2Params
+}
+
+func newArgon2idHasher() argon2idHasher {
+	iterations := intFromEnv("ARGON2_ITERATIONS", 3)
+	if iterations < 1 || iterations > math.MaxUint32 {
+		iterations = 3
+	}
+	parallelism := intFromEnv("ARGON2_PARALLELISM", 2)
+	if parallelism < 1 || parallelism > math.MaxUint8 {
+		parallelism = 2
+	}
+	// Argon2 needs at least 8 KiB per lane
+	memory := intFromEnv("ARGON2_MEMORY_KIB", 64*1024)
+	if memory < 8*parallelism || memory > math.MaxUint32 {
+		memory = 64 * 1024
+	}
+
+	return argon2idHasher{params: argon2Params{
+		memory:      uint32(memory),
+		iterations:  uint32(iterations),
+		parallelism: uint8(parallelism),
+		saltLength:  16,
+		keyLength:   32,
+	}}
+}
+
+func (h argon2idHasher) Algorithm() string { return HashAlgorithmArgon2id }
+
+func (h argon2idHasher) Recognizes(encoded string) bool {
+	return strings.HasPrefix(encoded, "$argon2id$")
+}
+
+func (h argon2idHasher) Hash(password string) (string, error) {
+	p := h.params
+	salt := make([]byte, p.saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, p.iterations, p.memory, p.parallelism, p.keyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.memory, p.iterations, p.parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func (h argon2idHasher) Verify(password, encoded string) (bool, error) {
+	p, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return false, err
+	}
+
+	candidate := argon2.IDKey([]byte(password), salt, p.iterations, p.memory, p.parallelism, uint32(len(key)))
+	return subtle.ConstantTimeCompare(candidate, key) == 1, nil
+}
+
+func (h argon2idHasher) IsCurrent(encoded string) bool {
+	p, _, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return false
+	}
+	return p.memory == h.params.memory &&
+		p.iterations == h.params.iterations &&
+		p.parallelism == h.params.parallelism &&
+		uint32(len(key)) == h.params.keyLength
+}
+
+func (h argon2idHasher) MaxPasswordBytes() int { return 1024 }
+
+// decodeArgon2id parses a PHC string: $argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>
+func decodeArgon2id(encoded string) (argon2Params, []byte, []byte, error) {
+	var p argon2Params
+
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != HashAlgorithmArgon2id {
+		return p, nil, nil, ErrUnknownPasswordHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, nil, nil, fmt.Errorf("unsupported argon2 version %q", parts[2])
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.memory, &p.iterations, &p.parallelism); err != nil {
+		return p, nil, nil, fmt.Errorf("invalid argon2 parameters: %w", err)
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return p, nil, nil, err
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return p, nil, nil, err
+	}
+
+	p.saltLength = len(salt)
+	p.keyLength = uint32(len(key))
+	return p, salt, key, nil
+}
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"go-crud/schemas"
+	"strings"
+	"sync"
+)
+
+// Password policy violation codes
+const (
+	PasswordTooShort      = "too_short"
+	PasswordTooLong       = "too_long"
+	PasswordCommon        = "common_password"
+	PasswordContainsEmail = "contains_email"
+)
+
+//go:embed data/common_passwords.txt
+var commonPasswordsFile string
+
+var commonPasswords = sync.OnceValue(func() map[string]bool {
+	passwords := map[string]bool{}
+	scanner := bufio.NewScanner(strings.NewReader(commonPasswordsFile))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			passwords[strings.ToLower(line)] = true
+		}
+	}
+	return passwords
+})
+
+// PasswordMinLength returns the minimum password length in characters (PASSWORD_MIN_LENGTH, default 8)
+func PasswordMinLength() int {
+	return intFromEnv("PASSWORD_MIN_LENGTH", 8)
+}
+
+// PasswordRejectCommon reports whether passwords from the bundled common password list are refused
+// (PASSWORD_REJECT_COMMON, default true)
+func PasswordRejectCommon() bool {
+	return boolFromEnv("PASSWORD_REJECT_COMMON", true)
+}
+
+// PasswordPolicyError lists every rule a password breaks
+type PasswordPolicyError struct {
+	Violations []schemas.PasswordViolation
+}
+
+func (e *PasswordPolicyError) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, violation := range e.Violations {
+		messages = append(messages, violation.Message)
+	}
+	return "password does not meet the policy: " + strings.Join(messages, "; ")
+}
+
+// ValidatePassword checks a new password against the policy. The email is the address of the
+// account the password is for. It returns a *PasswordPolicyError listing all violations.
+func ValidatePassword(password, email string) error {
+	var violations []schemas.PasswordViolation
+
+	if minLength := PasswordMinLength(); len([]rune(password)) < minLength {
+		violations = append(violations, schemas.PasswordViolation{
+			Code:    PasswordTooShort,
+			Message: fmt.Sprintf("must be at least %d characters long", minLength),
+		})
+	}
+	if maxBytes := currentPasswordHasher().MaxPasswordBytes(); len(password) > maxBytes {
+		violations = append(violations, schemas.PasswordViolation{
+			Code:    PasswordTooLong,
+			Message: fmt.Sprintf("must be at most %d bytes long", maxBytes),
+		})
+	}
+
+	lowered := strings.ToLower(password)
+	if PasswordRejectCommon() && commonPasswords()[lowered] {
+		violations = append(violations, schemas.PasswordViolation{
+			Code:    PasswordCommon,
+			Message: "is too common, it appears in lists of breached passwords",
+		})
+	}
+
+	// Checking the local part too catches "connor2024" for connor@example.com
+	email = strings.ToLower(strings.TrimSpace(email))
+	localPart, _, _ := strings.Cut(email, "@")
+	if email != "" && (strings.Contains(lowered, email) || (len(localPart) >= 3 && strings.Contains(lowered, localPart))) {
+		violations = append(violations, schemas.PasswordViolation{
+			Code:    PasswordContainsEmail,
+			Message: "must not contain your email address",
+		})
+	}
+
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
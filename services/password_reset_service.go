@@ -0,0 +1,106 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"go-crud/initializers"
+	"go-crud/models"
+	"log"
+	"net/url"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrInvalidResetToken = errors.New("invalid or expired reset token")
+
+// PasswordResetService handles the forgot/reset password flow
+type PasswordResetService struct {
+	db *gorm.DB
+}
+
+// NewPasswordResetService creates a new PasswordResetService instance
+func NewPasswordResetService() *PasswordResetService {
+	return &PasswordResetService{
+		db: initializers.DB,
+	}
+}
+
+// PasswordResetTTL returns how long a reset link stays valid (PASSWORD_RESET_TTL, default 1 hour)
+func PasswordResetTTL() time.Duration {
+	return durationFromEnv("PASSWORD_RESET_TTL", time.Hour)
+}
+
+// RequestReset emails a reset link to the account with the given email.
+// It succeeds silently for unknown emails so callers cannot probe which accounts exist.
+func (s *PasswordResetService) RequestReset(email string) error {
+	user, err := NewUserService().FindByEmail(email)
+	if err != nil {
+		if err.Error() == "user not found" {
+			return nil
+		}
+		return err
+	}
+
+	token, err := NewOneTimeTokenService().Issue(user.ID, models.PasswordResetPurpose, PasswordResetTTL())
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/reset-password?token=%s", frontendURL(), url.QueryEscape(token))
+	body := fmt.Sprintf("Hi %s,\n\n"+
+		"We received a request to reset your password. Use the link below to choose a new one:\n\n"+
+		"%s\n\n"+
+		"The link expires in %s and can only be used once. "+
+		"If you did not request a reset, you can ignore this email.\n",
+		user.Name, link, PasswordResetTTL())
+
+	// Delivery failures are logged rather than returned, so they cannot reveal that the account exists
+	if err := sendEmail(user.Email, "Reset your password", body); err != nil {
+		log.Printf("[MAILER] Failed to send password reset email to user %d: %v", user.ID, err)
+	}
+	return nil
+}
+
+// ResetPassword consumes a reset token, sets the new password and logs the user out everywhere.
+// A password that breaks the policy leaves the token usable for another try.
+func (s *PasswordResetService) ResetPassword(token, newPassword string) error {
+	tokens := NewOneTimeTokenService()
+	userID, err := tokens.Lookup(token, models.PasswordResetPurpose)
+	if err != nil {
+		if errors.Is(err, ErrInvalidOneTimeToken) {
+			return ErrInvalidResetToken
+		}
+		return err
+	}
+
+	user, err := NewUserService().GetByID(userID)
+	if err != nil {
+		return ErrInvalidResetToken
+	}
+	if err := ValidatePassword(newPassword, user.Email); err != nil {
+		return err
+	}
+
+	if _, err := tokens.Consume(token, models.PasswordResetPurpose); err != nil {
+		if errors.Is(err, ErrInvalidOneTimeToken) {
+			return ErrInvalidResetToken
+		}
+		return err
+	}
+
+	hashedPassword, err := HashPassword(newPassword)
+	if err != nil {
+		return errors.New("failed to hash password")
+	}
+
+	result := s.db.Model(&models.User{}).Where("id = ?", userID).Update("hashed_password", hashedPassword)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvalidResetToken
+	}
+
+	return NewTokenRevocationService().RevokeAllForUser(userID)
+}
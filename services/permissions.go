@@ -0,0 +1,77 @@
+package services
+
+import (
+	"errors"
+	"go-crud/models"
+)
+
+// Permissions checked by RequirePermission and the post policy
+const (
+	PermPostsCreate      = "posts:create"
+	PermPostsUpdateOwn   = "posts:update:own"
+	PermPostsUpdateAny   = "posts:update:any"
+	PermPostsDeleteOwn   = "posts:delete:own"
+	PermPostsDeleteAny   = "posts:delete:any"
+	PermTagsCreate       = "tags:create"
+	PermRedirectsManage  = "redirects:manage"
+	PermTagsUpdate       = "tags:update"
+	PermTagsDelete       = "tags:delete"
+	PermUsersDelete      = "users:delete"
+	PermUsersManage      = "users:manage"
+	PermUsersImpersonate = "users:impersonate"
+	PermInvitesManage    = "invites:manage"
+)
+
+var ErrInvalidRole = errors.New("invalid role")
+
+// RolePermissions lists what each role may do. Every role includes the permissions of the one before it.
+var RolePermissions = map[models.Role][]string{}
+
+func init() {
+	reader := []string{}
+	author := append(reader,
+		PermPostsCreate,
+		PermPostsUpdateOwn,
+		PermPostsDeleteOwn,
+		PermTagsCreate,
+	)
+	editor := append(author,
+		PermPostsUpdateAny,
+		PermPostsDeleteAny,
+		PermRedirectsManage,
+		PermTagsUpdate,
+		PermTagsDelete,
+	)
+	admin := append(editor,
+		PermUsersDelete,
+		PermUsersManage,
+		PermUsersImpersonate,
+		PermInvitesManage,
+	)
+
+	RolePermissions[models.RoleReader] = reader
+	RolePermissions[models.RoleAuthor] = author
+	RolePermissions[models.RoleEditor] = editor
+	RolePermissions[models.RoleAdmin] = admin
+}
+
+// HasPermission reports whether the role grants the permission
+func HasPermission(role models.Role, permission string) bool {
+	for _, granted := range RolePermissions[role] {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// Actor is the authenticated user a request is made on behalf of
+type Actor struct {
+	UserID uint
+	Role   models.Role
+}
+
+// Can reports whether the actor's role grants the permission
+func (a Actor) Can(permission string) bool {
+	return HasPermission(a.Role, permission)
+}
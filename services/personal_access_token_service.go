@@ -0,0 +1,138 @@
+package services
+
+import (
+	"errors"
+	"go-crud/initializers"
+	"go-crud/models"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PersonalAccessTokenPrefix marks personal access tokens so they can be told apart from JWTs
+// (and picked up by secret scanners)
+const PersonalAccessTokenPrefix = "hbp_"
+
+// Scopes a personal access token can be granted
+const (
+	ScopeRead       = "read"
+	ScopePostsWrite = "posts:write"
+	ScopeTagsWrite  = "tags:write"
+)
+
+// lastUsedResolution limits how often last_used_at is written for a busy token
+const lastUsedResolution = time.Minute
+
+var (
+	ErrInvalidPersonalAccessToken  = errors.New("invalid, expired or revoked personal access token")
+	ErrPersonalAccessTokenNotFound = errors.New("personal access token not found")
+	ErrInvalidScope                = errors.New("invalid scope")
+)
+
+// PersonalAccessTokenService handles scoped, long-lived API tokens
+type PersonalAccessTokenService struct {
+	db *gorm.DB
+}
+
+// NewPersonalAccessTokenService creates a new PersonalAccessTokenService instance
+func NewPersonalAccessTokenService() *PersonalAccessTokenService {
+	return &PersonalAccessTokenService{
+		db: initializers.DB,
+	}
+}
+
+// IsValidScope reports whether scope is one a token can be granted
+func IsValidScope(scope string) bool {
+	switch scope {
+	case ScopeRead, ScopePostsWrite, ScopeTagsWrite:
+		return true
+	}
+	return false
+}
+
+// IsPersonalAccessToken reports whether a bearer token looks like a personal access token
+func IsPersonalAccessToken(token string) bool {
+	return strings.HasPrefix(token, PersonalAccessTokenPrefix)
+}
+
+// Create issues a new token and returns it with its secret, which is never retrievable again
+func (s *PersonalAccessTokenService) Create(userID uint, name string, scopes []string, expiresAt *time.Time) (*models.PersonalAccessToken, string, error) {
+	if len(scopes) == 0 {
+		return nil, "", ErrInvalidScope
+	}
+	for _, scope := range scopes {
+		if !IsValidScope(scope) {
+			return nil, "", ErrInvalidScope
+		}
+	}
+
+	secret, _, err := newOpaqueToken()
+	if err != nil {
+		return nil, "", err
+	}
+	token := PersonalAccessTokenPrefix + secret
+
+	pat := models.PersonalAccessToken{
+		UserID:    userID,
+		Name:      name,
+		Prefix:    token[:len(PersonalAccessTokenPrefix)+8],
+		TokenHash: hashOpaqueToken(token),
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+	}
+	if err := s.db.Create(&pat).Error; err != nil {
+		return nil, "", err
+	}
+
+	return &pat, token, nil
+}
+
+// List returns the user's tokens that have not been revoked, newest first
+func (s *PersonalAccessTokenService) List(userID uint) ([]models.PersonalAccessToken, error) {
+	var tokens []models.PersonalAccessToken
+	result := s.db.Where("user_id = ? AND revoked_at IS NULL", userID).Order("created_at DESC").Find(&tokens)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return tokens, nil
+}
+
+// Revoke revokes one of the user's tokens
+func (s *PersonalAccessTokenService) Revoke(userID, id uint) error {
+	result := s.db.Model(&models.PersonalAccessToken{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrPersonalAccessTokenNotFound
+	}
+	return nil
+}
+
+// Authenticate resolves a presented token and records that it was used
+func (s *PersonalAccessTokenService) Authenticate(token string) (*models.PersonalAccessToken, error) {
+	var pat models.PersonalAccessToken
+	result := s.db.Where("token_hash = ?", hashOpaqueToken(token)).First(&pat)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidPersonalAccessToken
+		}
+		return nil, result.Error
+	}
+
+	now := time.Now()
+	if pat.RevokedAt != nil || (pat.ExpiresAt != nil && now.After(*pat.ExpiresAt)) {
+		return nil, ErrInvalidPersonalAccessToken
+	}
+
+	if pat.LastUsedAt == nil || now.Sub(*pat.LastUsedAt) > lastUsedResolution {
+		if err := s.db.Model(&pat).Update("last_used_at", now).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return &pat, nil
+}
@@ -0,0 +1,22 @@
+package services
+
+import "go-crud/models"
+
+// The post policy is the single place that decides who may act on a post.
+// Views must go through it instead of comparing post.UserID themselves.
+
+// CanUpdatePost reports whether the actor may edit the post: its author, or an editor or admin
+func CanUpdatePost(actor Actor, post models.Post) bool {
+	if post.UserID == actor.UserID && actor.Can(PermPostsUpdateOwn) {
+		return true
+	}
+	return actor.Can(PermPostsUpdateAny)
+}
+
+// CanDeletePost reports whether the actor may delete the post: its author, or an editor or admin
+func CanDeletePost(actor Actor, post models.Post) bool {
+	if post.UserID == actor.UserID && actor.Can(PermPostsDeleteOwn) {
+		return true
+	}
+	return actor.Can(PermPostsDeleteAny)
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"errors"
+	"go-crud/initializers"
+	"go-crud/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrRedirectNotFound          = errors.New("redirect not found")
+	ErrRedirectExists            = errors.New("a redirect from this slug already exists")
+	ErrRedirectTargetUnavailable = errors.New("redirects can only point at published posts")
+)
+
+// PostRedirectService keeps old slugs and manual redirects pointing at posts
+type PostRedirectService struct {
+	db *gorm.DB
+}
+
+// NewPostRedirectService creates a new PostRedirectService instance
+func NewPostRedirectService() *PostRedirectService {
+	return &PostRedirectService{
+		db: initializers.DB,
+	}
+}
+
+// Resolve returns the post a slug redirects to. Redirects to posts that are not published are
+// ignored, so they never leak drafts.
+func (s *PostRedirectService) Resolve(slug string) (*models.Post, error) {
+	published, args := effectiveStatusCondition(models.Published, time.Now())
+
+	var post models.Post
+	result := s.db.Joins("JOIN post_redirects ON post_redirects.post_id = posts.id").
+		Where("post_redirects.from_slug = ?", slug).
+		Where(published, args...).
+		First(&post)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, ErrRedirectNotFound
+		}
+		return nil, result.Error
+	}
+	return &post, nil
+}
+
+// Create adds a manual redirect from a slug to a published post. The slug must not be in use by a
+// post or by another redirect.
+func (s *PostRedirectService) Create(fromSlug string, postID uint, createdByID uint) (*models.PostRedirect, error) {
+	slug := Slugify(fromSlug)
+	if slug == "" {
+		return nil, ErrInvalidSlug
+	}
+
+	var post models.Post
+	result := s.db.First(&post, postID)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, ErrRedirectTargetUnavailable
+		}
+		return nil, result.Error
+	}
+	if post.EffectiveStatus(time.Now()) != models.Published {
+		return nil, ErrRedirectTargetUnavailable
+	}
+
+	var count int64
+	if err := s.db.Model(&models.Post{}).Where("slug = ?", slug).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, ErrSlugTaken
+	}
+	if err := s.db.Model(&models.PostRedirect{}).Where("from_slug = ?", slug).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, ErrRedirectExists
+	}
+
+	redirect := models.PostRedirect{
+		FromSlug:    slug,
+		PostID:      post.ID,
+		Kind:        models.RedirectManual,
+		CreatedByID: &createdByID,
+	}
+	if err := s.db.Create(&redirect).Error; err != nil {
+		return nil, err
+	}
+	return &redirect, nil
+}
+
+// List returns redirects, most recent first, optionally only those to one post
+func (s *PostRedirectService) List(postID *uint) ([]models.PostRedirect, error) {
+	query := s.db.Order("created_at DESC, id DESC")
+	if postID != nil {
+		query = query.Where("post_id = ?", *postID)
+	}
+
+	var redirects []models.PostRedirect
+	if err := query.Find(&redirects).Error; err != nil {
+		return nil, err
+	}
+	return redirects, nil
+}
+
+// Delete removes a redirect, manual or recorded from a slug change
+func (s *PostRedirectService) Delete(id uint) error {
+	result := s.db.Delete(&models.PostRedirect{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrRedirectNotFound
+	}
+	return nil
+}
+
+// recordSlugChange keeps the previous slug of a post redirecting to it. The new slug is live again,
+// so the post's own redirect from it, if any, is dropped; customSlug has already refused slugs that
+// redirect to other posts.
+func recordSlugChange(tx *gorm.DB, postID uint, previous, current string) error {
+	if err := tx.Where("from_slug = ? AND post_id = ?", current, postID).Delete(&models.PostRedirect{}).Error; err != nil {
+		return err
+	}
+	redirect := models.PostRedirect{
+		FromSlug: previous,
+		PostID:   postID,
+		Kind:     models.RedirectHistory,
+	}
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "from_slug"}},
+		DoUpdates: clause.AssignmentColumns([]string{"post_id", "kind", "created_by_id", "created_at"}),
+	}).Create(&redirect).Error
+}
@@ -0,0 +1,203 @@
+package services
+
+import (
+	"errors"
+	"go-crud/initializers"
+	"go-crud/models"
+	"go-crud/schemas"
+	"strings"
+	"time"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Kinds of lines in a revision diff
+const (
+	DiffEqual  = "equal"
+	DiffInsert = "insert"
+	DiffDelete = "delete"
+)
+
+var ErrRevisionNotFound = errors.New("revision not found")
+
+// PostRevisionRetentionCount returns how many of the latest revisions of each post are always kept
+// (POST_REVISION_RETENTION_COUNT, default 50). 0 keeps every revision.
+func PostRevisionRetentionCount() int {
+	count := intFromEnv("POST_REVISION_RETENTION_COUNT", 50)
+	if count < 0 {
+		return 50
+	}
+	return count
+}
+
+// PostRevisionRetentionPeriod returns the age after which revisions are pruned even when they are
+// among the latest ones (POST_REVISION_RETENTION_PERIOD, e.g. "2160h"). Unset means no age limit.
+func PostRevisionRetentionPeriod() time.Duration {
+	return durationFromEnv("POST_REVISION_RETENTION_PERIOD", 0)
+}
+
+// PostRevisionService reads, compares and restores the revisions of posts
+type PostRevisionService struct {
+	db *gorm.DB
+}
+
+// NewPostRevisionService creates a new PostRevisionService instance
+func NewPostRevisionService() *PostRevisionService {
+	return &PostRevisionService{
+		db: initializers.DB,
+	}
+}
+
+// List returns the revisions of a post, latest first, without their content
+func (s *PostRevisionService) List(postID uint) ([]models.PostRevision, error) {
+	var revisions []models.PostRevision
+	if err := s.db.Omit("content_markdown", "content_json").
+		Where("post_id = ?", postID).
+		Order("number DESC").
+		Find(&revisions).Error; err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+// Get returns a revision of a post by its number
+func (s *PostRevisionService) Get(postID uint, number int) (*models.PostRevision, error) {
+	var revision models.PostRevision
+	result := s.db.Where("post_id = ? AND number = ?", postID, number).First(&revision)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, ErrRevisionNotFound
+		}
+		return nil, result.Error
+	}
+	return &revision, nil
+}
+
+// Diff compares the markdown of two revisions of a post line by line, from the first to the second
+func (s *PostRevisionService) Diff(postID uint, from, to int) ([]schemas.PostRevisionDiffLine, error) {
+	older, err := s.Get(postID, from)
+	if err != nil {
+		return nil, err
+	}
+	newer, err := s.Get(postID, to)
+	if err != nil {
+		return nil, err
+	}
+	return diffLines(older.ContentMarkdown, newer.ContentMarkdown), nil
+}
+
+// Restore brings the title and content of an old revision back into the post. This is a save like
+// any other: it records a new revision, and the history in between is kept.
+func (s *PostRevisionService) Restore(postID uint, number int, authorID uint) (*models.Post, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var post models.Post
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&post, postID)
+		if result.Error != nil {
+			if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+				return errors.New("post not found")
+			}
+			return result.Error
+		}
+
+		var revision models.PostRevision
+		result = tx.Where("post_id = ? AND number = ?", postID, number).First(&revision)
+		if result.Error != nil {
+			if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+				return ErrRevisionNotFound
+			}
+			return result.Error
+		}
+
+		post.Title = revision.Title
+		post.ContentMarkdown = revision.ContentMarkdown
+		post.ContentJSON = revision.ContentJSON
+		if err := tx.Save(&post).Error; err != nil {
+			return err
+		}
+		return recordRevision(tx, post, authorID, &revision.Number)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return NewPostService().GetByID(postID)
+}
+
+// PruneExpired deletes the revisions that fall outside the retention policy. The latest revision of
+// a post mirrors its current content and is never deleted.
+func (s *PostRevisionService) PruneExpired() error {
+	latest := "(SELECT MAX(latest.number) FROM post_revisions latest WHERE latest.post_id = post_revisions.post_id)"
+
+	if count := PostRevisionRetentionCount(); count > 0 {
+		if err := s.db.Where("number <= "+latest+" - ?", count).Delete(&models.PostRevision{}).Error; err != nil {
+			return err
+		}
+	}
+
+	if period := PostRevisionRetentionPeriod(); period > 0 {
+		if err := s.db.Where("created_at < ? AND number < "+latest, time.Now().Add(-period)).Delete(&models.PostRevision{}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordRevision snapshots the post as it was just saved. It runs in the transaction of the save,
+// after it, so the post row is locked while the next number is picked.
+func recordRevision(tx *gorm.DB, post models.Post, authorID uint, restoredFrom *int) error {
+	var last int
+	if err := tx.Model(&models.PostRevision{}).
+		Where("post_id = ?", post.ID).
+		Select("COALESCE(MAX(number), 0)").
+		Scan(&last).Error; err != nil {
+		return err
+	}
+
+	revision := models.PostRevision{
+		PostID:          post.ID,
+		Number:          last + 1,
+		Title:           post.Title,
+		ContentMarkdown: post.ContentMarkdown,
+		ContentJSON:     post.ContentJSON,
+		RestoredFrom:    restoredFrom,
+	}
+	if authorID != 0 {
+		revision.AuthorID = &authorID
+	}
+	return tx.Create(&revision).Error
+}
+
+// diffLines returns every line of both texts, marking those only in before as deleted and those
+// only in after as inserted
+func diffLines(before, after string) []schemas.PostRevisionDiffLine {
+	a := strings.Split(before, "\n")
+	b := strings.Split(after, "\n")
+
+	// Without autojunk, so that blank lines and other frequent ones are matched like the rest
+	matcher := difflib.NewMatcherWithJunk(a, b, false, nil)
+
+	lines := []schemas.PostRevisionDiffLine{}
+	for _, op := range matcher.GetOpCodes() {
+		if op.Tag == 'e' {
+			for i := 0; i < op.I2-op.I1; i++ {
+				lines = append(lines, schemas.PostRevisionDiffLine{
+					Type:      DiffEqual,
+					OldNumber: op.I1 + i + 1,
+					NewNumber: op.J1 + i + 1,
+					Text:      a[op.I1+i],
+				})
+			}
+			continue
+		}
+		// Replacements are shown as the old lines removed, then the new ones added
+		for i := op.I1; i < op.I2; i++ {
+			lines = append(lines, schemas.PostRevisionDiffLine{Type: DiffDelete, OldNumber: i + 1, Text: a[i]})
+		}
+		for j := op.J1; j < op.J2; j++ {
+			lines = append(lines, schemas.PostRevisionDiffLine{Type: DiffInsert, NewNumber: j + 1, Text: b[j]})
+		}
+	}
+	return lines
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"errors"
+	"go-crud/initializers"
+	"go-crud/models"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// postSchedulerLockKey identifies the Postgres advisory lock held by the replica running the scheduler
+const postSchedulerLockKey = 4_307_221_024
+
+var (
+	ErrPublishAtRequired  = errors.New("publish_at is required for scheduled posts")
+	ErrInvalidUnpublishAt = errors.New("unpublish_at must be in the future and after publish_at")
+)
+
+// PostSchedulerInterval returns how often due posts are published and unpublished
+// (POST_SCHEDULER_INTERVAL, default 1 minute)
+func PostSchedulerInterval() time.Duration {
+	return durationFromEnv("POST_SCHEDULER_INTERVAL", time.Minute)
+}
+
+// PostSchedulerService publishes scheduled posts and unpublishes embargoed ones when their time comes
+type PostSchedulerService struct {
+	db *gorm.DB
+}
+
+// NewPostSchedulerService creates a new PostSchedulerService instance
+func NewPostSchedulerService() *PostSchedulerService {
+	return &PostSchedulerService{
+		db: initializers.DB,
+	}
+}
+
+// RunDue publishes the scheduled posts whose publish_at has passed and puts the posts whose
+// unpublish_at has passed back to draft. Every replica runs it, but only the one holding the
+// advisory lock does the work; the others skip the tick. Rows are also claimed with SKIP LOCKED,
+// so a post being saved by a request is left for the next tick.
+func (s *PostSchedulerService) RunDue() error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var leader bool
+		if err := tx.Raw("SELECT pg_try_advisory_xact_lock(?)", postSchedulerLockKey).Scan(&leader).Error; err != nil {
+			return err
+		}
+		if !leader {
+			return nil
+		}
+
+		now := time.Now()
+
+		var unpublished []uint
+		if err := tx.Model(&models.Post{}).
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("unpublish_at <= ?", now).
+			Pluck("id", &unpublished).Error; err != nil {
+			return err
+		}
+		if len(unpublished) > 0 {
+			if err := tx.Model(&models.Post{}).Where("id IN ?", unpublished).Updates(map[string]interface{}{
+				"status":       models.Draft,
+				"publish_at":   nil,
+				"unpublish_at": nil,
+			}).Error; err != nil {
+				return err
+			}
+			log.Printf("[SCHEDULER] unpublished posts %v", unpublished)
+		}
+
+		var published []uint
+		if err := tx.Model(&models.Post{}).
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND publish_at <= ?", models.Scheduled, now).
+			Pluck("id", &published).Error; err != nil {
+			return err
+		}
+		if len(published) > 0 {
+			if err := tx.Model(&models.Post{}).Where("id IN ?", published).Update("status", models.Published).Error; err != nil {
+				return err
+			}
+			log.Printf("[SCHEDULER] published posts %v", published)
+		}
+		return nil
+	})
+}
+
+// applySchedule checks the publishing times of a post about to be saved. Drafts have none; a
+// scheduled post needs publish_at, and is published right away when it has already passed.
+func applySchedule(post *models.Post, now time.Time) error {
+	switch post.Status {
+	case models.Draft:
+		post.PublishAt = nil
+		post.UnpublishAt = nil
+	case models.Scheduled:
+		if post.PublishAt == nil {
+			return ErrPublishAtRequired
+		}
+		if !post.PublishAt.After(now) {
+			post.Status = models.Published
+		}
+	}
+
+	if post.UnpublishAt != nil {
+		if !post.UnpublishAt.After(now) || (post.PublishAt != nil && !post.UnpublishAt.After(*post.PublishAt)) {
+			return ErrInvalidUnpublishAt
+		}
+	}
+	return nil
+}
+
+// effectiveStatusCondition returns a condition on posts matching those whose status is effectively
+// the given one at the given time, see models.Post.EffectiveStatus
+func effectiveStatusCondition(status models.PostStatus, now time.Time) (string, []interface{}) {
+	switch status {
+	case models.Published:
+		return "((posts.status = ? OR (posts.status = ? AND posts.publish_at <= ?)) AND (posts.unpublish_at IS NULL OR posts.unpublish_at > ?))",
+			[]interface{}{models.Published, models.Scheduled, now, now}
+	case models.Scheduled:
+		return "(posts.status = ? AND posts.publish_at > ? AND (posts.unpublish_at IS NULL OR posts.unpublish_at > ?))",
+			[]interface{}{models.Scheduled, now, now}
+	default:
+		return "(posts.status = ? OR posts.unpublish_at <= ?)", []interface{}{status, now}
+	}
+}
@@ -0,0 +1,176 @@
+package services
+
+import (
+	"errors"
+	"go-crud/initializers"
+	"go-crud/models"
+	"go-crud/schemas"
+	"html"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// postSearchVector is the document posts are searched in: the title weighs more than the content.
+// It must stay identical to the expression of the idx_posts_search GIN index for the index to be used.
+const postSearchVector = "(setweight(to_tsvector('english', coalesce(posts.title, '')), 'A') || " +
+	"setweight(to_tsvector('english', coalesce(posts.content_markdown, '')), 'B'))"
+
+// Postgres marks matched words with private-use characters rather than HTML, since the text around
+// them is the post's own and has to be escaped first; see renderHighlight
+const (
+	highlightStart = "\ue000"
+	highlightStop  = "\ue001"
+)
+
+// Snippets are cut around the best matches, titles are highlighted whole
+const (
+	postTitleHeadlineOptions   = `HighlightAll=true, StartSel="` + highlightStart + `", StopSel="` + highlightStop + `"`
+	postSnippetHeadlineOptions = `StartSel="` + highlightStart + `", StopSel="` + highlightStop + `", ` +
+		`MaxWords=35, MinWords=15, MaxFragments=2, FragmentDelimiter=" ... "`
+)
+
+var highlightMarks = strings.NewReplacer(highlightStart, "<mark>", highlightStop, "</mark>")
+
+var ErrEmptySearchQuery = errors.New("search query has no words to search for")
+
+var (
+	searchTermPattern = regexp.MustCompile(`"([^"]*)"?|(\S+)`)
+	searchWordPattern = regexp.MustCompile(`[\p{L}\p{N}]+`)
+)
+
+// PostSearchService runs full-text searches over posts
+type PostSearchService struct {
+	db *gorm.DB
+}
+
+// NewPostSearchService creates a new PostSearchService instance
+func NewPostSearchService() *PostSearchService {
+	return &PostSearchService{
+		db: initializers.DB,
+	}
+}
+
+// postSearchRow is a match before its post is loaded
+type postSearchRow struct {
+	ID             uint
+	Rank           float64
+	TitleHighlight string
+	Snippet        string
+}
+
+// Search returns the posts matching the query, best matches first, with the same filters as post
+// lists. Words must all appear, in any form ("running" finds "run"); "quoted words" must appear
+// in that order, and a word ending with * matches every word starting with it.
+func (s *PostSearchService) Search(query schemas.SearchPostsQueryParams) ([]schemas.PostSearchResult, int64, error) {
+	tsquery, args := searchTSQuery(query.Query)
+	if tsquery == "" {
+		return nil, 0, ErrEmptySearchQuery
+	}
+
+	// The query is computed once and joined to every post
+	db := filterPosts(s.db.Model(&models.Post{}), query.ListPostsQueryParams).
+		Joins("CROSS JOIN (SELECT "+tsquery+" AS query) AS search", args...).
+		Where(postSearchVector + " @@ search.query")
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var rows []postSearchRow
+	offset := (query.Page - 1) * query.Limit
+	if err := db.Select("posts.id, "+
+		"ts_rank("+postSearchVector+", search.query) AS rank, "+
+		"ts_headline('english', posts.title, search.query, ?) AS title_highlight, "+
+		"ts_headline('english', coalesce(posts.content_markdown, ''), search.query, ?) AS snippet",
+		postTitleHeadlineOptions, postSnippetHeadlineOptions).
+		Order("rank DESC, posts.created_at DESC, posts.id DESC").
+		Limit(query.Limit).
+		Offset(offset).
+		Scan(&rows).Error; err != nil {
+		return nil, 0, err
+	}
+	if len(rows) == 0 {
+		return []schemas.PostSearchResult{}, total, nil
+	}
+
+	ids := make([]uint, len(rows))
+	for i, row := range rows {
+		ids[i] = row.ID
+	}
+	var posts []models.Post
+	if err := s.db.Preload("User").Preload("Tags").Where("id IN ?", ids).Find(&posts).Error; err != nil {
+		return nil, 0, err
+	}
+	byID := make(map[uint]models.Post, len(posts))
+	for _, post := range posts {
+		byID[post.ID] = post
+	}
+
+	results := make([]schemas.PostSearchResult, 0, len(rows))
+	for _, row := range rows {
+		post, found := byID[row.ID]
+		if !found {
+			// Deleted since it was matched
+			continue
+		}
+		results = append(results, schemas.PostSearchResult{
+			Post:           post,
+			Rank:           row.Rank,
+			TitleHighlight: renderHighlight(row.TitleHighlight),
+			Snippet:        renderHighlight(row.Snippet),
+		})
+	}
+	return results, total, nil
+}
+
+// renderHighlight turns a headline into HTML: the post's text is escaped, then the matched words are
+// wrapped in <mark>
+func renderHighlight(headline string) string {
+	return highlightMarks.Replace(html.EscapeString(headline))
+}
+
+// searchTSQuery turns what a reader typed into a tsquery expression and its arguments. Every term
+// is required: "quoted words" become a phrase, a word ending with * a prefix, anything else is
+// parsed like plain text. The text is only ever passed as an argument, so operators typed by the
+// reader are not interpreted. It returns "" when there is nothing to search for.
+func searchTSQuery(input string) (string, []interface{}) {
+	var parts []string
+	var args []interface{}
+
+	for _, match := range searchTermPattern.FindAllStringSubmatch(input, -1) {
+		if strings.HasPrefix(match[0], `"`) {
+			if phrase := strings.TrimSpace(match[1]); phrase != "" {
+				parts = append(parts, "phraseto_tsquery('english', ?)")
+				args = append(args, phrase)
+			}
+			continue
+		}
+
+		term := match[2]
+		if !strings.HasSuffix(term, "*") {
+			parts = append(parts, "plainto_tsquery('english', ?)")
+			args = append(args, term)
+			continue
+		}
+
+		words := searchWordPattern.FindAllString(term, -1)
+		if len(words) == 0 {
+			continue
+		}
+		if len(words) > 1 {
+			parts = append(parts, "plainto_tsquery('english', ?)")
+			args = append(args, strings.Join(words[:len(words)-1], " "))
+		}
+		// Only letters and digits reach to_tsquery, followed by the prefix marker
+		parts = append(parts, "to_tsquery('english', ?)")
+		args = append(args, words[len(words)-1]+":*")
+	}
+
+	if len(parts) == 0 {
+		return "", nil
+	}
+	return "(" + strings.Join(parts, " && ") + ")", args
+}
@@ -5,6 +5,7 @@ import (
 	"go-crud/initializers"
 	"go-crud/models"
 	"go-crud/schemas"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -33,23 +34,43 @@ func (s *PostService) Create(post models.Post, tagNames []string) (*models.Post,
 		return nil, errors.New("content_json is required")
 	}
 
+	if err := applySchedule(&post, time.Now()); err != nil {
+		return nil, err
+	}
+
 	// Start transaction
 	tx := s.db.Begin()
 	if tx.Error != nil {
 		return nil, tx.Error
 	}
 
-	// Create the post
+	// Generate the slug from the title unless the author picked one
+	var err error
+	if post.Slug != "" {
+		post.Slug, err = customSlug(tx, post.Slug, 0)
+	} else {
+		post.Slug, err = uniqueSlug(tx, post.Title, 0)
+	}
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// Create the post, with its first revision
 	result := tx.Create(&post)
 	if result.Error != nil {
 		tx.Rollback()
 		return nil, result.Error
 	}
+	if err = recordRevision(tx, post, post.UserID, nil); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
 
 	// Associate tags if provided
 	if len(tagNames) > 0 {
 		tagService := NewTagService()
-		err := tagService.AssociateTagsWithPost(post.ID, tagNames)
+		err = tagService.AssociateTagsWithPost(post.ID, tagNames)
 		if err != nil {
 			tx.Rollback()
 			return nil, err
@@ -79,6 +100,20 @@ func (s *PostService) GetByID(id uint) (*models.Post, error) {
 	return &post, nil
 }
 
+// GetBySlug retrieves a post by its slug
+func (s *PostService) GetBySlug(slug string) (*models.Post, error) {
+	var post models.Post
+	result := s.db.Preload("User").Preload("Tags").Where("slug = ?", slug).First(&post)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, errors.New("post not found")
+		}
+		return nil, result.Error
+	}
+
+	return &post, nil
+}
+
 // GetAll retrieves all posts
 func (s *PostService) GetAll() ([]models.Post, error) {
 	var posts []models.Post
@@ -96,14 +131,34 @@ func (s *PostService) GetWithPagination(query schemas.ListPostsQueryParams) ([]m
 	var total int64
 
 	// Build query with optional filters
-	db := s.db.Model(&models.Post{})
+	db := filterPosts(s.db.Model(&models.Post{}), query)
+
+	// Get total count
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// Calculate offset
+	offset := (query.Page - 1) * query.Limit
+
+	// Get paginated results with preloading, sorted by created date DESC
+	result := db.Preload("User").Preload("Tags").Order("posts.created_at DESC").Limit(query.Limit).Offset(offset).Find(&posts)
+	if result.Error != nil {
+		return nil, 0, result.Error
+	}
+
+	return posts, total, nil
+}
 
+// filterPosts narrows a query on posts down to the author, effective status and tags asked for
+func filterPosts(db *gorm.DB, query schemas.ListPostsQueryParams) *gorm.DB {
 	if query.UserID != nil {
-		db = db.Where("user_id = ?", *query.UserID)
+		db = db.Where("posts.user_id = ?", *query.UserID)
 	}
 
 	if query.Status != nil {
-		db = db.Where("status = ?", *query.Status)
+		condition, args := effectiveStatusCondition(*query.Status, time.Now())
+		db = db.Where(condition, args...)
 	}
 
 	// Filter by tags if provided
@@ -119,32 +174,15 @@ func (s *PostService) GetWithPagination(query schemas.ListPostsQueryParams) ([]m
 		}
 
 		if len(tagIDs) > 0 {
-			// Join with post_tags and filter by tag IDs
-			db = db.Joins("JOIN post_tags ON posts.id = post_tags.post_id").
-				Where("post_tags.tag_id IN ?", tagIDs).
-				Group("posts.id")
+			// Posts with any of the tags, without joining so that each post appears once
+			db = db.Where("posts.id IN (SELECT post_id FROM post_tags WHERE tag_id IN ?)", tagIDs)
 		}
 	}
-
-	// Get total count
-	if err := db.Count(&total).Error; err != nil {
-		return nil, 0, err
-	}
-
-	// Calculate offset
-	offset := (query.Page - 1) * query.Limit
-
-	// Get paginated results with preloading, sorted by created date DESC
-	result := db.Preload("User").Preload("Tags").Order("created_at DESC").Limit(query.Limit).Offset(offset).Find(&posts)
-	if result.Error != nil {
-		return nil, 0, result.Error
-	}
-
-	return posts, total, nil
+	return db
 }
 
-// Update updates an existing post
-func (s *PostService) Update(id uint, updatedPost models.Post) (*models.Post, error) {
+// Update updates an existing post, recording the change as a revision by authorID
+func (s *PostService) Update(id uint, updatedPost models.Post, authorID uint) (*models.Post, error) {
 	var post models.Post
 
 	// Check if post exists
@@ -174,18 +212,28 @@ func (s *PostService) Update(id uint, updatedPost models.Post) (*models.Post, er
 	if updatedPost.Status != "" {
 		post.Status = updatedPost.Status
 	}
+	post.PublishAt = updatedPost.PublishAt
+	post.UnpublishAt = updatedPost.UnpublishAt
+	if err := applySchedule(&post, time.Now()); err != nil {
+		return nil, err
+	}
 
 	// Save changes
-	result = s.db.Save(&post)
-	if result.Error != nil {
-		return nil, result.Error
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&post).Error; err != nil {
+			return err
+		}
+		return recordRevision(tx, post, authorID, nil)
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return &post, nil
 }
 
-// PartialUpdate updates specific fields of an existing post
-func (s *PostService) PartialUpdate(id uint, partialData map[string]interface{}) (*models.Post, error) {
+// PartialUpdate updates specific fields of an existing post, recording the change as a revision by authorID
+func (s *PostService) PartialUpdate(id uint, partialData map[string]interface{}, authorID uint) (*models.Post, error) {
 	var post models.Post
 
 	// Check if post exists
@@ -197,6 +245,8 @@ func (s *PostService) PartialUpdate(id uint, partialData map[string]interface{})
 		return nil, result.Error
 	}
 
+	previousSlug := post.Slug
+
 	// Update only provided fields
 	if title, exists := partialData["title"]; exists {
 		if titleStr, ok := title.(string); ok && titleStr != "" {
@@ -222,18 +272,53 @@ func (s *PostService) PartialUpdate(id uint, partialData map[string]interface{})
 		}
 	}
 
+	if slug, exists := partialData["slug"]; exists {
+		slugStr, _ := slug.(string)
+		customized, err := customSlug(s.db, slugStr, post.ID)
+		if err != nil {
+			return nil, err
+		}
+		post.Slug = customized
+	}
+
 	if status, exists := partialData["status"]; exists {
-		if statusEnum, ok := status.(models.PostStatus); ok && (statusEnum == models.Draft || statusEnum == models.Published) {
+		if statusEnum, ok := status.(models.PostStatus); ok && statusEnum.IsValid() {
 			post.Status = statusEnum
 		} else {
-			return nil, errors.New("invalid status: must be 'draft' or 'published'")
+			return nil, errors.New("invalid status: must be 'draft', 'published' or 'scheduled'")
 		}
 	}
 
-	// Save changes
-	result = s.db.Save(&post)
-	if result.Error != nil {
-		return nil, result.Error
+	if publishAt, exists := partialData["publish_at"]; exists {
+		if publishAtTime, ok := publishAt.(time.Time); ok {
+			post.PublishAt = &publishAtTime
+		}
+	}
+
+	if unpublishAt, exists := partialData["unpublish_at"]; exists {
+		if unpublishAtTime, ok := unpublishAt.(time.Time); ok {
+			post.UnpublishAt = &unpublishAtTime
+		}
+	}
+
+	if err := applySchedule(&post, time.Now()); err != nil {
+		return nil, err
+	}
+
+	// Save changes, keeping the previous slug redirecting to the post
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if post.Slug != previousSlug {
+			if err := recordSlugChange(tx, post.ID, previousSlug, post.Slug); err != nil {
+				return err
+			}
+		}
+		if err := tx.Save(&post).Error; err != nil {
+			return err
+		}
+		return recordRevision(tx, post, authorID, nil)
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return &post, nil
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"errors"
+	"go-crud/initializers"
+	"go-crud/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected")
+)
+
+// RefreshTokenService handles issuing and rotating refresh tokens
+type RefreshTokenService struct {
+	db *gorm.DB
+}
+
+// NewRefreshTokenService creates a new RefreshTokenService instance
+func NewRefreshTokenService() *RefreshTokenService {
+	return &RefreshTokenService{
+		db: initializers.DB,
+	}
+}
+
+// RefreshTokenTTL returns how long a refresh token stays valid (REFRESH_TOKEN_TTL, default 30 days)
+func RefreshTokenTTL() time.Duration {
+	return durationFromEnv("REFRESH_TOKEN_TTL", 30*24*time.Hour)
+}
+
+// Issue creates a refresh token that starts a new token family for the user and returns
+// it along with the family ID
+func (s *RefreshTokenService) Issue(userID uint) (string, string, error) {
+	familyID, err := newTokenID()
+	if err != nil {
+		return "", "", err
+	}
+
+	token, err := s.create(s.db, userID, familyID)
+	if err != nil {
+		return "", "", err
+	}
+	return token, familyID, nil
+}
+
+// Rotate consumes a refresh token and returns its replacement along with the consumed token.
+// Presenting a token that was already used revokes the whole family.
+func (s *RefreshTokenService) Rotate(token string) (string, *models.RefreshToken, error) {
+	var current models.RefreshToken
+	result := s.db.Where("token_hash = ?", hashOpaqueToken(token)).First(&current)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return "", nil, ErrInvalidRefreshToken
+		}
+		return "", nil, result.Error
+	}
+
+	if current.UsedAt != nil {
+		if err := s.RevokeFamily(current.FamilyID); err != nil {
+			return "", nil, err
+		}
+		return "", nil, ErrRefreshTokenReused
+	}
+
+	if current.RevokedAt != nil || time.Now().After(current.ExpiresAt) {
+		return "", nil, ErrInvalidRefreshToken
+	}
+
+	var replacement string
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Mark as used only if nobody else got there first
+		update := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND used_at IS NULL", current.ID).
+			Update("used_at", time.Now())
+		if update.Error != nil {
+			return update.Error
+		}
+		if update.RowsAffected == 0 {
+			return ErrRefreshTokenReused
+		}
+
+		var err error
+		replacement, err = s.create(tx, current.UserID, current.FamilyID)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, ErrRefreshTokenReused) {
+			if revokeErr := s.RevokeFamily(current.FamilyID); revokeErr != nil {
+				return "", nil, revokeErr
+			}
+		}
+		return "", nil, err
+	}
+
+	return replacement, &current, nil
+}
+
+// RevokeFamily revokes every refresh token descending from the same login, and the session they belong to
+func (s *RefreshTokenService) RevokeFamily(familyID string) error {
+	now := time.Now()
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.RefreshToken{}).
+			Where("family_id = ? AND revoked_at IS NULL", familyID).
+			Update("revoked_at", now).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&models.Session{}).
+			Where("family_id = ? AND revoked_at IS NULL", familyID).
+			Update("revoked_at", now).Error
+	})
+}
+
+// RevokeToken revokes the family of a refresh token, provided it belongs to the given user
+func (s *RefreshTokenService) RevokeToken(token string, userID uint) error {
+	var refreshToken models.RefreshToken
+	result := s.db.Where("token_hash = ? AND user_id = ?", hashOpaqueToken(token), userID).First(&refreshToken)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return ErrInvalidRefreshToken
+		}
+		return result.Error
+	}
+
+	return s.RevokeFamily(refreshToken.FamilyID)
+}
+
+// PurgeExpired deletes refresh tokens that have expired
+func (s *RefreshTokenService) PurgeExpired() error {
+	return s.db.Where("expires_at < ?", time.Now()).Delete(&models.RefreshToken{}).Error
+}
+
+func (s *RefreshTokenService) create(db *gorm.DB, userID uint, familyID string) (string, error) {
+	token, tokenHash, err := newOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	refreshToken := models.RefreshToken{
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(RefreshTokenTTL()),
+	}
+	if err := db.Create(&refreshToken).Error; err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
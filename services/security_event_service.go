@@ -0,0 +1,25 @@
+package services
+
+import (
+	"go-crud/initializers"
+	"go-crud/models"
+
+	"gorm.io/gorm"
+)
+
+// SecurityEventService stores the security audit trail of accounts
+type SecurityEventService struct {
+	db *gorm.DB
+}
+
+// NewSecurityEventService creates a new SecurityEventService instance
+func NewSecurityEventService() *SecurityEventService {
+	return &SecurityEventService{
+		db: initializers.DB,
+	}
+}
+
+// Record appends an event
+func (s *SecurityEventService) Record(event *models.SecurityEvent) error {
+	return s.db.Create(event).Error
+}
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"go-crud/initializers"
+	"go-crud/models"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrSessionNotFound = errors.New("session not found")
+	ErrSessionRevoked  = errors.New("session has been revoked")
+)
+
+// Device describes the client a session was started from
+type Device struct {
+	IP        string
+	UserAgent string
+}
+
+// hash identifies the device across sessions. The IP is left out on purpose, it changes too
+// often to tell devices apart.
+func (d Device) hash() string {
+	return hashOpaqueToken(d.UserAgent)
+}
+
+// LoginAlertsEnabled reports whether users are emailed about logins from new devices
+func LoginAlertsEnabled() bool {
+	return boolFromEnv("LOGIN_ALERTS_ENABLED", false)
+}
+
+// SessionService keeps track of where users are signed in
+type SessionService struct {
+	db *gorm.DB
+}
+
+// NewSessionService creates a new SessionService instance
+func NewSessionService() *SessionService {
+	return &SessionService{
+		db: initializers.DB,
+	}
+}
+
+// Start records a session for a new refresh token family and, when enabled, alerts the user
+// if the device has never been used with the account before
+func (s *SessionService) Start(user models.User, familyID string, device Device) (*models.Session, error) {
+	var previous, fromDevice int64
+	if err := s.db.Model(&models.Session{}).Where("user_id = ?", user.ID).Count(&previous).Error; err != nil {
+		return nil, err
+	}
+	if err := s.db.Model(&models.Session{}).
+		Where("user_id = ? AND device_hash = ?", user.ID, device.hash()).
+		Count(&fromDevice).Error; err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	session := models.Session{
+		UserID:     user.ID,
+		FamilyID:   familyID,
+		DeviceHash: device.hash(),
+		UserAgent:  truncate(device.UserAgent, 512),
+		IP:         device.IP,
+		LastSeenAt: now,
+	}
+	if err := s.db.Create(&session).Error; err != nil {
+		return nil, err
+	}
+
+	// The first session of an account (signup) is not worth an alert
+	if LoginAlertsEnabled() && previous > 0 && fromDevice == 0 {
+		if err := sendLoginAlert(user, session); err != nil {
+			log.Printf("[MAILER] Failed to send login alert to user %d: %v", user.ID, err)
+		}
+	}
+
+	return &session, nil
+}
+
+// ForFamily returns the session of a refresh token family and marks it as seen
+func (s *SessionService) ForFamily(familyID string) (*models.Session, error) {
+	var session models.Session
+	result := s.db.Where("family_id = ?", familyID).First(&session)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, ErrSessionNotFound
+		}
+		return nil, result.Error
+	}
+
+	if err := s.db.Model(&session).Update("last_seen_at", time.Now()).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// Touch checks that a session is still active and records that it was used
+func (s *SessionService) Touch(id uint) error {
+	var session models.Session
+	result := s.db.First(&session, id)
+	if result.Error != nil {
+		// Sessions are only purged long after their tokens stopped working
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return ErrSessionRevoked
+		}
+		return result.Error
+	}
+	if session.RevokedAt != nil {
+		return ErrSessionRevoked
+	}
+
+	if time.Since(session.LastSeenAt) > lastUsedResolution {
+		return s.db.Model(&session).Update("last_seen_at", time.Now()).Error
+	}
+	return nil
+}
+
+// List returns the user's active sessions, most recently used first
+func (s *SessionService) List(userID uint) ([]models.Session, error) {
+	var sessions []models.Session
+	result := s.db.Where("user_id = ? AND revoked_at IS NULL AND last_seen_at > ?", userID, time.Now().Add(-RefreshTokenTTL())).
+		Order("last_seen_at DESC").
+		Find(&sessions)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return sessions, nil
+}
+
+// Revoke signs one of the user's sessions out: its refresh tokens stop working and its
+// access tokens are rejected from the next request on
+func (s *SessionService) Revoke(userID, id uint) error {
+	var session models.Session
+	result := s.db.Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).First(&session)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return ErrSessionNotFound
+		}
+		return result.Error
+	}
+
+	return NewRefreshTokenService().RevokeFamily(session.FamilyID)
+}
+
+// PurgeExpired deletes sessions whose refresh tokens have long expired. Revoked sessions are kept
+// until then as well, they are the device history for login alerts.
+func (s *SessionService) PurgeExpired() error {
+	return s.db.Where("last_seen_at < ?", time.Now().Add(-RefreshTokenTTL())).Delete(&models.Session{}).Error
+}
+
+func sendLoginAlert(user models.User, session models.Session) error {
+	body := fmt.Sprintf("Hi %s,\n\n"+
+		"Your account was just signed in to from a device we haven't seen before:\n\n"+
+		"Device: %s\n"+
+		"IP address: %s\n"+
+		"Time: %s\n\n"+
+		"If this was you, there is nothing to do. Otherwise, sign the session out at %s/settings/sessions "+
+		"and change your password.\n",
+		user.Name, session.UserAgent, session.IP, session.CreatedAt.UTC().Format(time.RFC1123), frontendURL())
+
+	return sendEmail(user.Email, "New sign-in to your account", body)
+}
+
+// truncate shortens s to at most n bytes without splitting a character. Postgres rejects invalid
+// UTF-8, so any is dropped as well.
+func truncate(s string, n int) string {
+	s = strings.ToValidUTF8(s, "")
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"go-crud/models"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+	"gorm.io/gorm"
+)
+
+// slugMaxLength keeps permalinks readable; longer titles are cut at a word boundary
+const slugMaxLength = 100
+
+var (
+	ErrSlugTaken   = errors.New("slug is already used by another post")
+	ErrInvalidSlug = errors.New("slug must contain letters or digits")
+)
+
+// slugTransliterations spells out letters that do not decompose into an ASCII base letter
+var slugTransliterations = map[rune]string{
+	'đ': "d", 'ð': "d", 'ß': "ss", 'æ': "ae", 'œ': "oe", 'ø': "o", 'ł': "l", 'þ': "th", 'ı': "i",
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh", 'з': "z",
+	'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p", 'р': "r",
+	'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch",
+	'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya", 'є': "ye", 'і': "i", 'ї': "yi", 'ґ': "g",
+}
+
+// Slugify turns a title into a lowercase, ASCII, dash separated slug: "Xin chào, Thế giới!" becomes
+// "xin-chao-the-gioi". Accents are stripped and a few other alphabets transliterated; anything else
+// is dropped. It returns "" when nothing is left.
+func Slugify(title string) string {
+	var slug strings.Builder
+	dash := false
+	for _, r := range norm.NFKD.String(strings.ToLower(title)) {
+		transliteration, known := slugTransliterations[r]
+		switch {
+		case r <= unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			slug.WriteRune(r)
+			dash = false
+		case unicode.Is(unicode.Mn, r):
+			// Combining accent split off its letter by the decomposition
+		case known:
+			slug.WriteString(transliteration)
+			dash = dash && transliteration == ""
+		case !dash && slug.Len() > 0:
+			slug.WriteByte('-')
+			dash = true
+		}
+	}
+
+	result := strings.TrimSuffix(slug.String(), "-")
+	if len(result) > slugMaxLength {
+		result = result[:slugMaxLength]
+		if cut := strings.LastIndexByte(result, '-'); cut > slugMaxLength/2 {
+			result = result[:cut]
+		}
+		result = strings.TrimSuffix(result, "-")
+	}
+	return result
+}
+
+// uniqueSlug returns the slug for a post, generated from its title, with a -2, -3, ... suffix when
+// another post already uses it or redirects from it. excludeID is the post itself when it is being
+// updated.
+func uniqueSlug(db *gorm.DB, title string, excludeID uint) (string, error) {
+	base := Slugify(title)
+	if base == "" {
+		base = "post"
+	}
+
+	var taken, redirected []string
+	if err := db.Model(&models.Post{}).
+		Where("(slug = ? OR slug LIKE ?) AND id <> ?", base, base+"-%", excludeID).
+		Pluck("slug", &taken).Error; err != nil {
+		return "", err
+	}
+	if err := db.Model(&models.PostRedirect{}).
+		Where("(from_slug = ? OR from_slug LIKE ?) AND post_id <> ?", base, base+"-%", excludeID).
+		Pluck("from_slug", &redirected).Error; err != nil {
+		return "", err
+	}
+
+	used := make(map[string]bool, len(taken)+len(redirected))
+	for _, slug := range append(taken, redirected...) {
+		used[slug] = true
+	}
+
+	slug := base
+	for n := 2; used[slug]; n++ {
+		slug = fmt.Sprintf("%s-%d", base, n)
+	}
+	return slug, nil
+}
+
+// customSlug normalizes a slug chosen by the author. Unlike generated slugs it is not suffixed:
+// the request fails when another post already uses it, or when it redirects to another post. A
+// post may take back a slug that redirects to itself.
+func customSlug(db *gorm.DB, requested string, excludeID uint) (string, error) {
+	slug := Slugify(requested)
+	if slug == "" {
+		return "", ErrInvalidSlug
+	}
+
+	var count int64
+	if err := db.Model(&models.Post{}).Where("slug = ? AND id <> ?", slug, excludeID).Count(&count).Error; err != nil {
+		return "", err
+	}
+	if count > 0 {
+		return "", ErrSlugTaken
+	}
+	if err := db.Model(&models.PostRedirect{}).Where("from_slug = ? AND post_id <> ?", slug, excludeID).Count(&count).Error; err != nil {
+		return "", err
+	}
+	if count > 0 {
+		return "", ErrSlugTaken
+	}
+	return slug, nil
+}
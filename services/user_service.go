@@ -2,13 +2,21 @@ package services
 
 import (
 	"errors"
+	"fmt"
 	"go-crud/initializers"
 	"go-crud/models"
 	"go-crud/schemas"
+	"log"
 
 	"gorm.io/gorm"
 )
 
+var (
+	ErrIncorrectPassword = errors.New("incorrect password")
+	ErrPasswordNotSet    = errors.New("account has no password")
+	ErrEmailTaken        = errors.New("email is already in use")
+)
+
 type UserService struct {
 	db *gorm.DB
 }
@@ -21,23 +29,83 @@ func NewUserService() *UserService {
 
 // Create creates a new user
 func (s *UserService) Create(user models.User) (*models.User, error) {
+	if err := prepareNewUser(&user); err != nil {
+		return nil, err
+	}
+
+	return &user, s.db.Create(&user).Error
+}
+
+// Register creates a user signing up on their own, subject to the registration policy.
+// A valid invite code redeems one use of the invite and gives the account the invite's role.
+func (s *UserService) Register(user models.User, inviteCode string) (*models.User, error) {
+	if err := validateNewUser(&user); err != nil {
+		return nil, err
+	}
+
+	// Refuse signups the policy does not allow before paying for the password hash
+	if err := CheckRegistrationPolicy(user.Email, inviteCode != ""); err != nil {
+		return nil, err
+	}
+	if inviteCode != "" {
+		if err := NewInviteService().checkUsable(inviteCode); err != nil {
+			return nil, err
+		}
+	}
+	if err := hashNewUserPassword(&user); err != nil {
+		return nil, err
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if inviteCode != "" {
+			invite, err := NewInviteService().redeem(tx, inviteCode)
+			if err != nil {
+				return err
+			}
+			user.Role = invite.Role
+			user.InviteID = &invite.ID
+		}
+		return tx.Create(&user).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// prepareNewUser validates a new user and replaces the plain password with its hash
+func prepareNewUser(user *models.User) error {
+	if err := validateNewUser(user); err != nil {
+		return err
+	}
+	return hashNewUserPassword(user)
+}
+
+// validateNewUser checks the fields of a new user, whose HashedPassword still holds the plain password
+func validateNewUser(user *models.User) error {
 	if user.Name == "" {
-		return nil, errors.New("name is required")
+		return errors.New("name is required")
 	}
 	if user.Email == "" {
-		return nil, errors.New("email is required")
+		return errors.New("email is required")
+	}
+	if isReservedEmail(user.Email) {
+		return ErrEmailTaken
 	}
 	if user.HashedPassword == "" {
-		return nil, errors.New("password is required")
+		return errors.New("password is required")
 	}
+	return ValidatePassword(user.HashedPassword, user.Email)
+}
 
+// hashNewUserPassword replaces the plain password of a new user with its hash
+func hashNewUserPassword(user *models.User) error {
 	hashedPassword, err := HashPassword(user.HashedPassword)
 	if err != nil {
-		return nil, errors.New("failed to hash password")
+		return errors.New("failed to hash password")
 	}
 	user.HashedPassword = hashedPassword
-
-	return &user, s.db.Create(&user).Error
+	return nil
 }
 
 // GetByID retrieves a user by ID
@@ -53,7 +121,8 @@ func (s *UserService) GetByID(id uint) (*models.User, error) {
 	return &user, nil
 }
 
-// Partial Update user fields partially
+// Partial Update user fields partially. Email and password have their own,
+// re-authenticated methods: ChangeEmail and ChangePassword.
 func (s *UserService) PartialUpdate(id uint, input schemas.PartialUpdateUserInput) (*models.User, error) {
 	user, err := s.GetByID(id)
 	if err != nil {
@@ -63,16 +132,6 @@ func (s *UserService) PartialUpdate(id uint, input schemas.PartialUpdateUserInpu
 	if input.Name != nil {
 		user.Name = *input.Name
 	}
-	if input.Email != nil {
-		user.Email = *input.Email
-	}
-	if input.Password != nil {
-		hashedPassword, err := HashPassword(*input.Password)
-		if err != nil {
-			return nil, errors.New("failed to hash password")
-		}
-		user.HashedPassword = hashedPassword
-	}
 
 	result := s.db.Save(user)
 	if result.Error != nil {
@@ -82,25 +141,164 @@ func (s *UserService) PartialUpdate(id uint, input schemas.PartialUpdateUserInpu
 	return user, nil
 }
 
-// Delete user by ID
-func (s *UserService) Delete(id uint) error {
-	var user models.User
+// CheckPassword reports whether the password is the user's. On success, a hash made with an
+// outdated algorithm or outdated parameters is replaced with one made with the current settings.
+func (s *UserService) CheckPassword(user *models.User, password string) bool {
+	if user.HashedPassword == "" {
+		CheckDummyPassword(password)
+		return false
+	}
+	if !CheckHashedPassword(password, user.HashedPassword) {
+		return false
+	}
 
-	result := s.db.First(&user, id)
-	if result.Error != nil {
-		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			return errors.New("user not found")
+	if PasswordNeedsRehash(user.HashedPassword) {
+		// A failed upgrade must not fail the login; it is retried on the next one
+		hashedPassword, err := HashPassword(password)
+		if err == nil {
+			err = s.db.Model(&models.User{}).
+				Where("id = ? AND hashed_password = ?", user.ID, user.HashedPassword).
+				Update("hashed_password", hashedPassword).Error
+		}
+		if err != nil {
+			log.Printf("[AUTH] Failed to rehash password of user %d: %v", user.ID, err)
+		} else {
+			user.HashedPassword = hashedPassword
 		}
-		return result.Error
 	}
 
-	result = s.db.Delete(&user)
-	if result.Error != nil {
-		return result.Error
+	return true
+}
+
+// checkCurrentPassword re-authenticates the user before a credential change
+func (s *UserService) checkCurrentPassword(user *models.User, password string) error {
+	if user.HashedPassword == "" {
+		return ErrPasswordNotSet
+	}
+	if !s.CheckPassword(user, password) {
+		return ErrIncorrectPassword
 	}
 	return nil
 }
 
+// ConfirmIdentity checks that the account holder is present before a sensitive change, with a
+// two-factor code when one is given and enabled, and with the current password otherwise
+func (s *UserService) ConfirmIdentity(user *models.User, password, code string) error {
+	if code != "" && user.HasTOTPEnabled() {
+		return NewMFAService().VerifyCode(user, code)
+	}
+	return s.checkCurrentPassword(user, password)
+}
+
+// ChangePassword sets a new password after checking the current one, and ends every
+// existing session
+func (s *UserService) ChangePassword(id uint, currentPassword, newPassword string) (*models.User, error) {
+	user, err := s.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkCurrentPassword(user, currentPassword); err != nil {
+		return nil, err
+	}
+	if err := ValidatePassword(newPassword, user.Email); err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := HashPassword(newPassword)
+	if err != nil {
+		return nil, errors.New("failed to hash password")
+	}
+	if err := s.db.Model(user).Update("hashed_password", hashedPassword).Error; err != nil {
+		return nil, err
+	}
+
+	if err := NewTokenRevocationService().RevokeAllForUser(user.ID); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// ChangeEmail moves the account to a new address after checking the password. The new
+// address has to be verified again, and the old one is told about the change.
+func (s *UserService) ChangeEmail(id uint, password, newEmail string) (*models.User, error) {
+	user, err := s.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkCurrentPassword(user, password); err != nil {
+		return nil, err
+	}
+
+	if isReservedEmail(newEmail) {
+		return nil, ErrEmailTaken
+	}
+	var taken int64
+	if err := s.db.Model(&models.User{}).Where("email = ? AND id <> ?", newEmail, user.ID).Count(&taken).Error; err != nil {
+		return nil, err
+	}
+	if taken > 0 {
+		return nil, ErrEmailTaken
+	}
+
+	oldEmail := user.Email
+	if err := s.db.Model(user).Updates(map[string]interface{}{
+		"email":             newEmail,
+		"email_verified_at": nil,
+	}).Error; err != nil {
+		return nil, err
+	}
+	user.Email = newEmail
+	user.EmailVerifiedAt = nil
+
+	// Links mailed to the old address would otherwise sign in and verify the new one
+	if err := NewOneTimeTokenService().Invalidate(user.ID, models.MagicLinkPurpose); err != nil {
+		return nil, err
+	}
+
+	// Signup does the same: the change stands even if the emails cannot be sent
+	if err := NewEmailVerificationService().SendVerification(*user); err != nil {
+		log.Printf("[MAILER] Failed to send verification email to user %d: %v", user.ID, err)
+	}
+	if err := sendEmailChangedNotice(*user, oldEmail); err != nil {
+		log.Printf("[MAILER] Failed to send email change notice to user %d: %v", user.ID, err)
+	}
+
+	return user, nil
+}
+
+func sendEmailChangedNotice(user models.User, oldEmail string) error {
+	body := fmt.Sprintf("Hi %s,\n\n"+
+		"The email address of your account was changed to %s.\n\n"+
+		"If you did not make this change, reset your password right away: %s\n",
+		user.Name, user.Email, frontendURL())
+
+	return sendEmail(oldEmail, "Your email address was changed", body)
+}
+
+// UpdateRole changes a user's role. Existing tokens carry the old role, so they are revoked.
+func (s *UserService) UpdateRole(id uint, role models.Role) (*models.User, error) {
+	if !role.IsValid() {
+		return nil, ErrInvalidRole
+	}
+
+	user, err := s.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Model(user).Update("role", role).Error; err != nil {
+		return nil, err
+	}
+	user.Role = role
+
+	if err := NewTokenRevocationService().RevokeAllForUser(user.ID); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
 // FindByEmail finds a user by email address
 func (s *UserService) FindByEmail(email string) (*models.User, error) {
 	var user models.User
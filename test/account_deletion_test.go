@@ -0,0 +1,164 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-crud/initializers"
+	"go-crud/models"
+	"go-crud/schemas"
+	"go-crud/services"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Helper function to schedule a deletion and move its purge date into the past
+func scheduleDueDeletion(t *testing.T, suite *BaseTestSuite, token string, userID uint, body interface{}) {
+	w := authJSON(suite, "DELETE", fmt.Sprintf("/users/%d", userID), token, body)
+	assert.Equal(t, http.StatusAccepted, w.Code)
+
+	initializers.DB.Model(&models.AccountDeletion{}).
+		Where("user_id = ?", userID).
+		Update("purge_at", time.Now().Add(-time.Minute))
+}
+
+func TestAccountDeletionCanBeCancelled(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	user := UserFactory("testPassword123", WithEmail("delete-cancel@example.com"))
+	token := getAuthToken(t, suite, "delete-cancel@example.com")
+	suite.Mailer().Reset()
+
+	w := authJSON(suite, "DELETE", fmt.Sprintf("/users/%d", user.ID), token, nil)
+	assert.Equal(t, http.StatusAccepted, w.Code)
+
+	var response schemas.AccountDeletionResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, models.ReassignPosts, response.Data.PostsPolicy)
+	assert.WithinDuration(t, time.Now().Add(services.AccountDeletionGracePeriod()), response.Data.PurgeAt, time.Minute)
+	assert.Len(t, suite.Mailer().MessagesTo("delete-cancel@example.com"), 1)
+
+	w = authJSON(suite, "DELETE", fmt.Sprintf("/users/%d", user.ID), token, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = authJSON(suite, "GET", fmt.Sprintf("/users/%d/deletion", user.ID), token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = authJSON(suite, "DELETE", fmt.Sprintf("/users/%d/deletion", user.ID), token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = authJSON(suite, "GET", fmt.Sprintf("/users/%d/deletion", user.ID), token, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// A cancelled deletion is never purged
+	assert.NoError(t, services.NewAccountDeletionService().PurgeDue())
+	var count int64
+	initializers.DB.Model(&models.User{}).Where("id = ?", user.ID).Count(&count)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestAccountDeletionReassignsPosts(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	user := UserFactory("testPassword123", WithEmail("delete-reassign@example.com"))
+	token := getAuthToken(t, suite, "delete-reassign@example.com")
+	post := PostFactory(WithUserID(user.ID))
+
+	scheduleDueDeletion(t, suite, token, user.ID, map[string]string{"posts": "reassign"})
+	assert.NoError(t, services.NewAccountDeletionService().PurgeDue())
+
+	var count int64
+	initializers.DB.Model(&models.User{}).Where("id = ?", user.ID).Count(&count)
+	assert.Equal(t, int64(0), count)
+
+	var reassigned models.Post
+	assert.NoError(t, initializers.DB.Preload("User").First(&reassigned, post.ID).Error)
+	assert.Equal(t, services.DeletedUserEmail, reassigned.User.Email)
+	assert.True(t, reassigned.User.IsPlaceholder)
+}
+
+func TestAccountDeletionDeletesPosts(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	user := UserFactory("testPassword123", WithEmail("delete-posts@example.com"))
+	token := getAuthToken(t, suite, "delete-posts@example.com")
+	post := PostFactory(WithUserID(user.ID))
+
+	scheduleDueDeletion(t, suite, token, user.ID, map[string]string{"posts": "delete"})
+	assert.NoError(t, services.NewAccountDeletionService().PurgeDue())
+
+	var count int64
+	initializers.DB.Model(&models.Post{}).Where("id = ?", post.ID).Count(&count)
+	assert.Equal(t, int64(0), count)
+}
+
+func TestAccountDeletionTransfersPosts(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	user := UserFactory("testPassword123", WithEmail("delete-transfer@example.com"))
+	heir := UserFactory("testPassword123", WithEmail("delete-heir@example.com"))
+	UserFactory("testPassword123", WithEmail("delete-admin@example.com"), WithRole(models.RoleAdmin))
+	userToken := getAuthToken(t, suite, "delete-transfer@example.com")
+	adminToken := getAuthToken(t, suite, "delete-admin@example.com")
+	post := PostFactory(WithUserID(user.ID))
+
+	body := map[string]interface{}{"posts": "transfer", "transfer_to_id": heir.ID}
+
+	// Users cannot hand their posts to someone else on their own
+	w := authJSON(suite, "DELETE", fmt.Sprintf("/users/%d", user.ID), userToken, body)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	scheduleDueDeletion(t, suite, adminToken, user.ID, body)
+	assert.NoError(t, services.NewAccountDeletionService().PurgeDue())
+
+	var transferred models.Post
+	assert.NoError(t, initializers.DB.First(&transferred, post.ID).Error)
+	assert.Equal(t, heir.ID, transferred.UserID)
+}
+
+func TestAccountDeletionKeepsInvites(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	user := UserFactory("testPassword123", WithEmail("delete-inviter@example.com"))
+	token := getAuthToken(t, suite, "delete-inviter@example.com")
+	invite, _, err := services.NewInviteService().Create(user.ID, models.RoleAuthor, 5, time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+
+	scheduleDueDeletion(t, suite, token, user.ID, nil)
+	assert.NoError(t, services.NewAccountDeletionService().PurgeDue())
+
+	var kept models.Invite
+	assert.NoError(t, initializers.DB.First(&kept, invite.ID).Error)
+	assert.Nil(t, kept.CreatedByID)
+}
+
+func TestDeletedUserEmailIsReserved(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	w := postJSON(suite, "/users", map[string]string{
+		"name":     "Squatter",
+		"email":    services.DeletedUserEmail,
+		"password": "plum-orbit-canvas-42",
+	})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	UserFactory("testPassword123", WithEmail("squatter@example.com"))
+	token := getAuthToken(t, suite, "squatter@example.com")
+	w = authJSON(suite, "POST", "/users/me/email", token, map[string]string{
+		"password":  "testPassword123",
+		"new_email": "Deleted-User@deleted.invalid",
+	})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	var count int64
+	initializers.DB.Model(&models.User{}).Where("LOWER(email) = ?", services.DeletedUserEmail).Count(&count)
+	assert.Equal(t, int64(0), count)
+}
@@ -6,7 +6,9 @@ import (
 	"go-crud/schemas"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
+	"regexp"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -178,3 +180,349 @@ func getAuthToken(t *testing.T, suite *BaseTestSuite, email string) string {
 
 	return response.Token
 }
+
+// Helper function to log in and return the full auth response (access + refresh tokens)
+func loginUser(t *testing.T, suite *BaseTestSuite, email string) schemas.AuthResponse {
+	requestBody := map[string]string{
+		"email":    email,
+		"password": "testPassword123",
+	}
+
+	jsonData, _ := json.Marshal(requestBody)
+
+	req, _ := http.NewRequest("POST", "/auth/login", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+
+	var response schemas.AuthResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+
+	return response
+}
+
+// Helper function to exchange a refresh token
+func refreshTokens(suite *BaseTestSuite, refreshToken string) *httptest.ResponseRecorder {
+	jsonData, _ := json.Marshal(map[string]string{"refresh_token": refreshToken})
+
+	req, _ := http.NewRequest("POST", "/auth/refresh", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	return w
+}
+
+func TestLoginReturnsRefreshToken(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	UserFactory("testPassword123", WithEmail("refresh@example.com"))
+
+	response := loginUser(t, suite, "refresh@example.com")
+	assert.NotEmpty(t, response.Token)
+	assert.NotEmpty(t, response.RefreshToken)
+	assert.Greater(t, response.ExpiresIn, int64(0))
+}
+
+func TestRefreshTokenRotation(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	user := UserFactory("testPassword123", WithEmail("rotate@example.com"))
+	login := loginUser(t, suite, "rotate@example.com")
+
+	w := refreshTokens(suite, login.RefreshToken)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response schemas.AuthResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, response.Token)
+	assert.NotEmpty(t, response.RefreshToken)
+	assert.NotEqual(t, login.RefreshToken, response.RefreshToken)
+	assert.Equal(t, user.ID, response.Data.ID)
+
+	// The rotated token keeps working
+	w = refreshTokens(suite, response.RefreshToken)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestRefreshTokenReuseRevokesFamily(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	UserFactory("testPassword123", WithEmail("reuse@example.com"))
+	login := loginUser(t, suite, "reuse@example.com")
+
+	w := refreshTokens(suite, login.RefreshToken)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var rotated schemas.AuthResponse
+	json.Unmarshal(w.Body.Bytes(), &rotated)
+
+	// Replaying the original token is treated as theft
+	w = refreshTokens(suite, login.RefreshToken)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	var response schemas.ErrorResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Contains(t, response.Error, "already been used")
+
+	// ...and the legitimate descendant is revoked as well
+	w = refreshTokens(suite, rotated.RefreshToken)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestRefreshTokenInvalid(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	w := refreshTokens(suite, "not-a-real-token")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	var response schemas.ErrorResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Contains(t, response.Error, "Invalid or expired refresh token")
+}
+
+func TestLogoutRevokesAccessToken(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	UserFactory("testPassword123", WithEmail("logout@example.com"))
+	login := loginUser(t, suite, "logout@example.com")
+
+	jsonData, _ := json.Marshal(map[string]string{"refresh_token": login.RefreshToken})
+	req, _ := http.NewRequest("POST", "/auth/logout", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+login.Token)
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// The same token is now rejected
+	req, _ = http.NewRequest("GET", "/users/me/posts", nil)
+	req.Header.Set("Authorization", "Bearer "+login.Token)
+
+	w = httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// ...and so is its refresh token
+	w = refreshTokens(suite, login.RefreshToken)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestLogoutAllRevokesEverySession(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	UserFactory("testPassword123", WithEmail("logout-all@example.com"))
+	first := loginUser(t, suite, "logout-all@example.com")
+	second := loginUser(t, suite, "logout-all@example.com")
+
+	req, _ := http.NewRequest("POST", "/auth/logout-all", nil)
+	req.Header.Set("Authorization", "Bearer "+first.Token)
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	for _, token := range []string{first.Token, second.Token} {
+		req, _ = http.NewRequest("GET", "/users/me/posts", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		w = httptest.NewRecorder()
+		suite.router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	}
+
+	w = refreshTokens(suite, second.RefreshToken)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestLogoutRequiresAuthentication(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	req, _ := http.NewRequest("POST", "/auth/logout", nil)
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+var emailTokenPattern = regexp.MustCompile(`token=([^\s&]+)`)
+
+// Helper function to pull the token out of the link in an email body
+func extractTokenFromEmail(t *testing.T, body string) string {
+	match := emailTokenPattern.FindStringSubmatch(body)
+	if !assert.Len(t, match, 2, "no token link found in email") {
+		return ""
+	}
+
+	token, err := url.QueryUnescape(match[1])
+	assert.NoError(t, err)
+	return token
+}
+
+// Helper function to build a request with an optional JSON body
+func newJSONRequest(method, path string, body interface{}) *http.Request {
+	var payload *bytes.Buffer
+	if body == nil {
+		payload = bytes.NewBuffer(nil)
+	} else {
+		jsonData, _ := json.Marshal(body)
+		payload = bytes.NewBuffer(jsonData)
+	}
+
+	req, _ := http.NewRequest(method, path, payload)
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+// Helper function to POST a JSON body without authentication
+func postJSON(suite *BaseTestSuite, path string, body interface{}) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, newJSONRequest("POST", path, body))
+	return w
+}
+
+func TestForgotPasswordSendsResetEmail(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	UserFactory("testPassword123", WithEmail("forgot@example.com"))
+
+	w := postJSON(suite, "/auth/password/forgot", map[string]string{"email": "forgot@example.com"})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	messages := suite.WaitForMessagesTo("forgot@example.com", 1)
+	assert.Len(t, messages, 1)
+	assert.Contains(t, messages[0].Body, "/reset-password?token=")
+}
+
+func TestForgotPasswordDoesNotRevealUnknownEmail(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	UserFactory("testPassword123", WithEmail("known@example.com"))
+
+	known := postJSON(suite, "/auth/password/forgot", map[string]string{"email": "known@example.com"})
+	unknown := postJSON(suite, "/auth/password/forgot", map[string]string{"email": "unknown@example.com"})
+
+	assert.Equal(t, known.Code, unknown.Code)
+	assert.Equal(t, known.Body.String(), unknown.Body.String())
+	assert.Len(t, suite.WaitForMessagesTo("known@example.com", 1), 1)
+	assert.Empty(t, suite.Mailer().MessagesTo("unknown@example.com"))
+}
+
+func TestResetPasswordSuccess(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	UserFactory("testPassword123", WithEmail("reset@example.com"))
+	login := loginUser(t, suite, "reset@example.com")
+
+	postJSON(suite, "/auth/password/forgot", map[string]string{"email": "reset@example.com"})
+	messages := suite.WaitForMessagesTo("reset@example.com", 1)
+	assert.Len(t, messages, 1)
+	token := extractTokenFromEmail(t, messages[0].Body)
+
+	w := postJSON(suite, "/auth/password/reset", map[string]string{
+		"token":        token,
+		"new_password": "brandNewPassword456",
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// The old password no longer works, the new one does
+	w = postJSON(suite, "/auth/login", map[string]string{"email": "reset@example.com", "password": "testPassword123"})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = postJSON(suite, "/auth/login", map[string]string{"email": "reset@example.com", "password": "brandNewPassword456"})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Existing sessions were logged out
+	w = refreshTokens(suite, login.RefreshToken)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// The token is single-use
+	w = postJSON(suite, "/auth/password/reset", map[string]string{
+		"token":        token,
+		"new_password": "yetAnotherPassword789",
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestResetPasswordInvalidToken(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	w := postJSON(suite, "/auth/password/reset", map[string]string{
+		"token":        "bogus",
+		"new_password": "brandNewPassword456",
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var response schemas.ErrorResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Contains(t, response.Error, "Invalid or expired reset token")
+}
+
+func TestVerifyEmailSuccess(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	w := postJSON(suite, "/users", map[string]string{
+		"name":     "Unverified User",
+		"email":    "unverified@example.com",
+		"password": "testPassword123",
+	})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	messages := suite.Mailer().MessagesTo("unverified@example.com")
+	assert.Len(t, messages, 1)
+	token := extractTokenFromEmail(t, messages[0].Body)
+
+	w = postJSON(suite, "/auth/verify-email", map[string]string{"token": token})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response schemas.UserResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.NotNil(t, response.Data.EmailVerifiedAt)
+}
+
+func TestVerifyEmailInvalidToken(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	w := postJSON(suite, "/auth/verify-email", map[string]string{"token": "bogus"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var response schemas.ErrorResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Contains(t, response.Error, "Invalid or expired verification token")
+}
+
+func TestResendVerificationEmail(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	UserFactory("testPassword123", WithEmail("resend@example.com"), WithUnverifiedEmail())
+
+	req, _ := http.NewRequest("POST", "/auth/verify-email/resend", nil)
+	req.Header.Set("Authorization", "Bearer "+getAuthToken(t, suite, "resend@example.com"))
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, suite.Mailer().MessagesTo("resend@example.com"), 1)
+}
@@ -2,9 +2,11 @@ package test
 
 import (
 	"go-crud/initializers"
+	"go-crud/mailer"
 	"go-crud/models"
 	"go-crud/router"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -27,10 +29,50 @@ func (suite *BaseTestSuite) SetUp() {
 }
 
 func (suite *BaseTestSuite) CleanUp() {
+	initializers.DB.Where("1 = 1").Delete(&models.ImpersonationRequest{})
+	initializers.DB.Where("1 = 1").Delete(&models.Impersonation{})
+	initializers.DB.Where("1 = 1").Delete(&models.AccountDeletion{})
+	initializers.DB.Where("1 = 1").Delete(&models.DataExport{})
+	initializers.DB.Where("1 = 1").Delete(&models.PasskeyChallenge{})
+	initializers.DB.Where("1 = 1").Delete(&models.Passkey{})
+	initializers.DB.Where("1 = 1").Delete(&models.SecurityEvent{})
+	initializers.DB.Where("1 = 1").Delete(&models.AuthThrottle{})
+	initializers.DB.Where("1 = 1").Delete(&models.PersonalAccessToken{})
+	initializers.DB.Where("1 = 1").Delete(&models.OAuthState{})
+	initializers.DB.Where("1 = 1").Delete(&models.UserIdentity{})
+	initializers.DB.Where("1 = 1").Delete(&models.RecoveryCode{})
+	initializers.DB.Where("1 = 1").Delete(&models.OneTimeToken{})
+	initializers.DB.Where("1 = 1").Delete(&models.RevokedToken{})
+	initializers.DB.Where("1 = 1").Delete(&models.RefreshToken{})
+	initializers.DB.Where("1 = 1").Delete(&models.Session{})
+	initializers.DB.Where("1 = 1").Delete(&models.PostRevision{})
+	initializers.DB.Where("1 = 1").Delete(&models.PostRedirect{})
 	initializers.DB.Where("1 = 1").Delete(&models.Post{})
+	initializers.DB.Where("1 = 1").Delete(&models.Invite{})
 	initializers.DB.Where("1 = 1").Delete(&models.User{})
 }
 
+// Mailer returns the in-memory mailer that captures emails sent during the test
+func (suite *BaseTestSuite) Mailer() *mailer.MemoryMailer {
+	memoryMailer, ok := initializers.Mailer.(*mailer.MemoryMailer)
+	if !ok {
+		suite.t.Fatal("tests require the in-memory mailer; unset SMTP_HOST")
+	}
+	return memoryMailer
+}
+
+// WaitForMessagesTo waits until count emails were sent to a recipient, for emails sent in the background
+func (suite *BaseTestSuite) WaitForMessagesTo(to string, count int) []mailer.Message {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		messages := suite.Mailer().MessagesTo(to)
+		if len(messages) >= count || time.Now().After(deadline) {
+			return messages
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
 func (suite *BaseTestSuite) TearDown() {
 	suite.CleanUp()
 }
@@ -0,0 +1,188 @@
+package test
+
+import (
+	"encoding/json"
+	"go-crud/schemas"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Helper function to log in asking for cookie-based auth
+func loginWithCookies(t *testing.T, suite *BaseTestSuite, email string) map[string]*http.Cookie {
+	req := newJSONRequest("POST", "/auth/login", map[string]string{"email": email, "password": "testPassword123"})
+	req.Header.Set("X-Auth-Mode", "cookie")
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	return responseCookies(w)
+}
+
+func responseCookies(w *httptest.ResponseRecorder) map[string]*http.Cookie {
+	cookies := map[string]*http.Cookie{}
+	for _, cookie := range w.Result().Cookies() {
+		cookies[cookie.Name] = cookie
+	}
+	return cookies
+}
+
+// Helper function to send a request authenticated by cookies, with an optional CSRF header
+func cookieRequest(suite *BaseTestSuite, method, path string, cookies map[string]*http.Cookie, csrfToken string) *httptest.ResponseRecorder {
+	req := newJSONRequest(method, path, nil)
+	for _, cookie := range cookies {
+		req.AddCookie(&http.Cookie{Name: cookie.Name, Value: cookie.Value})
+	}
+	if csrfToken != "" {
+		req.Header.Set("X-CSRF-Token", csrfToken)
+	}
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	return w
+}
+
+func TestCookieLoginSetsCookies(t *testing.T) {
+	t.Setenv("AUTH_COOKIES_ENABLED", "true")
+
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	UserFactory("testPassword123", WithEmail("cookie-login@example.com"))
+
+	req := newJSONRequest("POST", "/auth/login", map[string]string{"email": "cookie-login@example.com", "password": "testPassword123"})
+	req.Header.Set("X-Auth-Mode", "cookie")
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// The tokens are only in the cookies
+	var response schemas.AuthResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Empty(t, response.Token)
+	assert.Empty(t, response.RefreshToken)
+	assert.Equal(t, "cookie-login@example.com", response.Data.Email)
+
+	cookies := responseCookies(w)
+	access := cookies["access_token"]
+	if assert.NotNil(t, access) {
+		assert.True(t, access.HttpOnly)
+		assert.True(t, access.Secure)
+		assert.Equal(t, http.SameSiteLaxMode, access.SameSite)
+		assert.Equal(t, "/", access.Path)
+	}
+	if refresh := cookies["refresh_token"]; assert.NotNil(t, refresh) {
+		assert.True(t, refresh.HttpOnly)
+		assert.Equal(t, "/auth", refresh.Path)
+	}
+	if csrf := cookies["csrf_token"]; assert.NotNil(t, csrf) {
+		assert.False(t, csrf.HttpOnly)
+		assert.NotEmpty(t, csrf.Value)
+	}
+}
+
+func TestCookieModeDisabledReturnsTokensInBody(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	UserFactory("testPassword123", WithEmail("cookie-disabled@example.com"))
+
+	req := newJSONRequest("POST", "/auth/login", map[string]string{"email": "cookie-disabled@example.com", "password": "testPassword123"})
+	req.Header.Set("X-Auth-Mode", "cookie")
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response schemas.AuthResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NotEmpty(t, response.Token)
+	assert.Empty(t, w.Result().Cookies())
+}
+
+func TestCookieAuthSafeMethodWithoutCSRF(t *testing.T) {
+	t.Setenv("AUTH_COOKIES_ENABLED", "true")
+
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	UserFactory("testPassword123", WithEmail("cookie-get@example.com"))
+	cookies := loginWithCookies(t, suite, "cookie-get@example.com")
+
+	w := cookieRequest(suite, "GET", "/users/me/sessions", cookies, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestCookieAuthUnsafeMethodRequiresCSRF(t *testing.T) {
+	t.Setenv("AUTH_COOKIES_ENABLED", "true")
+
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	UserFactory("testPassword123", WithEmail("cookie-csrf@example.com"))
+	cookies := loginWithCookies(t, suite, "cookie-csrf@example.com")
+
+	w := cookieRequest(suite, "POST", "/auth/logout", cookies, "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	var response schemas.ErrorResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, "csrf_token_invalid", response.Code)
+
+	w = cookieRequest(suite, "POST", "/auth/logout", cookies, "not-the-token")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = cookieRequest(suite, "POST", "/auth/logout", cookies, cookies["csrf_token"].Value)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Logging out expires the cookies and revokes the refresh token from the cookie
+	cleared := responseCookies(w)
+	if assert.NotNil(t, cleared["access_token"]) {
+		assert.True(t, cleared["access_token"].MaxAge < 0)
+	}
+	w = refreshTokens(suite, cookies["refresh_token"].Value)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestCookieRefresh(t *testing.T) {
+	t.Setenv("AUTH_COOKIES_ENABLED", "true")
+
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	UserFactory("testPassword123", WithEmail("cookie-refresh@example.com"))
+	cookies := loginWithCookies(t, suite, "cookie-refresh@example.com")
+
+	w := cookieRequest(suite, "POST", "/auth/refresh", cookies, "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = cookieRequest(suite, "POST", "/auth/refresh", cookies, cookies["csrf_token"].Value)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	refreshed := responseCookies(w)
+	assert.NotEqual(t, cookies["access_token"].Value, refreshed["access_token"].Value)
+	assert.NotEqual(t, cookies["refresh_token"].Value, refreshed["refresh_token"].Value)
+	assert.NotEqual(t, cookies["csrf_token"].Value, refreshed["csrf_token"].Value)
+
+	w = cookieRequest(suite, "GET", "/users/me/sessions", refreshed, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestBearerAuthUnaffectedByCookieMode(t *testing.T) {
+	t.Setenv("AUTH_COOKIES_ENABLED", "true")
+
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	UserFactory("testPassword123", WithEmail("cookie-bearer@example.com"))
+	auth := loginUser(t, suite, "cookie-bearer@example.com")
+	assert.NotEmpty(t, auth.Token)
+
+	// Header-authenticated requests can't be forged cross-site, so they need no CSRF token
+	req := newJSONRequest("POST", "/auth/logout", nil)
+	req.Header.Set("Authorization", "Bearer "+auth.Token)
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
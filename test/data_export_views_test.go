@@ -0,0 +1,147 @@
+package test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go-crud/initializers"
+	"go-crud/models"
+	"go-crud/schemas"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Helper function to request an export and wait until it has been built
+func waitForExport(t *testing.T, suite *BaseTestSuite, token string) schemas.DataExportResponse {
+	w := authJSON(suite, "POST", "/users/me/export", token, nil)
+	assert.Equal(t, http.StatusAccepted, w.Code)
+
+	var response schemas.DataExportResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	path := fmt.Sprintf("/users/me/exports/%d", response.Data.ID)
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(50 * time.Millisecond) {
+		w = authJSON(suite, "GET", path, token, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		json.Unmarshal(w.Body.Bytes(), &response)
+		if response.Data.Status != models.DataExportPending && response.Data.Status != models.DataExportProcessing {
+			break
+		}
+	}
+	return response
+}
+
+func downloadExport(suite *BaseTestSuite, downloadURL string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, newJSONRequest("GET", downloadURL, nil))
+	return w
+}
+
+func readArchive(t *testing.T, data []byte) map[string][]byte {
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string][]byte{}
+	for _, file := range reader.File {
+		opened, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[file.Name], _ = io.ReadAll(opened)
+		opened.Close()
+	}
+	return files
+}
+
+func TestDataExport(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	user := UserFactory("testPassword123", WithEmail("export@example.com"))
+	token := getAuthToken(t, suite, "export@example.com")
+	post := PostFactory(WithUserID(user.ID), WithContentMarkdown("# Exported\n\nHello"))
+	tag := TagFactory()
+	initializers.DB.Create(&models.PostTag{PostID: post.ID, TagID: tag.ID})
+	initializers.DB.Create(&models.PostRevision{
+		PostID:          post.ID,
+		Number:          1,
+		AuthorID:        &user.ID,
+		Title:           post.Title,
+		ContentMarkdown: "# Draft\n\nHello",
+	})
+	suite.Mailer().Reset()
+
+	response := waitForExport(t, suite, token)
+	assert.Equal(t, models.DataExportReady, response.Data.Status)
+	assert.NotEmpty(t, response.DownloadURL)
+	assert.NotNil(t, response.Data.ExpiresAt)
+	assert.Len(t, suite.Mailer().MessagesTo("export@example.com"), 1)
+
+	// The signed URL works without an Authorization header
+	w := downloadExport(suite, response.DownloadURL)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/zip", w.Header().Get("Content-Type"))
+
+	files := readArchive(t, w.Body.Bytes())
+	assert.Contains(t, string(files["profile.json"]), "export@example.com")
+	assert.NotContains(t, string(files["profile.json"]), "hashed_password")
+	assert.Equal(t, "# Exported\n\nHello", string(files[fmt.Sprintf("posts/%d.md", post.ID)]))
+	assert.Equal(t, post.ContentJSON, string(files[fmt.Sprintf("posts/%d.json", post.ID)]))
+
+	var posts []models.Post
+	json.Unmarshal(files["posts.json"], &posts)
+	if assert.Len(t, posts, 1) {
+		assert.Equal(t, post.ContentJSON, posts[0].ContentJSON)
+		assert.Len(t, posts[0].Tags, 1)
+	}
+	assert.Contains(t, string(files["post_tags.json"]), tag.Name)
+
+	var revisions []models.PostRevision
+	json.Unmarshal(files["post_revisions.json"], &revisions)
+	if assert.Len(t, revisions, 1) {
+		assert.Equal(t, post.ID, revisions[0].PostID)
+		assert.Equal(t, "# Draft\n\nHello", revisions[0].ContentMarkdown)
+	}
+}
+
+func TestDataExportOfAnotherUser(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	UserFactory("testPassword123", WithEmail("export-owner@example.com"))
+	UserFactory("testPassword123", WithEmail("export-other@example.com"))
+	ownerToken := getAuthToken(t, suite, "export-owner@example.com")
+	otherToken := getAuthToken(t, suite, "export-other@example.com")
+
+	response := waitForExport(t, suite, ownerToken)
+
+	w := authJSON(suite, "GET", fmt.Sprintf("/users/me/exports/%d", response.Data.ID), otherToken, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestDataExportDownloadLinkExpires(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	UserFactory("testPassword123", WithEmail("export-expired@example.com"))
+	token := getAuthToken(t, suite, "export-expired@example.com")
+
+	t.Setenv("DATA_EXPORT_URL_TTL", "1ns")
+	t.Setenv("JWT_CLOCK_SKEW", "1ns")
+	response := waitForExport(t, suite, token)
+	time.Sleep(time.Second)
+
+	w := downloadExport(suite, response.DownloadURL)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = downloadExport(suite, "/exports/download?token=not-a-token")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
@@ -5,6 +5,8 @@ import (
 	"go-crud/initializers"
 	"go-crud/models"
 	"go-crud/services"
+	"strings"
+	"time"
 
 	"github.com/brianvoe/gofakeit/v6"
 )
@@ -17,6 +19,12 @@ func WithTitle(title string) PostOption {
 	}
 }
 
+func WithSlug(slug string) PostOption {
+	return func(p *models.Post) {
+		p.Slug = slug
+	}
+}
+
 func WithContentMarkdown(content string) PostOption {
 	return func(p *models.Post) {
 		p.ContentMarkdown = content
@@ -52,6 +60,8 @@ func PostFactory(opts ...PostOption) models.Post {
 		ContentMarkdown: gofakeit.Paragraph(1, 3, 12, " "),
 		Status:          models.Published,
 	}
+	// Inserted directly, so the slug is not generated by the service
+	post.Slug = services.Slugify(post.Title) + "-" + strings.ToLower(gofakeit.LetterN(8))
 
 	// Generate ContentJSON in Milkdown ProseMirror format
 	docContent := []map[string]interface{}{}
@@ -153,11 +163,25 @@ func WithName(name string) UserOption {
 	}
 }
 
+func WithUnverifiedEmail() UserOption {
+	return func(u *models.User) {
+		u.EmailVerifiedAt = nil
+	}
+}
+
+func WithRole(role models.Role) UserOption {
+	return func(u *models.User) {
+		u.Role = role
+	}
+}
+
 func UserFactory(plainPassword string, opts ...UserOption) models.User {
+	verifiedAt := time.Now()
 	user := &models.User{
-		Name:           gofakeit.Name(),
-		Email:          gofakeit.Email(),
-		HashedPassword: plainPassword, // Set plain password
+		Name:            gofakeit.Name(),
+		Email:           gofakeit.Email(),
+		HashedPassword:  plainPassword, // Set plain password
+		EmailVerifiedAt: &verifiedAt,
 	}
 
 	for _, opt := range opts {
@@ -172,3 +196,13 @@ func UserFactory(plainPassword string, opts ...UserOption) models.User {
 	initializers.DB.Create(user)
 	return *user
 }
+
+func TagFactory() models.Tag {
+	tag := &models.Tag{
+		Name:        gofakeit.LetterN(12),
+		Description: gofakeit.Sentence(5),
+	}
+
+	initializers.DB.Create(tag)
+	return *tag
+}
@@ -0,0 +1,134 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-crud/initializers"
+	"go-crud/models"
+	"go-crud/schemas"
+	"go-crud/services"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Helper function to impersonate a user as an admin
+func impersonate(t *testing.T, suite *BaseTestSuite, adminToken string, userID uint) schemas.ImpersonationTokenResponse {
+	w := authJSON(suite, "POST", fmt.Sprintf("/users/%d/impersonate", userID), adminToken,
+		map[string]string{"reason": "Reproducing a bug report"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var response schemas.ImpersonationTokenResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return response
+}
+
+func TestImpersonationIsAudited(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	admin := UserFactory("testPassword123", WithEmail("impersonate-admin@example.com"), WithRole(models.RoleAdmin))
+	user := UserFactory("testPassword123", WithEmail("impersonate-user@example.com"))
+	adminToken := getAuthToken(t, suite, "impersonate-admin@example.com")
+
+	response := impersonate(t, suite, adminToken, user.ID)
+	assert.NotEmpty(t, response.Token)
+
+	claims, err := services.ParseToken(response.Token)
+	assert.NoError(t, err)
+	assert.Equal(t, user.ID, claims.UserID)
+	assert.Equal(t, admin.ID, claims.ImpersonatorID)
+
+	w := authJSON(suite, "GET", "/users/me/posts?status=draft", response.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Impersonated users are not admins
+	w = authJSON(suite, "GET", "/invites", response.Token, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = authJSON(suite, "GET", fmt.Sprintf("/impersonations/%d", response.Data.ID), adminToken, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var audit schemas.ImpersonationResponse
+	json.Unmarshal(w.Body.Bytes(), &audit)
+	assert.Equal(t, "Reproducing a bug report", audit.Data.Reason)
+	if assert.Len(t, audit.Requests, 2) {
+		assert.Equal(t, "/users/me/posts?status=draft", audit.Requests[0].Path)
+		assert.Equal(t, http.StatusOK, audit.Requests[0].Status)
+		assert.Equal(t, "/invites", audit.Requests[1].Path)
+		assert.Equal(t, http.StatusForbidden, audit.Requests[1].Status)
+	}
+}
+
+func TestImpersonationCannotChangeCredentials(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	UserFactory("testPassword123", WithEmail("impersonate-admin2@example.com"), WithRole(models.RoleAdmin))
+	user := UserFactory("testPassword123", WithEmail("impersonate-victim@example.com"))
+	adminToken := getAuthToken(t, suite, "impersonate-admin2@example.com")
+	token := impersonate(t, suite, adminToken, user.ID).Token
+
+	w := authJSON(suite, "POST", "/users/me/password", token, map[string]string{
+		"current_password": "testPassword123",
+		"new_password":     "n3w-s3cret-pass",
+	})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, "impersonation_denied", errorCode(w))
+
+	w = authJSON(suite, "DELETE", fmt.Sprintf("/users/%d", user.ID), token, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// Impersonation tokens cannot be used to impersonate again
+	w = authJSON(suite, "POST", fmt.Sprintf("/users/%d/impersonate", user.ID), token,
+		map[string]string{"reason": "Nested"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	getAuthToken(t, suite, "impersonate-victim@example.com")
+}
+
+func TestImpersonationCannotExportData(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	UserFactory("testPassword123", WithEmail("impersonate-admin4@example.com"), WithRole(models.RoleAdmin))
+	user := UserFactory("testPassword123", WithEmail("impersonate-export@example.com"))
+	adminToken := getAuthToken(t, suite, "impersonate-admin4@example.com")
+	token := impersonate(t, suite, adminToken, user.ID).Token
+	suite.Mailer().Reset()
+
+	w := authJSON(suite, "POST", "/users/me/export", token, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, "impersonation_denied", errorCode(w))
+
+	w = authJSON(suite, "GET", "/users/me/exports/1", token, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, "impersonation_denied", errorCode(w))
+
+	var count int64
+	initializers.DB.Model(&models.DataExport{}).Where("user_id = ?", user.ID).Count(&count)
+	assert.Zero(t, count)
+	assert.Empty(t, suite.Mailer().MessagesTo("impersonate-export@example.com"))
+}
+
+func TestImpersonationRequiresAdmin(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	UserFactory("testPassword123", WithEmail("impersonate-editor@example.com"), WithRole(models.RoleEditor))
+	UserFactory("testPassword123", WithEmail("impersonate-admin3@example.com"), WithRole(models.RoleAdmin))
+	user := UserFactory("testPassword123", WithEmail("impersonate-target@example.com"))
+	editorToken := getAuthToken(t, suite, "impersonate-editor@example.com")
+	adminToken := getAuthToken(t, suite, "impersonate-admin3@example.com")
+
+	w := authJSON(suite, "POST", fmt.Sprintf("/users/%d/impersonate", user.ID), editorToken,
+		map[string]string{"reason": "Curious"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// Admins themselves cannot be impersonated
+	other := UserFactory("testPassword123", WithRole(models.RoleAdmin))
+	w = authJSON(suite, "POST", fmt.Sprintf("/users/%d/impersonate", other.ID), adminToken,
+		map[string]string{"reason": "Reproducing a bug report"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
@@ -0,0 +1,200 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-crud/initializers"
+	"go-crud/models"
+	"go-crud/schemas"
+	"go-crud/services"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Helper function to create an invite as an admin and return its code
+func createInvite(t *testing.T, suite *BaseTestSuite, token string, body map[string]interface{}) schemas.InviteCreatedResponse {
+	w := authJSON(suite, "POST", "/invites", token, body)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var response schemas.InviteCreatedResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return response
+}
+
+func signUp(suite *BaseTestSuite, email, inviteCode string) *httptest.ResponseRecorder {
+	return postJSON(suite, "/users", map[string]string{
+		"name":        "New User",
+		"email":       email,
+		"password":    "plum-orbit-canvas-42",
+		"invite_code": inviteCode,
+	})
+}
+
+func errorCode(w *httptest.ResponseRecorder) string {
+	var response schemas.ErrorResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return response.Code
+}
+
+func TestRegistrationClosed(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+	t.Setenv("REGISTRATION_MODE", services.RegistrationClosed)
+
+	w := signUp(suite, "closed@example.com", "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, "registration_closed", errorCode(w))
+
+	var count int64
+	initializers.DB.Model(&models.User{}).Where("email = ?", "closed@example.com").Count(&count)
+	assert.Equal(t, int64(0), count)
+}
+
+func TestRefusedSignupsSkipPasswordHashing(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	// Hashing with these settings takes seconds, so a quick answer means the password was not hashed
+	t.Setenv("ARGON2_ITERATIONS", "300")
+
+	t.Setenv("REGISTRATION_MODE", services.RegistrationClosed)
+	start := time.Now()
+	w := signUp(suite, "closed-fast@example.com", "")
+	assert.Equal(t, "registration_closed", errorCode(w))
+	assert.Less(t, time.Since(start), time.Second)
+
+	t.Setenv("REGISTRATION_MODE", services.RegistrationInviteOnly)
+	start = time.Now()
+	w = signUp(suite, "invite-fast@example.com", "inv_doesnotexist")
+	assert.Equal(t, "invalid_invite", errorCode(w))
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestInviteOnlyRegistration(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+	t.Setenv("REGISTRATION_MODE", services.RegistrationInviteOnly)
+
+	admin := UserFactory("testPassword123", WithEmail("invite-admin@example.com"), WithRole(models.RoleAdmin))
+	token := getAuthToken(t, suite, "invite-admin@example.com")
+
+	w := signUp(suite, "uninvited@example.com", "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, "invite_required", errorCode(w))
+
+	invite := createInvite(t, suite, token, map[string]interface{}{"role": "editor"})
+	assert.Equal(t, models.RoleEditor, invite.Data.Role)
+	assert.Equal(t, 1, invite.Data.MaxUses)
+	assert.NotNil(t, invite.Data.ExpiresAt)
+
+	w = signUp(suite, "invited@example.com", invite.Code)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var user models.User
+	initializers.DB.Where("email = ?", "invited@example.com").First(&user)
+	assert.Equal(t, models.RoleEditor, user.Role)
+	if assert.NotNil(t, user.InviteID) {
+		assert.Equal(t, invite.Data.ID, *user.InviteID)
+	}
+
+	var stored models.Invite
+	initializers.DB.First(&stored, invite.Data.ID)
+	assert.Equal(t, 1, stored.Uses)
+	assert.Equal(t, &admin.ID, stored.CreatedByID)
+}
+
+func TestInviteIsUsedUp(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+	t.Setenv("REGISTRATION_MODE", services.RegistrationInviteOnly)
+
+	UserFactory("testPassword123", WithEmail("invite-uses@example.com"), WithRole(models.RoleAdmin))
+	token := getAuthToken(t, suite, "invite-uses@example.com")
+	invite := createInvite(t, suite, token, map[string]interface{}{"max_uses": 2})
+
+	assert.Equal(t, http.StatusCreated, signUp(suite, "first@example.com", invite.Code).Code)
+	assert.Equal(t, http.StatusCreated, signUp(suite, "second@example.com", invite.Code).Code)
+
+	w := signUp(suite, "third@example.com", invite.Code)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "invalid_invite", errorCode(w))
+}
+
+func TestFailedSignupDoesNotUseInvite(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+	t.Setenv("REGISTRATION_MODE", services.RegistrationInviteOnly)
+
+	UserFactory("testPassword123", WithEmail("invite-taken@example.com"), WithRole(models.RoleAdmin))
+	token := getAuthToken(t, suite, "invite-taken@example.com")
+	invite := createInvite(t, suite, token, map[string]interface{}{})
+
+	// The email is taken, so the account is not created and the invite keeps its use
+	w := signUp(suite, "invite-taken@example.com", invite.Code)
+	assert.NotEqual(t, http.StatusCreated, w.Code)
+
+	var stored models.Invite
+	initializers.DB.First(&stored, invite.Data.ID)
+	assert.Equal(t, 0, stored.Uses)
+}
+
+func TestExpiredAndRevokedInvites(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+	t.Setenv("REGISTRATION_MODE", services.RegistrationInviteOnly)
+
+	UserFactory("testPassword123", WithEmail("invite-revoke@example.com"), WithRole(models.RoleAdmin))
+	token := getAuthToken(t, suite, "invite-revoke@example.com")
+
+	t.Setenv("INVITE_TTL", "1ns")
+	expired := createInvite(t, suite, token, map[string]interface{}{})
+	assert.Equal(t, http.StatusBadRequest, signUp(suite, "expired@example.com", expired.Code).Code)
+
+	revoked := createInvite(t, suite, token, map[string]interface{}{"expires_in_days": 7})
+	w := authJSON(suite, "DELETE", fmt.Sprintf("/invites/%d", revoked.Data.ID), token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, http.StatusBadRequest, signUp(suite, "revoked@example.com", revoked.Code).Code)
+
+	w = authJSON(suite, "GET", "/invites", token, nil)
+	var response schemas.ListInvitesResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Len(t, response.Data, 1)
+	assert.Equal(t, expired.Data.ID, response.Data[0].ID)
+}
+
+func TestDomainAllowlistRegistration(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+	t.Setenv("REGISTRATION_MODE", services.RegistrationDomainAllowlist)
+	t.Setenv("REGISTRATION_ALLOWED_DOMAINS", "example.com, @corp.example.org")
+
+	assert.Equal(t, http.StatusCreated, signUp(suite, "staff@Corp.Example.org", "").Code)
+
+	w := signUp(suite, "outsider@example.net", "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, "email_domain_not_allowed", errorCode(w))
+
+	// An invite lets people from other domains in
+	UserFactory("testPassword123", WithEmail("domain-admin@example.com"), WithRole(models.RoleAdmin))
+	token := getAuthToken(t, suite, "domain-admin@example.com")
+	invite := createInvite(t, suite, token, map[string]interface{}{})
+	assert.Equal(t, http.StatusCreated, signUp(suite, "outsider@example.net", invite.Code).Code)
+}
+
+func TestManageInvitesRequiresAdmin(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	UserFactory("testPassword123", WithEmail("invite-editor@example.com"), WithRole(models.RoleEditor))
+	token := getAuthToken(t, suite, "invite-editor@example.com")
+
+	w := authJSON(suite, "POST", "/invites", token, map[string]interface{}{})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = authJSON(suite, "GET", "/invites", token, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
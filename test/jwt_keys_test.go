@@ -0,0 +1,262 @@
+package test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"go-crud/models"
+	"go-crud/schemas"
+	"go-crud/services"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+// Helper function to write a PEM encoded private key as <kid>.pem
+func writeSigningKey(t *testing.T, dir, kid string, key interface{}) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(filepath.Join(dir, kid+".pem"), data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Helper function to replace a key file with its public half only (a retired key)
+func writeVerificationKey(t *testing.T, dir, kid string, key interface{}) {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	if err := os.WriteFile(filepath.Join(dir, kid+".pem"), data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newRSAKey(t *testing.T) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func tokenHeader(t *testing.T, token string) map[string]interface{} {
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed.Header
+}
+
+func testTokenUser() models.User {
+	return models.User{ID: 42, Email: "keys@example.com", Name: "Key Tester"}
+}
+
+func TestRS256TokensCarryKid(t *testing.T) {
+	dir := t.TempDir()
+	writeSigningKey(t, dir, "2025-01", newRSAKey(t))
+	t.Setenv("JWT_KEYS_DIR", dir)
+
+	token, err := services.GenerateToken(testTokenUser())
+	assert.NoError(t, err)
+
+	header := tokenHeader(t, token)
+	assert.Equal(t, "RS256", header["alg"])
+	assert.Equal(t, "2025-01", header["kid"])
+
+	claims, err := services.ParseToken(token)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(42), claims.UserID)
+	assert.Equal(t, services.TokenIssuer(), claims.Issuer)
+	assert.Contains(t, claims.Audience, services.TokenAudience())
+}
+
+func TestEdDSATokens(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+	writeSigningKey(t, dir, "ed-1", key)
+	t.Setenv("JWT_KEYS_DIR", dir)
+
+	token, err := services.GenerateToken(testTokenUser())
+	assert.NoError(t, err)
+	assert.Equal(t, "EdDSA", tokenHeader(t, token)["alg"])
+
+	_, err = services.ParseToken(token)
+	assert.NoError(t, err)
+}
+
+func TestKeyRotationKeepsOldKeysValid(t *testing.T) {
+	dir := t.TempDir()
+	oldKey := newRSAKey(t)
+	writeSigningKey(t, dir, "key-1", oldKey)
+	t.Setenv("JWT_KEYS_DIR", dir)
+
+	oldToken, err := services.GenerateToken(testTokenUser())
+	assert.NoError(t, err)
+
+	// Rotate: add a new key and make it the active one
+	writeSigningKey(t, dir, "key-2", newRSAKey(t))
+	t.Setenv("JWT_ACTIVE_KID", "key-2")
+
+	newToken, err := services.GenerateToken(testTokenUser())
+	assert.NoError(t, err)
+	assert.Equal(t, "key-2", tokenHeader(t, newToken)["kid"])
+
+	_, err = services.ParseToken(oldToken)
+	assert.NoError(t, err)
+
+	// Retire the old key: only its public half is kept for verification. The key ring
+	// is reloaded whenever its configuration changes.
+	writeVerificationKey(t, dir, "key-1", &oldKey.PublicKey)
+	t.Setenv("JWT_ACTIVE_KID", "")
+
+	_, err = services.ParseToken(oldToken)
+	assert.NoError(t, err)
+
+	ring, err := services.CurrentKeyRing()
+	assert.NoError(t, err)
+	assert.Len(t, ring.JWKS(), 2)
+
+	// Once removed, tokens signed with it are rejected
+	os.Remove(filepath.Join(dir, "key-1.pem"))
+	t.Setenv("JWT_ACTIVE_KID", "key-2")
+
+	_, err = services.ParseToken(oldToken)
+	assert.Error(t, err)
+	_, err = services.ParseToken(newToken)
+	assert.NoError(t, err)
+}
+
+func TestParseTokenChecksIssuerAndAudience(t *testing.T) {
+	dir := t.TempDir()
+	writeSigningKey(t, dir, "key-1", newRSAKey(t))
+	t.Setenv("JWT_KEYS_DIR", dir)
+
+	token, err := services.GenerateToken(testTokenUser())
+	assert.NoError(t, err)
+
+	t.Setenv("JWT_AUDIENCE", "another-service")
+	_, err = services.ParseToken(token)
+	assert.Error(t, err)
+
+	t.Setenv("JWT_AUDIENCE", "")
+	t.Setenv("JWT_ISSUER", "someone-else")
+	_, err = services.ParseToken(token)
+	assert.Error(t, err)
+}
+
+func TestParseTokenAllowsClockSkew(t *testing.T) {
+	dir := t.TempDir()
+	key := newRSAKey(t)
+	writeSigningKey(t, dir, "key-1", key)
+	t.Setenv("JWT_KEYS_DIR", dir)
+
+	// A token that expired ten seconds ago, as seen by a server with a slightly fast clock
+	now := time.Now()
+	expired := jwt.NewWithClaims(jwt.SigningMethodRS256, services.AccessClaims{
+		UserID: 42,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        "skewed",
+			Issuer:    services.TokenIssuer(),
+			Audience:  jwt.ClaimStrings{services.TokenAudience()},
+			IssuedAt:  jwt.NewNumericDate(now.Add(-time.Minute)),
+			ExpiresAt: jwt.NewNumericDate(now.Add(-10 * time.Second)),
+		},
+	})
+	expired.Header["kid"] = "key-1"
+	token, err := expired.SignedString(key)
+	assert.NoError(t, err)
+
+	t.Setenv("JWT_CLOCK_SKEW", "30s")
+	_, err = services.ParseToken(token)
+	assert.NoError(t, err)
+
+	t.Setenv("JWT_CLOCK_SKEW", "1s")
+	_, err = services.ParseToken(token)
+	assert.Error(t, err)
+}
+
+func TestParseTokenRejectsAlgorithmConfusion(t *testing.T) {
+	dir := t.TempDir()
+	key := newRSAKey(t)
+	writeSigningKey(t, dir, "key-1", key)
+	t.Setenv("JWT_KEYS_DIR", dir)
+
+	// HS256 token "signed" with the public key, which anyone can fetch from the JWKS endpoint
+	publicDER, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	assert.NoError(t, err)
+
+	now := time.Now()
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, services.AccessClaims{
+		UserID: 42,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    services.TokenIssuer(),
+			Audience:  jwt.ClaimStrings{services.TokenAudience()},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+		},
+	})
+	forged.Header["kid"] = "key-1"
+	token, err := forged.SignedString(publicDER)
+	assert.NoError(t, err)
+
+	_, err = services.ParseToken(token)
+	assert.Error(t, err)
+}
+
+func TestJWKSEndpointPublishesVerificationKeys(t *testing.T) {
+	dir := t.TempDir()
+	writeSigningKey(t, dir, "key-1", newRSAKey(t))
+	t.Setenv("JWT_KEYS_DIR", dir)
+
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	UserFactory("testPassword123", WithEmail("jwks@example.com"))
+	token := getAuthToken(t, suite, "jwks@example.com")
+
+	req, _ := http.NewRequest("GET", "/.well-known/jwks.json", nil)
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response schemas.JWKSResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Len(t, response.Keys, 1)
+
+	jwk := response.Keys[0]
+	assert.Equal(t, "RSA", jwk.KeyType)
+	assert.Equal(t, "key-1", jwk.KeyID)
+	assert.Equal(t, "RS256", jwk.Algorithm)
+
+	// Another service can verify the access token with nothing but the published key
+	n, _ := base64.RawURLEncoding.DecodeString(jwk.N)
+	e, _ := base64.RawURLEncoding.DecodeString(jwk.E)
+	publicKey := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+
+	parsed, err := jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
+		return publicKey, nil
+	}, jwt.WithValidMethods([]string{"RS256"}), jwt.WithAudience(services.TokenAudience()), jwt.WithIssuer(services.TokenIssuer()))
+	assert.NoError(t, err)
+	assert.True(t, parsed.Valid)
+}
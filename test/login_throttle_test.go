@@ -0,0 +1,169 @@
+package test
+
+import (
+	"encoding/json"
+	"go-crud/initializers"
+	"go-crud/models"
+	"go-crud/router"
+	"go-crud/schemas"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Helper function to fail a login n times
+func failLogins(t *testing.T, suite *BaseTestSuite, email string, n int) *httptest.ResponseRecorder {
+	var w *httptest.ResponseRecorder
+	for i := 0; i < n; i++ {
+		w = postJSON(suite, "/auth/login", map[string]string{"email": email, "password": "wrongPassword"})
+	}
+	return w
+}
+
+// Helper function to log in from a given client address. Requests built by newJSONRequest have no
+// remote address, so only the per-account counters apply to them.
+func loginFrom(suite *BaseTestSuite, ip, email, password string) *httptest.ResponseRecorder {
+	req := newJSONRequest("POST", "/auth/login", map[string]string{"email": email, "password": password})
+	req.RemoteAddr = ip + ":40000"
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	return w
+}
+
+func TestLoginLockoutAfterRepeatedFailures(t *testing.T) {
+	t.Setenv("LOGIN_MAX_FAILURES", "3")
+	t.Setenv("LOGIN_LOCKOUT_DURATION", "1m")
+
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	user := UserFactory("testPassword123", WithEmail("lockout@example.com"))
+
+	w := failLogins(t, suite, "lockout@example.com", 2)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// The third failure reaches the threshold
+	w = failLogins(t, suite, "lockout@example.com", 1)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+
+	retryAfter, err := strconv.Atoi(w.Header().Get("Retry-After"))
+	assert.NoError(t, err)
+	assert.InDelta(t, 60, retryAfter, 1)
+
+	var response schemas.ErrorResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, "too_many_attempts", response.Code)
+
+	// While locked, even the right password is refused, whatever the email's case
+	w = postJSON(suite, "/auth/login", map[string]string{"email": "Lockout@Example.com", "password": "testPassword123"})
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+
+	var event models.SecurityEvent
+	err = initializers.DB.Where("type = ?", models.AccountLockedEvent).First(&event).Error
+	assert.NoError(t, err)
+	assert.Equal(t, &user.ID, event.UserID)
+}
+
+func TestLoginLockoutSurvivesRestart(t *testing.T) {
+	t.Setenv("LOGIN_MAX_FAILURES", "2")
+
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	UserFactory("testPassword123", WithEmail("restart@example.com"))
+
+	w := failLogins(t, suite, "restart@example.com", 2)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+
+	// A fresh router only has the database to go on
+	restarted := &BaseTestSuite{router: router.SetupRouter(), t: t}
+	w = postJSON(restarted, "/auth/login", map[string]string{"email": "restart@example.com", "password": "testPassword123"})
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+}
+
+func TestLoginLockoutBacksOffExponentially(t *testing.T) {
+	t.Setenv("LOGIN_MAX_FAILURES", "2")
+	t.Setenv("LOGIN_LOCKOUT_DURATION", "1m")
+
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	UserFactory("testPassword123", WithEmail("backoff@example.com"))
+
+	w := failLogins(t, suite, "backoff@example.com", 2)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+
+	// Let the first lockout run out without resetting the count, then fail again
+	initializers.DB.Model(&models.AuthThrottle{}).
+		Where("key = ?", "account:backoff@example.com").
+		Update("locked_until", time.Now().Add(-time.Second))
+
+	w = failLogins(t, suite, "backoff@example.com", 1)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+
+	retryAfter, _ := strconv.Atoi(w.Header().Get("Retry-After"))
+	assert.InDelta(t, 120, retryAfter, 1)
+
+	var count int64
+	initializers.DB.Model(&models.SecurityEvent{}).Where("type = ?", models.AccountLockedEvent).Count(&count)
+	assert.Equal(t, int64(2), count)
+}
+
+func TestLoginSuccessResetsFailureCount(t *testing.T) {
+	t.Setenv("LOGIN_MAX_FAILURES", "3")
+
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	UserFactory("testPassword123", WithEmail("reset-count@example.com"))
+
+	failLogins(t, suite, "reset-count@example.com", 2)
+	loginUser(t, suite, "reset-count@example.com")
+
+	w := failLogins(t, suite, "reset-count@example.com", 2)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestLoginLockoutAppliesToUnknownEmails(t *testing.T) {
+	t.Setenv("LOGIN_MAX_FAILURES", "2")
+
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	// Unknown accounts lock the same way, so lockouts don't reveal which emails exist
+	w := failLogins(t, suite, "nobody@example.com", 2)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+}
+
+func TestLoginLockoutPerIP(t *testing.T) {
+	t.Setenv("LOGIN_MAX_FAILURES_PER_IP", "3")
+
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	UserFactory("testPassword123", WithEmail("ip-target@example.com"))
+
+	// Spraying different accounts from one address locks the address
+	loginFrom(suite, "203.0.113.7", "spray-1@example.com", "wrongPassword")
+	loginFrom(suite, "203.0.113.7", "spray-2@example.com", "wrongPassword")
+	w := loginFrom(suite, "203.0.113.7", "spray-3@example.com", "wrongPassword")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+
+	w = loginFrom(suite, "203.0.113.7", "ip-target@example.com", "testPassword123")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+
+	// The account itself is not locked
+	w = loginFrom(suite, "198.51.100.20", "ip-target@example.com", "testPassword123")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var event models.SecurityEvent
+	err := initializers.DB.Where("type = ?", models.IPLockedEvent).First(&event).Error
+	assert.NoError(t, err)
+	assert.Nil(t, event.UserID)
+}
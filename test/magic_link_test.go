@@ -0,0 +1,150 @@
+package test
+
+import (
+	"encoding/json"
+	"go-crud/initializers"
+	"go-crud/models"
+	"go-crud/schemas"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Helper function to request a magic link and return the token from the email
+func requestMagicLink(t *testing.T, suite *BaseTestSuite, email string) string {
+	suite.Mailer().Reset()
+
+	w := postJSON(suite, "/auth/magic-link", map[string]string{"email": email})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	messages := suite.WaitForMessagesTo(email, 1)
+	if !assert.Len(t, messages, 1) {
+		return ""
+	}
+	assert.Equal(t, "Your sign-in link", messages[0].Subject)
+	return extractTokenFromEmail(t, messages[0].Body)
+}
+
+func TestMagicLinkLogin(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	UserFactory("testPassword123", WithEmail("magic@example.com"))
+	token := requestMagicLink(t, suite, "magic@example.com")
+
+	w := postJSON(suite, "/auth/magic-link/verify", map[string]string{"token": token})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response schemas.AuthResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NotEmpty(t, response.Token)
+	assert.NotEmpty(t, response.RefreshToken)
+	assert.Equal(t, "magic@example.com", response.Data.Email)
+
+	// Links are single-use
+	w = postJSON(suite, "/auth/magic-link/verify", map[string]string{"token": token})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestMagicLinkUnknownEmail(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+	suite.Mailer().Reset()
+
+	UserFactory("testPassword123", WithEmail("magic-known@example.com"))
+
+	w := postJSON(suite, "/auth/magic-link", map[string]string{"email": "magic-known@example.com"})
+	unknown := postJSON(suite, "/auth/magic-link", map[string]string{"email": "magic-nobody@example.com"})
+
+	// Same answer either way, but only real accounts get an email
+	assert.Equal(t, w.Code, unknown.Code)
+	assert.Equal(t, w.Body.String(), unknown.Body.String())
+	assert.Len(t, suite.WaitForMessagesTo("magic-known@example.com", 1), 1)
+	assert.Empty(t, suite.Mailer().MessagesTo("magic-nobody@example.com"))
+}
+
+func TestMagicLinkExpired(t *testing.T) {
+	t.Setenv("MAGIC_LINK_TTL", "1ns")
+
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	UserFactory("testPassword123", WithEmail("magic-expired@example.com"))
+	token := requestMagicLink(t, suite, "magic-expired@example.com")
+
+	w := postJSON(suite, "/auth/magic-link/verify", map[string]string{"token": token})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestMagicLinkRateLimitedPerEmail(t *testing.T) {
+	t.Setenv("MAGIC_LINK_MAX_REQUESTS", "2")
+
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	for i := 0; i < 2; i++ {
+		w := postJSON(suite, "/auth/magic-link", map[string]string{"email": "magic-limit@example.com"})
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+
+	w := postJSON(suite, "/auth/magic-link", map[string]string{"email": "magic-limit@example.com"})
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+
+	// Other addresses are unaffected
+	w = postJSON(suite, "/auth/magic-link", map[string]string{"email": "magic-other@example.com"})
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestMagicLinkVerifiesEmail(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	// An account without a password, so only the owner of the address can get into it
+	user := UserFactory("", WithEmail("magic-unverified@example.com"), WithUnverifiedEmail())
+	initializers.DB.Model(&user).Update("hashed_password", "")
+	token := requestMagicLink(t, suite, "magic-unverified@example.com")
+
+	w := postJSON(suite, "/auth/magic-link/verify", map[string]string{"token": token})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var reloaded models.User
+	initializers.DB.First(&reloaded, user.ID)
+	assert.NotNil(t, reloaded.EmailVerifiedAt)
+}
+
+func TestMagicLinkRefusesUnverifiedAccountWithPassword(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	// Someone registered the address with a password of their choosing; the link must not hand the
+	// account to the address owner while the registrant can still log in with it
+	user := UserFactory("squatterPassword123", WithEmail("magic-squatted@example.com"), WithUnverifiedEmail())
+	token := requestMagicLink(t, suite, "magic-squatted@example.com")
+
+	w := postJSON(suite, "/auth/magic-link/verify", map[string]string{"token": token})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, "email_not_verified", errorCode(w))
+
+	var reloaded models.User
+	initializers.DB.First(&reloaded, user.ID)
+	assert.Nil(t, reloaded.EmailVerifiedAt)
+}
+
+func TestMagicLinkRequiresMFA(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	UserFactory("testPassword123", WithEmail("magic-mfa@example.com"))
+	enableTOTP(t, suite, getAuthToken(t, suite, "magic-mfa@example.com"))
+	token := requestMagicLink(t, suite, "magic-mfa@example.com")
+
+	w := postJSON(suite, "/auth/magic-link/verify", map[string]string{"token": token})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response schemas.MFAChallengeResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.True(t, response.MFARequired)
+	assert.NotEmpty(t, response.MFAToken)
+}
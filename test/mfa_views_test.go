@@ -0,0 +1,205 @@
+package test
+
+import (
+	"encoding/json"
+	"go-crud/schemas"
+	"go-crud/services"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Helper function to call an authenticated MFA endpoint
+func postMFA(suite *BaseTestSuite, path, token string, body interface{}) *httptest.ResponseRecorder {
+	req := newJSONRequest("POST", path, body)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	return w
+}
+
+// Helper function to enroll and confirm TOTP for a user, returning the secret and recovery codes
+func enableTOTP(t *testing.T, suite *BaseTestSuite, token string) (string, []string) {
+	w := postMFA(suite, "/auth/mfa/totp/enroll", token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var enrollment schemas.TOTPEnrollmentResponse
+	json.Unmarshal(w.Body.Bytes(), &enrollment)
+
+	code, err := services.TOTPCode(enrollment.Secret, time.Now())
+	assert.NoError(t, err)
+
+	w = postMFA(suite, "/auth/mfa/totp/confirm", token, map[string]string{"code": code})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var confirmation schemas.RecoveryCodesResponse
+	json.Unmarshal(w.Body.Bytes(), &confirmation)
+
+	return enrollment.Secret, confirmation.RecoveryCodes
+}
+
+func TestTOTPCodeMatchesRFC6238Vectors(t *testing.T) {
+	// Base32 of the RFC 6238 SHA1 test key "12345678901234567890"
+	secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tt := range tests {
+		code, err := services.TOTPCode(secret, time.Unix(tt.unix, 0))
+		assert.NoError(t, err)
+		assert.Equal(t, tt.code, code, "time %d", tt.unix)
+	}
+}
+
+func TestEnrollTOTPReturnsProvisioningURI(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	UserFactory("testPassword123", WithEmail("mfa-enroll@example.com"))
+	token := getAuthToken(t, suite, "mfa-enroll@example.com")
+
+	w := postMFA(suite, "/auth/mfa/totp/enroll", token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response schemas.TOTPEnrollmentResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, response.Secret)
+	assert.Contains(t, response.ProvisioningURI, "otpauth://totp/")
+	assert.Contains(t, response.ProvisioningURI, "secret="+response.Secret)
+}
+
+func TestConfirmTOTPWithInvalidCode(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	UserFactory("testPassword123", WithEmail("mfa-invalid@example.com"))
+	token := getAuthToken(t, suite, "mfa-invalid@example.com")
+
+	postMFA(suite, "/auth/mfa/totp/enroll", token, nil)
+	w := postMFA(suite, "/auth/mfa/totp/confirm", token, map[string]string{"code": "000000x"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestLoginWithTOTPRequiresSecondStep(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	UserFactory("testPassword123", WithEmail("mfa-login@example.com"))
+	secret, recoveryCodes := enableTOTP(t, suite, getAuthToken(t, suite, "mfa-login@example.com"))
+	assert.Len(t, recoveryCodes, 10)
+
+	w := postJSON(suite, "/auth/login", map[string]string{
+		"email":    "mfa-login@example.com",
+		"password": "testPassword123",
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var challenge schemas.MFAChallengeResponse
+	json.Unmarshal(w.Body.Bytes(), &challenge)
+	assert.True(t, challenge.MFARequired)
+	assert.NotEmpty(t, challenge.MFAToken)
+
+	// A wrong code is rejected
+	w = postJSON(suite, "/auth/mfa/verify", map[string]string{"mfa_token": challenge.MFAToken, "code": "000000"})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// The code used during enrollment cannot be replayed, so use the next period's code
+	code, _ := services.TOTPCode(secret, time.Now().Add(30*time.Second))
+	w = postJSON(suite, "/auth/mfa/verify", map[string]string{"mfa_token": challenge.MFAToken, "code": code})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response schemas.AuthResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NotEmpty(t, response.Token)
+	assert.NotEmpty(t, response.RefreshToken)
+
+	// ...and that code is now spent as well
+	w = postJSON(suite, "/auth/mfa/verify", map[string]string{"mfa_token": challenge.MFAToken, "code": code})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestPasswordStepKeepsWrongCodeCount(t *testing.T) {
+	t.Setenv("LOGIN_MAX_FAILURES", "3")
+
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	UserFactory("testPassword123", WithEmail("mfa-throttle@example.com"))
+	enableTOTP(t, suite, getAuthToken(t, suite, "mfa-throttle@example.com"))
+
+	login := func() string {
+		w := postJSON(suite, "/auth/login", map[string]string{
+			"email":    "mfa-throttle@example.com",
+			"password": "testPassword123",
+		})
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var challenge schemas.MFAChallengeResponse
+		json.Unmarshal(w.Body.Bytes(), &challenge)
+		return challenge.MFAToken
+	}
+
+	mfaToken := login()
+	for i := 0; i < 2; i++ {
+		w := postJSON(suite, "/auth/mfa/verify", map[string]string{"mfa_token": mfaToken, "code": "000000"})
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	}
+
+	// Getting the password right again does not forget the wrong codes: the next one locks the account
+	mfaToken = login()
+	w := postJSON(suite, "/auth/mfa/verify", map[string]string{"mfa_token": mfaToken, "code": "000000"})
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+}
+
+func TestLoginWithRecoveryCode(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	UserFactory("testPassword123", WithEmail("mfa-recovery@example.com"))
+	_, recoveryCodes := enableTOTP(t, suite, getAuthToken(t, suite, "mfa-recovery@example.com"))
+
+	w := postJSON(suite, "/auth/login", map[string]string{
+		"email":    "mfa-recovery@example.com",
+		"password": "testPassword123",
+	})
+	var challenge schemas.MFAChallengeResponse
+	json.Unmarshal(w.Body.Bytes(), &challenge)
+
+	w = postJSON(suite, "/auth/mfa/verify", map[string]string{"mfa_token": challenge.MFAToken, "code": recoveryCodes[0]})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Recovery codes are single-use
+	w = postJSON(suite, "/auth/mfa/verify", map[string]string{"mfa_token": challenge.MFAToken, "code": recoveryCodes[0]})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestDisableTOTP(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	UserFactory("testPassword123", WithEmail("mfa-disable@example.com"))
+	token := getAuthToken(t, suite, "mfa-disable@example.com")
+	_, recoveryCodes := enableTOTP(t, suite, token)
+
+	w := postMFA(suite, "/auth/mfa/totp/disable", token, map[string]string{"code": recoveryCodes[1]})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Login goes straight through again
+	response := loginUser(t, suite, "mfa-disable@example.com")
+	assert.NotEmpty(t, response.Token)
+}
@@ -0,0 +1,266 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-crud/initializers"
+	"go-crud/models"
+	"go-crud/schemas"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Helper function to start a social login and return the provider authorization URL
+func startOIDCLogin(t *testing.T, suite *BaseTestSuite, token string) string {
+	method, path := "GET", "/auth/oidc/mock/authorize"
+	if token != "" {
+		method, path = "POST", "/auth/oidc/mock/link"
+	}
+
+	req, _ := http.NewRequest(method, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response schemas.AuthorizationURLResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return response.AuthorizationURL
+}
+
+// Helper function to call the callback endpoint the way the frontend does after the provider redirect
+func oidcCallback(suite *BaseTestSuite, code, state string) *httptest.ResponseRecorder {
+	path := fmt.Sprintf("/auth/oidc/mock/callback?code=%s&state=%s", url.QueryEscape(code), url.QueryEscape(state))
+	req, _ := http.NewRequest("GET", path, nil)
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	return w
+}
+
+func TestOIDCLoginCreatesPasswordlessUser(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+	provider := NewMockOIDCServer(t)
+
+	authorizationURL := startOIDCLogin(t, suite, "")
+	assert.Contains(t, authorizationURL, provider.URL+"/authorize")
+
+	code, state := provider.Authorize(t, authorizationURL, MockOIDCUser{
+		Subject:       "mock-user-1",
+		Email:         "oidc-new@example.com",
+		EmailVerified: true,
+		Name:          "OIDC User",
+	})
+
+	w := oidcCallback(suite, code, state)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response schemas.AuthResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, response.Token)
+	assert.NotEmpty(t, response.RefreshToken)
+	assert.Equal(t, "oidc-new@example.com", response.Data.Email)
+	assert.Equal(t, "OIDC User", response.Data.Name)
+	assert.NotNil(t, response.Data.EmailVerifiedAt)
+
+	var user models.User
+	initializers.DB.Where("email = ?", "oidc-new@example.com").First(&user)
+	assert.Empty(t, user.HashedPassword)
+
+	// Password login must not work for an account without a password
+	w = postJSON(suite, "/auth/login", map[string]string{"email": "oidc-new@example.com", "password": ""})
+	assert.NotEqual(t, http.StatusOK, w.Code)
+	w = postJSON(suite, "/auth/login", map[string]string{"email": "oidc-new@example.com", "password": "anything"})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// Signing in again resolves to the same account
+	code, state = provider.Authorize(t, startOIDCLogin(t, suite, ""), MockOIDCUser{
+		Subject: "mock-user-1",
+		Email:   "oidc-new@example.com",
+	})
+	w = oidcCallback(suite, code, state)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var count int64
+	initializers.DB.Model(&models.User{}).Where("email = ?", "oidc-new@example.com").Count(&count)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestOIDCLoginLinksExistingUserByVerifiedEmail(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+	provider := NewMockOIDCServer(t)
+
+	user := UserFactory("testPassword123", WithEmail("oidc-existing@example.com"))
+
+	code, state := provider.Authorize(t, startOIDCLogin(t, suite, ""), MockOIDCUser{
+		Subject:       "mock-user-2",
+		Email:         "oidc-existing@example.com",
+		EmailVerified: true,
+	})
+
+	w := oidcCallback(suite, code, state)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response schemas.AuthResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, user.ID, response.Data.ID)
+
+	var identity models.UserIdentity
+	err := initializers.DB.Where("provider = ? AND subject = ?", "mock", "mock-user-2").First(&identity).Error
+	assert.NoError(t, err)
+	assert.Equal(t, user.ID, identity.UserID)
+}
+
+func TestOIDCLoginRejectsUnverifiedEmailOfExistingUser(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+	provider := NewMockOIDCServer(t)
+
+	UserFactory("testPassword123", WithEmail("oidc-unverified@example.com"))
+
+	code, state := provider.Authorize(t, startOIDCLogin(t, suite, ""), MockOIDCUser{
+		Subject:       "mock-user-3",
+		Email:         "oidc-unverified@example.com",
+		EmailVerified: false,
+	})
+
+	w := oidcCallback(suite, code, state)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	var count int64
+	initializers.DB.Model(&models.UserIdentity{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+}
+
+func TestOIDCLoginDoesNotLinkUnverifiedExistingUser(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+	provider := NewMockOIDCServer(t)
+
+	// Someone registered the address first without ever verifying it
+	squatter := UserFactory("testPassword123", WithEmail("oidc-squatted@example.com"), WithUnverifiedEmail())
+
+	code, state := provider.Authorize(t, startOIDCLogin(t, suite, ""), MockOIDCUser{
+		Subject:       "mock-user-squatted",
+		Email:         "oidc-squatted@example.com",
+		EmailVerified: true,
+	})
+
+	w := oidcCallback(suite, code, state)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	var count int64
+	initializers.DB.Model(&models.UserIdentity{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+
+	var stored models.User
+	initializers.DB.First(&stored, squatter.ID)
+	assert.Nil(t, stored.EmailVerifiedAt)
+}
+
+func TestOIDCCallbackRejectsReusedState(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+	provider := NewMockOIDCServer(t)
+
+	authorizationURL := startOIDCLogin(t, suite, "")
+	user := MockOIDCUser{Subject: "mock-user-4", Email: "oidc-state@example.com", EmailVerified: true}
+
+	code, state := provider.Authorize(t, authorizationURL, user)
+	w := oidcCallback(suite, code, state)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	code, _ = provider.Authorize(t, authorizationURL, user)
+	w = oidcCallback(suite, code, state)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = oidcCallback(suite, code, "forged-state")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestOIDCUnknownProvider(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+	NewMockOIDCServer(t)
+
+	req, _ := http.NewRequest("GET", "/auth/oidc/unknown/authorize", nil)
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestOIDCLinkAndUnlinkIdentity(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+	provider := NewMockOIDCServer(t)
+
+	user := UserFactory("testPassword123", WithEmail("oidc-link@example.com"))
+	token := getAuthToken(t, suite, "oidc-link@example.com")
+
+	// The provider account uses a different email, so only an explicit link can attach it
+	code, state := provider.Authorize(t, startOIDCLogin(t, suite, token), MockOIDCUser{
+		Subject: "mock-user-5",
+		Email:   "someone-else@example.com",
+	})
+	w := oidcCallback(suite, code, state)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	req, _ := http.NewRequest("GET", "/users/me/identities", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w = httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var identities schemas.ListIdentitiesResponse
+	json.Unmarshal(w.Body.Bytes(), &identities)
+	assert.Len(t, identities.Data, 1)
+	assert.Equal(t, user.ID, identities.Data[0].UserID)
+	assert.Equal(t, "mock", identities.Data[0].Provider)
+
+	req, _ = http.NewRequest("DELETE", fmt.Sprintf("/users/me/identities/%d", identities.Data[0].ID), nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w = httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var count int64
+	initializers.DB.Model(&models.UserIdentity{}).Where("user_id = ?", user.ID).Count(&count)
+	assert.Equal(t, int64(0), count)
+}
+
+func TestOIDCUnlinkLastLoginMethodOfPasswordlessUser(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+	provider := NewMockOIDCServer(t)
+
+	code, state := provider.Authorize(t, startOIDCLogin(t, suite, ""), MockOIDCUser{
+		Subject:       "mock-user-6",
+		Email:         "oidc-only@example.com",
+		EmailVerified: true,
+	})
+	w := oidcCallback(suite, code, state)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response schemas.AuthResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	var identity models.UserIdentity
+	initializers.DB.Where("user_id = ?", response.Data.ID).First(&identity)
+
+	req, _ := http.NewRequest("DELETE", fmt.Sprintf("/users/me/identities/%d", identity.ID), nil)
+	req.Header.Set("Authorization", "Bearer "+response.Token)
+	w = httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
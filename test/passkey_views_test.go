@@ -0,0 +1,209 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-crud/initializers"
+	"go-crud/models"
+	"go-crud/schemas"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Helper function to register a passkey on the authenticator for the user of the token, whose
+// password must be testPassword123
+func registerPasskey(t *testing.T, suite *BaseTestSuite, authenticator *SoftwareAuthenticator, token, name string) *httptest.ResponseRecorder {
+	w := authJSON(suite, "POST", "/users/me/passkeys/register/begin", token, map[string]string{"password": "testPassword123"})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	credential := authenticator.Register(t, w.Body.Bytes())
+	return authJSON(suite, "POST", "/users/me/passkeys/register/finish", token, map[string]interface{}{
+		"name":       name,
+		"credential": credential,
+	})
+}
+
+// Helper function to run a passkey login with the authenticator
+func loginWithPasskey(t *testing.T, suite *BaseTestSuite, authenticator *SoftwareAuthenticator) *httptest.ResponseRecorder {
+	w := postJSON(suite, "/auth/passkeys/login/begin", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	credential := authenticator.Login(t, w.Body.Bytes())
+	return postJSON(suite, "/auth/passkeys/login/finish", map[string]interface{}{"credential": credential})
+}
+
+func listPasskeys(t *testing.T, suite *BaseTestSuite, token string) []models.Passkey {
+	w := authJSON(suite, "GET", "/users/me/passkeys", token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response schemas.ListPasskeysResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return response.Data
+}
+
+func TestPasskeyRegistrationAndLogin(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	UserFactory("testPassword123", WithEmail("passkey@example.com"))
+	token := getAuthToken(t, suite, "passkey@example.com")
+	authenticator := NewSoftwareAuthenticator()
+
+	w := registerPasskey(t, suite, authenticator, token, "Test laptop")
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	passkeys := listPasskeys(t, suite, token)
+	assert.Len(t, passkeys, 1)
+	assert.Equal(t, "Test laptop", passkeys[0].Name)
+	assert.Nil(t, passkeys[0].LastUsedAt)
+
+	w = loginWithPasskey(t, suite, authenticator)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response schemas.AuthResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NotEmpty(t, response.Token)
+	assert.NotEmpty(t, response.RefreshToken)
+	assert.Equal(t, "passkey@example.com", response.Data.Email)
+
+	var stored models.Passkey
+	initializers.DB.First(&stored, passkeys[0].ID)
+	assert.Equal(t, uint32(1), stored.SignCount)
+	assert.NotNil(t, stored.LastUsedAt)
+}
+
+func TestPasskeyRegistrationRequiresReauthentication(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	user := UserFactory("testPassword123", WithEmail("passkey-reauth@example.com"))
+	token := getAuthToken(t, suite, "passkey-reauth@example.com")
+	suite.Mailer().Reset()
+
+	// A stolen access token alone is not enough
+	w := authJSON(suite, "POST", "/users/me/passkeys/register/begin", token, map[string]string{})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = authJSON(suite, "POST", "/users/me/passkeys/register/begin", token, map[string]string{"password": "wrongPassword"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = registerPasskey(t, suite, NewSoftwareAuthenticator(), token, "Test laptop")
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	// The owner hears about the new passkey
+	messages := suite.Mailer().MessagesTo("passkey-reauth@example.com")
+	if assert.Len(t, messages, 1) {
+		assert.Contains(t, messages[0].Body, "Test laptop")
+	}
+	var event models.SecurityEvent
+	assert.NoError(t, initializers.DB.Where("user_id = ? AND type = ?", user.ID, models.PasskeyAddedEvent).First(&event).Error)
+}
+
+func TestPasskeyLoginChallengeIsSingleUse(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	UserFactory("testPassword123", WithEmail("passkey-replay@example.com"))
+	token := getAuthToken(t, suite, "passkey-replay@example.com")
+	authenticator := NewSoftwareAuthenticator()
+	registerPasskey(t, suite, authenticator, token, "Test laptop")
+
+	w := postJSON(suite, "/auth/passkeys/login/begin", nil)
+	credential := authenticator.Login(t, w.Body.Bytes())
+
+	w = postJSON(suite, "/auth/passkeys/login/finish", map[string]interface{}{"credential": credential})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = postJSON(suite, "/auth/passkeys/login/finish", map[string]interface{}{"credential": credential})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestPasskeyLoginRejectsClonedAuthenticator(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	user := UserFactory("testPassword123", WithEmail("passkey-clone@example.com"))
+	token := getAuthToken(t, suite, "passkey-clone@example.com")
+	authenticator := NewSoftwareAuthenticator()
+	registerPasskey(t, suite, authenticator, token, "Test laptop")
+
+	w := loginWithPasskey(t, suite, authenticator)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// The same signature counter again means two copies of the key are in use
+	authenticator.ResetSignCount()
+	w = loginWithPasskey(t, suite, authenticator)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	var events int64
+	initializers.DB.Model(&models.SecurityEvent{}).
+		Where("user_id = ? AND type = ?", user.ID, models.PasskeyClonedEvent).
+		Count(&events)
+	assert.Equal(t, int64(1), events)
+}
+
+func TestPasskeyRegistrationFromWrongOrigin(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	UserFactory("testPassword123", WithEmail("passkey-origin@example.com"))
+	token := getAuthToken(t, suite, "passkey-origin@example.com")
+	authenticator := NewSoftwareAuthenticator()
+	authenticator.Origin = "https://phishing.example.net"
+
+	w := registerPasskey(t, suite, authenticator, token, "Test laptop")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Empty(t, listPasskeys(t, suite, token))
+}
+
+func TestPasskeyLoginWithUnknownCredential(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	UserFactory("testPassword123", WithEmail("passkey-deleted@example.com"))
+	token := getAuthToken(t, suite, "passkey-deleted@example.com")
+	authenticator := NewSoftwareAuthenticator()
+	registerPasskey(t, suite, authenticator, token, "Test laptop")
+
+	passkeys := listPasskeys(t, suite, token)
+	w := authJSON(suite, "DELETE", fmt.Sprintf("/users/me/passkeys/%d", passkeys[0].ID), token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = loginWithPasskey(t, suite, authenticator)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestDeleteOnlyLoginMethodPasskey(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	user := UserFactory("testPassword123", WithEmail("passkey-only@example.com"))
+	token := getAuthToken(t, suite, "passkey-only@example.com")
+	authenticator := NewSoftwareAuthenticator()
+	registerPasskey(t, suite, authenticator, token, "Test laptop")
+
+	// Without a password the passkey is the only way in
+	initializers.DB.Model(&models.User{}).Where("id = ?", user.ID).Update("hashed_password", "")
+
+	passkeys := listPasskeys(t, suite, token)
+	w := authJSON(suite, "DELETE", fmt.Sprintf("/users/me/passkeys/%d", passkeys[0].ID), token, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Len(t, listPasskeys(t, suite, token), 1)
+}
+
+func TestDeletePasskeyOfAnotherUser(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	UserFactory("testPassword123", WithEmail("passkey-owner@example.com"))
+	UserFactory("testPassword123", WithEmail("passkey-other@example.com"))
+	ownerToken := getAuthToken(t, suite, "passkey-owner@example.com")
+	otherToken := getAuthToken(t, suite, "passkey-other@example.com")
+	registerPasskey(t, suite, NewSoftwareAuthenticator(), ownerToken, "Test laptop")
+
+	passkeys := listPasskeys(t, suite, ownerToken)
+	w := authJSON(suite, "DELETE", fmt.Sprintf("/users/me/passkeys/%d", passkeys[0].ID), otherToken, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
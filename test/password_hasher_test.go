@@ -0,0 +1,126 @@
+package test
+
+import (
+	"go-crud/initializers"
+	"go-crud/models"
+	"go-crud/services"
+	"math"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArgon2idIsTheDefaultHash(t *testing.T) {
+	hash, err := services.HashPassword("plum-orbit-canvas-42")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=65536,t=3,p=2$"))
+
+	assert.True(t, services.CheckHashedPassword("plum-orbit-canvas-42", hash))
+	assert.False(t, services.CheckHashedPassword("plum-orbit-canvas-43", hash))
+	assert.False(t, services.PasswordNeedsRehash(hash))
+}
+
+func TestBcryptHashesKeepVerifying(t *testing.T) {
+	t.Setenv("PASSWORD_HASH_ALGORITHM", "bcrypt")
+	hash, err := services.HashPassword("plum-orbit-canvas-42")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$2a$"))
+
+	t.Setenv("PASSWORD_HASH_ALGORITHM", "")
+	assert.True(t, services.CheckHashedPassword("plum-orbit-canvas-42", hash))
+	assert.False(t, services.CheckHashedPassword("wrong", hash))
+	assert.True(t, services.PasswordNeedsRehash(hash))
+}
+
+func TestPasswordNeedsRehashWhenParametersChange(t *testing.T) {
+	t.Setenv("ARGON2_MEMORY_KIB", "19456")
+	t.Setenv("ARGON2_ITERATIONS", "2")
+	hash, err := services.HashPassword("plum-orbit-canvas-42")
+	assert.NoError(t, err)
+	assert.False(t, services.PasswordNeedsRehash(hash))
+
+	// Raising the cost marks old hashes as outdated without breaking them
+	t.Setenv("ARGON2_MEMORY_KIB", "")
+	t.Setenv("ARGON2_ITERATIONS", "")
+	assert.True(t, services.PasswordNeedsRehash(hash))
+	assert.True(t, services.CheckHashedPassword("plum-orbit-canvas-42", hash))
+
+	t.Setenv("PASSWORD_HASH_ALGORITHM", "bcrypt")
+	t.Setenv("BCRYPT_COST", "4")
+	bcryptHash, err := services.HashPassword("plum-orbit-canvas-42")
+	assert.NoError(t, err)
+	assert.False(t, services.PasswordNeedsRehash(bcryptHash))
+
+	t.Setenv("BCRYPT_COST", "5")
+	assert.True(t, services.PasswordNeedsRehash(bcryptHash))
+}
+
+func TestInvalidArgon2ParametersFallBackToDefaults(t *testing.T) {
+	t.Setenv("ARGON2_MEMORY_KIB", "0")
+	t.Setenv("ARGON2_ITERATIONS", "0")
+	t.Setenv("ARGON2_PARALLELISM", "256")
+
+	hash, err := services.HashPassword("plum-orbit-canvas-42")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=65536,t=3,p=2$"))
+	assert.True(t, services.CheckHashedPassword("plum-orbit-canvas-42", hash))
+}
+
+func TestCheckHashedPasswordRejectsUnknownFormats(t *testing.T) {
+	assert.False(t, services.CheckHashedPassword("", ""))
+	assert.False(t, services.CheckHashedPassword("secret", "secret"))
+	assert.False(t, services.CheckHashedPassword("secret", "$argon2id$v=19$m=65536,t=3,p=2$broken"))
+}
+
+func TestLoginUpgradesOutdatedHash(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	t.Setenv("PASSWORD_HASH_ALGORITHM", "bcrypt")
+	user := UserFactory("testPassword123", WithEmail("rehash@example.com"))
+	assert.True(t, strings.HasPrefix(user.HashedPassword, "$2a$"))
+	t.Setenv("PASSWORD_HASH_ALGORITHM", "argon2id")
+
+	// A failed login leaves the hash alone
+	w := postJSON(suite, "/auth/login", map[string]string{"email": "rehash@example.com", "password": "wrongPassword"})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	var stored models.User
+	initializers.DB.First(&stored, user.ID)
+	assert.Equal(t, user.HashedPassword, stored.HashedPassword)
+
+	loginUser(t, suite, "rehash@example.com")
+
+	initializers.DB.First(&stored, user.ID)
+	assert.True(t, strings.HasPrefix(stored.HashedPassword, "$argon2id$"))
+
+	// And the upgraded hash works for the next login
+	loginUser(t, suite, "rehash@example.com")
+}
+
+func TestLoginWithUnknownEmailStillHashesThePassword(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	UserFactory("testPassword123", WithEmail("timing@example.com"))
+
+	// The fastest of a few tries, so a scheduling hiccup doesn't skew the comparison
+	fastest := func(email string) time.Duration {
+		best := time.Duration(math.MaxInt64)
+		for i := 0; i < 3; i++ {
+			start := time.Now()
+			w := postJSON(suite, "/auth/login", map[string]string{"email": email, "password": "wrongPassword"})
+			elapsed := time.Since(start)
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+			best = min(best, elapsed)
+		}
+		return best
+	}
+
+	known := fastest("timing@example.com")
+	unknown := fastest("nobody-here@example.com")
+	assert.Greater(t, unknown, known/4, "unknown email rejected in %v, wrong password in %v", unknown, known)
+}
@@ -0,0 +1,56 @@
+package test
+
+import (
+	"errors"
+	"go-crud/services"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func passwordViolations(t *testing.T, password, email string) []string {
+	err := services.ValidatePassword(password, email)
+	if err == nil {
+		return nil
+	}
+
+	var policyErr *services.PasswordPolicyError
+	if !errors.As(err, &policyErr) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var codes []string
+	for _, violation := range policyErr.Violations {
+		codes = append(codes, violation.Code)
+	}
+	return codes
+}
+
+func TestPasswordPolicyAcceptsStrongPassword(t *testing.T) {
+	assert.Empty(t, passwordViolations(t, "plum-orbit-canvas-42", "connor@example.com"))
+}
+
+func TestPasswordPolicyMinimumLength(t *testing.T) {
+	assert.Equal(t, []string{services.PasswordTooShort}, passwordViolations(t, "x7#kQ2", "connor@example.com"))
+
+	t.Setenv("PASSWORD_MIN_LENGTH", "16")
+	assert.Equal(t, []string{services.PasswordTooShort}, passwordViolations(t, "plum-orbit-42", "connor@example.com"))
+}
+
+func TestPasswordPolicyRejectsCommonPasswords(t *testing.T) {
+	assert.Equal(t, []string{services.PasswordCommon}, passwordViolations(t, "Password123", "connor@example.com"))
+	assert.Equal(t, []string{services.PasswordCommon}, passwordViolations(t, "iloveyou", "connor@example.com"))
+
+	t.Setenv("PASSWORD_REJECT_COMMON", "false")
+	assert.Empty(t, passwordViolations(t, "iloveyou", "connor@example.com"))
+}
+
+func TestPasswordPolicyRejectsEmail(t *testing.T) {
+	assert.Equal(t, []string{services.PasswordContainsEmail}, passwordViolations(t, "Connor@Example.com1", "connor@example.com"))
+	assert.Equal(t, []string{services.PasswordContainsEmail}, passwordViolations(t, "my-name-is-connor", "connor@example.com"))
+}
+
+func TestPasswordPolicyReportsEveryViolation(t *testing.T) {
+	codes := passwordViolations(t, "qwerty", "qwerty@example.com")
+	assert.ElementsMatch(t, []string{services.PasswordTooShort, services.PasswordCommon, services.PasswordContainsEmail}, codes)
+}
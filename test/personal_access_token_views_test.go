@@ -0,0 +1,154 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-crud/initializers"
+	"go-crud/models"
+	"go-crud/schemas"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Helper function to send a request authenticated with the given bearer token
+func bearerRequest(suite *BaseTestSuite, method, path, token string, body interface{}) *httptest.ResponseRecorder {
+	req := newJSONRequest(method, path, body)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	return w
+}
+
+// Helper function to create a personal access token with the given scopes
+func createPersonalAccessToken(t *testing.T, suite *BaseTestSuite, jwt string, scopes ...string) schemas.PersonalAccessTokenCreatedResponse {
+	w := bearerRequest(suite, "POST", "/users/me/tokens", jwt, map[string]interface{}{
+		"name":   "CI",
+		"scopes": scopes,
+	})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var response schemas.PersonalAccessTokenCreatedResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return response
+}
+
+func newPostBody() map[string]string {
+	return map[string]string{
+		"title":            "Published from CI",
+		"content_markdown": "Built from the docs repository",
+		"content_json":     "{\"type\":\"doc\",\"content\":[]}",
+	}
+}
+
+func TestCreatePersonalAccessTokenShowsSecretOnce(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	UserFactory("testPassword123", WithEmail("pat-create@example.com"))
+	jwt := getAuthToken(t, suite, "pat-create@example.com")
+
+	created := createPersonalAccessToken(t, suite, jwt, "posts:write", "read")
+	assert.True(t, strings.HasPrefix(created.Token, "hbp_"))
+	assert.True(t, strings.HasPrefix(created.Token, created.Data.Prefix))
+	assert.ElementsMatch(t, []string{"posts:write", "read"}, created.Data.Scopes)
+
+	w := bearerRequest(suite, "GET", "/users/me/tokens", jwt, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), created.Token)
+
+	var list schemas.ListPersonalAccessTokensResponse
+	json.Unmarshal(w.Body.Bytes(), &list)
+	assert.Len(t, list.Data, 1)
+	assert.Equal(t, "CI", list.Data[0].Name)
+}
+
+func TestCreatePersonalAccessTokenRejectsUnknownScope(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	UserFactory("testPassword123", WithEmail("pat-scope@example.com"))
+	jwt := getAuthToken(t, suite, "pat-scope@example.com")
+
+	w := bearerRequest(suite, "POST", "/users/me/tokens", jwt, map[string]interface{}{
+		"name":   "Too powerful",
+		"scopes": []string{"admin"},
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestPersonalAccessTokenCanPublishPosts(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	UserFactory("testPassword123", WithEmail("pat-publish@example.com"))
+	created := createPersonalAccessToken(t, suite, getAuthToken(t, suite, "pat-publish@example.com"), "posts:write")
+
+	w := bearerRequest(suite, "POST", "/posts", created.Token, newPostBody())
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var pat models.PersonalAccessToken
+	initializers.DB.First(&pat, created.Data.ID)
+	assert.NotNil(t, pat.LastUsedAt)
+}
+
+func TestPersonalAccessTokenScopesAreEnforced(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	UserFactory("testPassword123", WithEmail("pat-read@example.com"))
+	jwt := getAuthToken(t, suite, "pat-read@example.com")
+	created := createPersonalAccessToken(t, suite, jwt, "read")
+
+	w := bearerRequest(suite, "POST", "/posts", created.Token, newPostBody())
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	var response schemas.ErrorResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, "insufficient_scope", response.Code)
+
+	w = bearerRequest(suite, "GET", "/users/me/posts", created.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Routes that do not declare a scope never accept personal access tokens
+	w = bearerRequest(suite, "POST", "/users/me/tokens", created.Token, map[string]interface{}{
+		"name":   "Escalation",
+		"scopes": []string{"posts:write"},
+	})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestRevokedPersonalAccessTokenIsRejected(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	UserFactory("testPassword123", WithEmail("pat-revoke@example.com"))
+	jwt := getAuthToken(t, suite, "pat-revoke@example.com")
+	created := createPersonalAccessToken(t, suite, jwt, "read")
+
+	w := bearerRequest(suite, "DELETE", fmt.Sprintf("/users/me/tokens/%d", created.Data.ID), jwt, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = bearerRequest(suite, "GET", "/users/me/posts", created.Token, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestExpiredPersonalAccessTokenIsRejected(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	UserFactory("testPassword123", WithEmail("pat-expired@example.com"))
+	created := createPersonalAccessToken(t, suite, getAuthToken(t, suite, "pat-expired@example.com"), "read")
+
+	initializers.DB.Model(&models.PersonalAccessToken{}).
+		Where("id = ?", created.Data.ID).
+		Update("expires_at", time.Now().Add(-time.Minute))
+
+	w := bearerRequest(suite, "GET", "/users/me/posts", created.Token, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
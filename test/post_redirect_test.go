@@ -0,0 +1,175 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"go-crud/models"
+	"go-crud/schemas"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func patchPostSlug(t *testing.T, suite *BaseTestSuite, token string, postID uint, slug string) {
+	jsonData, _ := json.Marshal(map[string]string{"slug": slug})
+	req, _ := http.NewRequest("PATCH", "/posts/"+strconv.FormatUint(uint64(postID), 10), bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func createRedirectRequest(suite *BaseTestSuite, token string, fromSlug string, postID uint) *httptest.ResponseRecorder {
+	jsonData, _ := json.Marshal(map[string]interface{}{"from_slug": fromSlug, "post_id": postID})
+	req, _ := http.NewRequest("POST", "/redirects", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	return w
+}
+
+func getBySlug(suite *BaseTestSuite, slug string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", "/posts/by-slug/"+slug, nil)
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	return w
+}
+
+func TestOldSlugRedirectsToCurrentSlug(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	user := UserFactory("testPassword123")
+	post := PostFactory(WithUserID(user.ID), WithSlug("first-slug"))
+	token := getAuthToken(t, suite, user.Email)
+
+	patchPostSlug(t, suite, token, post.ID, "second-slug")
+	patchPostSlug(t, suite, token, post.ID, "third-slug")
+
+	// Every old slug leads straight to the current one
+	for _, slug := range []string{"first-slug", "second-slug"} {
+		w := getBySlug(suite, slug)
+		assert.Equal(t, http.StatusMovedPermanently, w.Code)
+		assert.Equal(t, "/posts/by-slug/third-slug", w.Header().Get("Location"))
+	}
+
+	w := getBySlug(suite, "third-slug")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Going back to an old slug makes it live again
+	patchPostSlug(t, suite, token, post.ID, "first-slug")
+	w = getBySlug(suite, "first-slug")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestRedirectToUnpublishedPostIsNotFollowed(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	user := UserFactory("testPassword123")
+	post := PostFactory(WithUserID(user.ID), WithSlug("before-rename"))
+	token := getAuthToken(t, suite, user.Email)
+	patchPostSlug(t, suite, token, post.ID, "after-rename")
+
+	jsonData, _ := json.Marshal(map[string]string{"status": "draft"})
+	req, _ := http.NewRequest("PATCH", "/posts/"+strconv.FormatUint(uint64(post.ID), 10), bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	suite.router.ServeHTTP(httptest.NewRecorder(), req)
+
+	w := getBySlug(suite, "before-rename")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestManageRedirects(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	editor := UserFactory("testPassword123", WithRole(models.RoleEditor))
+	token := getAuthToken(t, suite, editor.Email)
+	post := PostFactory(WithSlug("the-post"))
+	draft := PostFactory(WithSlug("the-draft"), WithStatus(models.Draft))
+
+	w := createRedirectRequest(suite, token, "Old Campaign Link", post.ID)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var created schemas.PostRedirectResponse
+	json.Unmarshal(w.Body.Bytes(), &created)
+	assert.Equal(t, "old-campaign-link", created.Data.FromSlug)
+	assert.Equal(t, models.RedirectManual, created.Data.Kind)
+
+	w = getBySlug(suite, "old-campaign-link")
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, "/posts/by-slug/the-post", w.Header().Get("Location"))
+
+	assert.Equal(t, http.StatusConflict, createRedirectRequest(suite, token, "old-campaign-link", post.ID).Code)
+	assert.Equal(t, http.StatusConflict, createRedirectRequest(suite, token, "the-draft", post.ID).Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, createRedirectRequest(suite, token, "to-a-draft", draft.ID).Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, createRedirectRequest(suite, token, "to-nowhere", 999999).Code)
+
+	req, _ := http.NewRequest("GET", "/redirects?post_id="+strconv.FormatUint(uint64(post.ID), 10), nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w = httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var list schemas.ListPostRedirectsResponse
+	json.Unmarshal(w.Body.Bytes(), &list)
+	assert.Len(t, list.Data, 1)
+
+	req, _ = http.NewRequest("DELETE", "/redirects/"+strconv.FormatUint(uint64(created.Data.ID), 10), nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w = httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = getBySlug(suite, "old-campaign-link")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestManageRedirectsRequiresEditor(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	author := UserFactory("testPassword123")
+	post := PostFactory(WithUserID(author.ID))
+
+	w := createRedirectRequest(suite, getAuthToken(t, suite, author.Email), "my-redirect", post.ID)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestRedirectedSlugsAreNotReused(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	user := UserFactory("testPassword123")
+	post := PostFactory(WithUserID(user.ID), WithSlug("launch-day"))
+	token := getAuthToken(t, suite, user.Email)
+	patchPostSlug(t, suite, token, post.ID, "launch-day-recap")
+
+	// The old slug still belongs to the renamed post
+	w := createPostRequest(t, suite, token, map[string]string{
+		"title":            "Another post",
+		"slug":             "launch-day",
+		"content_markdown": "Content",
+		"content_json":     "{}",
+	})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = createPostRequest(t, suite, token, map[string]string{
+		"title":            "Launch Day",
+		"content_markdown": "Content",
+		"content_json":     "{}",
+	})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var created schemas.PostResponse
+	json.Unmarshal(w.Body.Bytes(), &created)
+	assert.Equal(t, "launch-day-2", created.Data.Slug)
+
+	w = getBySlug(suite, "launch-day")
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, "/posts/by-slug/launch-day-recap", w.Header().Get("Location"))
+}
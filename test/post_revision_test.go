@@ -0,0 +1,149 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"go-crud/initializers"
+	"go-crud/models"
+	"go-crud/schemas"
+	"go-crud/services"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// createPostWithRevisions creates a post through the API and patches its markdown with each of the
+// given contents, so that it has len(contents)+1 revisions
+func createPostWithRevisions(t *testing.T, suite *BaseTestSuite, token string, contents ...string) uint {
+	w := createPostRequest(t, suite, token, map[string]string{
+		"title":            "Revisioned Post",
+		"content_markdown": "# Title\n\nFirst paragraph\n\nSecond paragraph",
+		"content_json":     "{}",
+	})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var created schemas.PostResponse
+	json.Unmarshal(w.Body.Bytes(), &created)
+
+	for _, content := range contents {
+		jsonData, _ := json.Marshal(map[string]string{"content_markdown": content})
+		req, _ := http.NewRequest("PATCH", "/posts/"+strconv.FormatUint(uint64(created.Data.ID), 10), bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		suite.router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+	return created.Data.ID
+}
+
+func revisionsRequest(suite *BaseTestSuite, method, token string, postID uint, path string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, "/posts/"+strconv.FormatUint(uint64(postID), 10)+"/revisions"+path, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	return w
+}
+
+func TestPostSavesRecordRevisions(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	user := UserFactory("testPassword123")
+	token := getAuthToken(t, suite, user.Email)
+	postID := createPostWithRevisions(t, suite, token, "Second version", "Third version")
+
+	w := revisionsRequest(suite, "GET", token, postID, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var list schemas.ListPostRevisionsResponse
+	json.Unmarshal(w.Body.Bytes(), &list)
+	assert.Len(t, list.Data, 3)
+	assert.Equal(t, 3, list.Data[0].Number)
+	assert.Equal(t, user.ID, *list.Data[0].AuthorID)
+	assert.Empty(t, list.Data[0].ContentMarkdown)
+
+	w = revisionsRequest(suite, "GET", token, postID, "/2")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var revision schemas.PostRevisionResponse
+	json.Unmarshal(w.Body.Bytes(), &revision)
+	assert.Equal(t, "Second version", revision.Data.ContentMarkdown)
+	assert.Equal(t, "{}", revision.Data.ContentJSON)
+
+	w = revisionsRequest(suite, "GET", token, postID, "/9")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestDiffPostRevisions(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	user := UserFactory("testPassword123")
+	token := getAuthToken(t, suite, user.Email)
+	postID := createPostWithRevisions(t, suite, token, "# Title\n\nFirst paragraph, edited\n\nSecond paragraph\n\nThird paragraph")
+
+	w := revisionsRequest(suite, "GET", token, postID, "/diff?from=1&to=2")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var diff schemas.PostRevisionDiffResponse
+	json.Unmarshal(w.Body.Bytes(), &diff)
+	assert.Equal(t, 3, diff.Additions)
+	assert.Equal(t, 1, diff.Deletions)
+	assert.Contains(t, diff.Lines, schemas.PostRevisionDiffLine{Type: services.DiffDelete, OldNumber: 3, Text: "First paragraph"})
+	assert.Contains(t, diff.Lines, schemas.PostRevisionDiffLine{Type: services.DiffInsert, NewNumber: 3, Text: "First paragraph, edited"})
+	assert.Contains(t, diff.Lines, schemas.PostRevisionDiffLine{Type: services.DiffEqual, OldNumber: 5, NewNumber: 5, Text: "Second paragraph"})
+
+	w = revisionsRequest(suite, "GET", token, postID, "/diff?from=1")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestRestorePostRevision(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	user := UserFactory("testPassword123")
+	token := getAuthToken(t, suite, user.Email)
+	postID := createPostWithRevisions(t, suite, token, "Second version")
+
+	w := revisionsRequest(suite, "POST", token, postID, "/1/restore")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var restored schemas.PostResponse
+	json.Unmarshal(w.Body.Bytes(), &restored)
+	assert.Equal(t, "# Title\n\nFirst paragraph\n\nSecond paragraph", restored.Data.ContentMarkdown)
+
+	// Restoring is a new save; the revision it replaced is kept
+	var revisions []models.PostRevision
+	initializers.DB.Where("post_id = ?", postID).Order("number").Find(&revisions)
+	assert.Len(t, revisions, 3)
+	assert.Equal(t, 1, *revisions[2].RestoredFrom)
+	assert.Equal(t, "Second version", revisions[1].ContentMarkdown)
+}
+
+func TestPostRevisionsRequireEditAccess(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	author := UserFactory("testPassword123")
+	postID := createPostWithRevisions(t, suite, getAuthToken(t, suite, author.Email))
+	other := UserFactory("testPassword123")
+	token := getAuthToken(t, suite, other.Email)
+
+	assert.Equal(t, http.StatusForbidden, revisionsRequest(suite, "GET", token, postID, "").Code)
+	assert.Equal(t, http.StatusForbidden, revisionsRequest(suite, "POST", token, postID, "/1/restore").Code)
+}
+
+func TestPrunePostRevisions(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	user := UserFactory("testPassword123")
+	token := getAuthToken(t, suite, user.Email)
+	postID := createPostWithRevisions(t, suite, token, "Second", "Third", "Fourth")
+
+	t.Setenv("POST_REVISION_RETENTION_COUNT", "2")
+	assert.NoError(t, services.NewPostRevisionService().PruneExpired())
+
+	var numbers []int
+	initializers.DB.Model(&models.PostRevision{}).Where("post_id = ?", postID).Order("number").Pluck("number", &numbers)
+	assert.Equal(t, []int{3, 4}, numbers)
+}
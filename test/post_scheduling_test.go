@@ -0,0 +1,104 @@
+package test
+
+import (
+	"encoding/json"
+	"go-crud/initializers"
+	"go-crud/models"
+	"go-crud/schemas"
+	"go-crud/services"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func listPostsWithStatus(t *testing.T, suite *BaseTestSuite, status string) schemas.ListPostsResponse {
+	req, _ := http.NewRequest("GET", "/posts?status="+status, nil)
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response schemas.ListPostsResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return response
+}
+
+func TestCreateScheduledPostValidation(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	user := UserFactory("testPassword123")
+	token := getAuthToken(t, suite, user.Email)
+	body := map[string]string{
+		"title":            "Scheduled",
+		"content_markdown": "Content",
+		"content_json":     "{}",
+		"status":           "scheduled",
+	}
+
+	w := createPostRequest(t, suite, token, body)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	body["publish_at"] = time.Now().Add(2 * time.Hour).Format(time.RFC3339)
+	body["unpublish_at"] = time.Now().Add(time.Hour).Format(time.RFC3339)
+	w = createPostRequest(t, suite, token, body)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	body["unpublish_at"] = time.Now().Add(3 * time.Hour).Format(time.RFC3339)
+	w = createPostRequest(t, suite, token, body)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var response schemas.PostResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, models.Scheduled, response.Data.Status)
+}
+
+func TestScheduledPostIsPublishedWhenDue(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	future := time.Now().Add(time.Hour)
+	post := PostFactory(WithStatus(models.Scheduled))
+	initializers.DB.Model(&post).Update("publish_at", future)
+
+	assert.Equal(t, 1, listPostsWithStatus(t, suite, "scheduled").Total)
+	assert.Equal(t, 0, listPostsWithStatus(t, suite, "published").Total)
+
+	// Due posts are listed as published before the scheduler gets to them
+	initializers.DB.Model(&post).Update("publish_at", time.Now().Add(-time.Minute))
+	assert.Equal(t, 0, listPostsWithStatus(t, suite, "scheduled").Total)
+	assert.Equal(t, 1, listPostsWithStatus(t, suite, "published").Total)
+
+	assert.NoError(t, services.NewPostSchedulerService().RunDue())
+	var stored models.Post
+	initializers.DB.First(&stored, post.ID)
+	assert.Equal(t, models.Published, stored.Status)
+}
+
+func TestEmbargoedPostIsUnpublishedWhenDue(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	post := PostFactory()
+	initializers.DB.Model(&post).Update("unpublish_at", time.Now().Add(-time.Minute))
+
+	assert.Equal(t, 0, listPostsWithStatus(t, suite, "published").Total)
+	assert.Equal(t, 1, listPostsWithStatus(t, suite, "draft").Total)
+
+	assert.NoError(t, services.NewPostSchedulerService().RunDue())
+	var stored models.Post
+	initializers.DB.First(&stored, post.ID)
+	assert.Equal(t, models.Draft, stored.Status)
+	assert.Nil(t, stored.UnpublishAt)
+}
+
+func TestListPostsRejectsUnknownStatus(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	req, _ := http.NewRequest("GET", "/posts?status=archived", nil)
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,115 @@
+package test
+
+import (
+	"encoding/json"
+	"go-crud/initializers"
+	"go-crud/models"
+	"go-crud/schemas"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func searchPosts(t *testing.T, suite *BaseTestSuite, params url.Values) (int, schemas.SearchPostsResponse) {
+	req, _ := http.NewRequest("GET", "/posts/search?"+params.Encode(), nil)
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+
+	var response schemas.SearchPostsResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return w.Code, response
+}
+
+func TestSearchPostsRanksTitleMatchesFirst(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	inContent := PostFactory(WithTitle("Weekly notes"), WithContentMarkdown("Some thoughts about gardening and tomatoes."))
+	inTitle := PostFactory(WithTitle("Gardening for beginners"), WithContentMarkdown("Where to start."))
+	_ = PostFactory(WithTitle("Unrelated"), WithContentMarkdown("Nothing to see here."))
+
+	code, response := searchPosts(t, suite, url.Values{"q": {"gardens"}})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 2, response.Total)
+	if assert.Len(t, response.Data, 2) {
+		assert.Equal(t, inTitle.ID, response.Data[0].Post.ID)
+		assert.Equal(t, inContent.ID, response.Data[1].Post.ID)
+		assert.Greater(t, response.Data[0].Rank, response.Data[1].Rank)
+		assert.Equal(t, "<mark>Gardening</mark> for beginners", response.Data[0].TitleHighlight)
+		assert.Contains(t, response.Data[1].Snippet, "<mark>gardening</mark>")
+	}
+}
+
+func TestSearchPostsEscapesHighlights(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	PostFactory(WithTitle("Tom & Jerry reunion"), WithContentMarkdown("A reunion <script>alert(1)</script> party, 1 < 2"))
+
+	_, response := searchPosts(t, suite, url.Values{"q": {"reunion"}})
+	if assert.Len(t, response.Data, 1) {
+		result := response.Data[0]
+		assert.Equal(t, "Tom &amp; Jerry <mark>reunion</mark>", result.TitleHighlight)
+		assert.Contains(t, result.Snippet, "<mark>reunion</mark>")
+
+		// Apart from the marks, no markup from the post comes through
+		unmarked := strings.NewReplacer("<mark>", "", "</mark>", "").Replace(result.Snippet)
+		assert.NotContains(t, unmarked, "<")
+		assert.NotContains(t, unmarked, ">")
+	}
+}
+
+func TestSearchPostsPhraseAndPrefix(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	phrase := PostFactory(WithTitle("Foxes"), WithContentMarkdown("The quick brown fox jumps."))
+	_ = PostFactory(WithTitle("Colors"), WithContentMarkdown("Brown bears are not quick."))
+
+	_, response := searchPosts(t, suite, url.Values{"q": {`"quick brown"`}})
+	if assert.Len(t, response.Data, 1) {
+		assert.Equal(t, phrase.ID, response.Data[0].Post.ID)
+	}
+
+	_, response = searchPosts(t, suite, url.Values{"q": {"bea*"}})
+	assert.Equal(t, 1, response.Total)
+
+	// Operators typed by the reader are searched for as text
+	code, _ := searchPosts(t, suite, url.Values{"q": {"quick & !brown | (fox"}})
+	assert.Equal(t, http.StatusOK, code)
+}
+
+func TestSearchPostsFilters(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	published := PostFactory(WithTitle("Kubernetes in practice"))
+	draft := PostFactory(WithTitle("Kubernetes draft"), WithStatus(models.Draft))
+	tag := TagFactory()
+	initializers.DB.Create(&models.PostTag{PostID: draft.ID, TagID: tag.ID})
+
+	_, response := searchPosts(t, suite, url.Values{"q": {"kubernetes"}})
+	if assert.Len(t, response.Data, 1) {
+		assert.Equal(t, published.ID, response.Data[0].Post.ID)
+	}
+
+	_, response = searchPosts(t, suite, url.Values{"q": {"kubernetes"}, "status": {"draft"}, "tags": {tag.Name}})
+	if assert.Len(t, response.Data, 1) {
+		assert.Equal(t, draft.ID, response.Data[0].Post.ID)
+	}
+}
+
+func TestSearchPostsRequiresQuery(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	code, _ := searchPosts(t, suite, url.Values{})
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, _ = searchPosts(t, suite, url.Values{"q": {"*"}})
+	assert.Equal(t, http.StatusBadRequest, code)
+}
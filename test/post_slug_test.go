@@ -0,0 +1,211 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"go-crud/initializers"
+	"go-crud/models"
+	"go-crud/schemas"
+	"go-crud/services"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func createPostRequest(t *testing.T, suite *BaseTestSuite, token string, body map[string]string) *httptest.ResponseRecorder {
+	jsonData, _ := json.Marshal(body)
+	req, _ := http.NewRequest("POST", "/posts", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	return w
+}
+
+func TestSlugify(t *testing.T) {
+	assert.Equal(t, "hello-world", services.Slugify("  Hello, World!  "))
+	assert.Equal(t, "xin-chao-the-gioi", services.Slugify("Xin chào, Thế giới!"))
+	assert.Equal(t, "duong-den-ha-noi", services.Slugify("Đường đến Hà Nội"))
+	assert.Equal(t, "obekt-privet", services.Slugify("Объект Привет"))
+	assert.Equal(t, "", services.Slugify("!!!"))
+}
+
+func TestCreatePostGeneratesUniqueSlug(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	user := UserFactory("testPassword123")
+	token := getAuthToken(t, suite, user.Email)
+	body := map[string]string{
+		"title":            "Xin chào, Thế giới!",
+		"content_markdown": "Content",
+		"content_json":     "{}",
+	}
+
+	var first, second schemas.PostResponse
+	w := createPostRequest(t, suite, token, body)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &first)
+
+	w = createPostRequest(t, suite, token, body)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &second)
+
+	assert.Equal(t, "xin-chao-the-gioi", first.Data.Slug)
+	assert.Equal(t, "xin-chao-the-gioi-2", second.Data.Slug)
+}
+
+func TestCreatePostWithCustomSlug(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	user := UserFactory("testPassword123")
+	token := getAuthToken(t, suite, user.Email)
+	body := map[string]string{
+		"title":            "Some Title",
+		"slug":             "My Custom Slug",
+		"content_markdown": "Content",
+		"content_json":     "{}",
+	}
+
+	w := createPostRequest(t, suite, token, body)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var response schemas.PostResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, "my-custom-slug", response.Data.Slug)
+
+	// A slug chosen by the author is never suffixed
+	w = createPostRequest(t, suite, token, body)
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestGetPostBySlug(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	post := PostFactory(WithSlug("a-permalink"))
+
+	req, _ := http.NewRequest("GET", "/posts/by-slug/a-permalink", nil)
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response schemas.PostResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, post.ID, response.Data.ID)
+
+	req, _ = http.NewRequest("GET", "/posts/by-slug/missing", nil)
+	w = httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestPatchPostSlug(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	user := UserFactory("testPassword123")
+	post := PostFactory(WithUserID(user.ID), WithSlug("original-slug"))
+	_ = PostFactory(WithSlug("taken-slug"))
+	token := getAuthToken(t, suite, user.Email)
+
+	patch := func(body map[string]string) *httptest.ResponseRecorder {
+		jsonData, _ := json.Marshal(body)
+		req, _ := http.NewRequest("PATCH", "/posts/"+strconv.FormatUint(uint64(post.ID), 10), bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		suite.router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Renaming the post keeps its permalink
+	w := patch(map[string]string{"title": "A Brand New Title"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response schemas.PostResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, "original-slug", response.Data.Slug)
+
+	w = patch(map[string]string{"slug": "taken-slug"})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = patch(map[string]string{"slug": "new-slug"})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var stored models.Post
+	initializers.DB.First(&stored, post.ID)
+	assert.Equal(t, "new-slug", stored.Slug)
+}
+
+// Helper function to run the slug migration over the existing posts, as if they predated slugs,
+// and return the backfilled slugs by post ID. Everything is rolled back afterwards.
+func backfillSlugs(t *testing.T) map[uint]string {
+	migration, err := os.ReadFile("../migrations/sql/000020_add_post_slugs.up.sql")
+	assert.NoError(t, err)
+
+	slugs := make(map[uint]string)
+	rollback := errors.New("rollback")
+	err = initializers.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("ALTER TABLE posts DROP COLUMN slug").Error; err != nil {
+			return err
+		}
+		if err := tx.Exec(string(migration)).Error; err != nil {
+			return err
+		}
+
+		var posts []models.Post
+		if err := tx.Select("id, slug").Find(&posts).Error; err != nil {
+			return err
+		}
+		for _, post := range posts {
+			slugs[post.ID] = post.Slug
+		}
+		return rollback
+	})
+	assert.ErrorIs(t, err, rollback)
+	return slugs
+}
+
+func TestSlugMigrationSkipsTakenSuffixes(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	user := UserFactory("testPassword123")
+	first := PostFactory(WithTitle("Foo"), WithUserID(user.ID))
+	second := PostFactory(WithTitle("Foo"), WithUserID(user.ID))
+	third := PostFactory(WithTitle("Foo 2"), WithUserID(user.ID))
+
+	slugs := backfillSlugs(t)
+	assert.Equal(t, "foo", slugs[first.ID])
+	assert.Equal(t, "foo-3", slugs[second.ID])
+	assert.Equal(t, "foo-2", slugs[third.ID])
+}
+
+func TestSlugMigrationTransliteratesLikeSlugify(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	user := UserFactory("testPassword123")
+	titles := []string{"Thế giới", "Đà Nẵng", "Straße", "Привет, мир", "Щука и ёжик", "日本語"}
+	posts := make([]models.Post, len(titles))
+	for i, title := range titles {
+		posts[i] = PostFactory(WithTitle(title), WithUserID(user.ID))
+	}
+
+	slugs := backfillSlugs(t)
+	assert.Equal(t, "the-gioi", slugs[posts[0].ID])
+	for i, title := range titles {
+		expected := services.Slugify(title)
+		if expected == "" {
+			expected = "post"
+		}
+		assert.Equal(t, expected, slugs[posts[i].ID], "title %q", title)
+	}
+}
@@ -529,6 +529,93 @@ func TestDeletePostFailWhenWrongUser(t *testing.T) {
 	assert.Contains(t, response.Error, "You can only delete your own posts")
 }
 
+func TestCreatePostFailWhenEmailNotVerified(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	user := UserFactory("testPassword123", WithUnverifiedEmail())
+
+	requestBody := map[string]string{
+		"title":            "Test Post Title",
+		"content_markdown": "This is a test post content",
+		"content_json":     "{\"type\":\"doc\",\"content\":[]}",
+	}
+
+	jsonData, _ := json.Marshal(requestBody)
+	req, _ := http.NewRequest("POST", "/posts", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+getAuthToken(t, suite, user.Email))
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	var response schemas.ErrorResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, "email_not_verified", response.Code)
+}
+
+func TestEditorCanUpdateAndDeleteOtherUsersPost(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	author := UserFactory("testPassword123", WithEmail("rbac-author@example.com"))
+	post := PostFactory(WithUserID(author.ID))
+
+	UserFactory("testPassword123", WithEmail("rbac-editor@example.com"), WithRole(models.RoleEditor))
+	token := getAuthToken(t, suite, "rbac-editor@example.com")
+	path := "/posts/" + strconv.FormatUint(uint64(post.ID), 10)
+
+	jsonData, _ := json.Marshal(map[string]string{"title": "Edited by an editor"})
+	req, _ := http.NewRequest("PATCH", path, bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response schemas.PostResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, "Edited by an editor", response.Data.Title)
+	assert.Equal(t, author.ID, response.Data.UserID)
+
+	req, _ = http.NewRequest("DELETE", path, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	w = httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestCreatePostFailWhenReader(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	user := UserFactory("testPassword123", WithRole(models.RoleReader))
+
+	requestBody := map[string]string{
+		"title":            "Test Post Title",
+		"content_markdown": "This is a test post content",
+		"content_json":     "{\"type\":\"doc\",\"content\":[]}",
+	}
+
+	jsonData, _ := json.Marshal(requestBody)
+	req, _ := http.NewRequest("POST", "/posts", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+getAuthToken(t, suite, user.Email))
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	var response schemas.ErrorResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, "permission_denied", response.Code)
+}
+
 // func TestListPostsWithMineFilter(t *testing.T) {
 // 	suite := NewTestSuite(t)
 // 	defer suite.TearDown()
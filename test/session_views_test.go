@@ -0,0 +1,190 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-crud/schemas"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Helper function to log in from a client with the given user agent
+func loginWithUserAgent(t *testing.T, suite *BaseTestSuite, email, userAgent string) schemas.AuthResponse {
+	req := newJSONRequest("POST", "/auth/login", map[string]string{"email": email, "password": "testPassword123"})
+	req.Header.Set("User-Agent", userAgent)
+	req.RemoteAddr = "203.0.113.7:40000"
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response schemas.AuthResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return response
+}
+
+func listSessions(t *testing.T, suite *BaseTestSuite, token string) []schemas.SessionInfo {
+	req, _ := http.NewRequest("GET", "/users/me/sessions", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response schemas.ListSessionsResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return response.Data
+}
+
+func revokeSession(suite *BaseTestSuite, token string, id uint) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("DELETE", fmt.Sprintf("/users/me/sessions/%d", id), nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	return w
+}
+
+func TestLoginRecordsSession(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	UserFactory("testPassword123", WithEmail("sessions@example.com"))
+	auth := loginWithUserAgent(t, suite, "sessions@example.com", "Firefox on Linux")
+
+	sessions := listSessions(t, suite, auth.Token)
+	assert.Len(t, sessions, 1)
+	assert.Equal(t, "Firefox on Linux", sessions[0].UserAgent)
+	assert.Equal(t, "203.0.113.7", sessions[0].IP)
+	assert.True(t, sessions[0].Current)
+	assert.False(t, sessions[0].LastSeenAt.IsZero())
+}
+
+func TestLoginTruncatesLongUserAgentOnCharacterBoundary(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	// 512 bytes in, the cut would fall in the middle of a three-byte character
+	UserFactory("testPassword123", WithEmail("sessions-unicode@example.com"))
+	auth := loginWithUserAgent(t, suite, "sessions-unicode@example.com", "x"+strings.Repeat("ế", 300))
+
+	sessions := listSessions(t, suite, auth.Token)
+	if assert.Len(t, sessions, 1) {
+		assert.Equal(t, "x"+strings.Repeat("ế", 170), sessions[0].UserAgent)
+		assert.True(t, utf8.ValidString(sessions[0].UserAgent))
+	}
+}
+
+func TestSignupRecordsSession(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	w := postJSON(suite, "/users", map[string]string{
+		"name":     "Session Signup",
+		"email":    "session-signup@example.com",
+		"password": "testPassword123",
+	})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var auth schemas.AuthResponse
+	json.Unmarshal(w.Body.Bytes(), &auth)
+
+	sessions := listSessions(t, suite, auth.Token)
+	assert.Len(t, sessions, 1)
+	assert.True(t, sessions[0].Current)
+}
+
+func TestRevokeSessionSignsDeviceOut(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	UserFactory("testPassword123", WithEmail("revoke-session@example.com"))
+	laptop := loginWithUserAgent(t, suite, "revoke-session@example.com", "Laptop")
+	phone := loginWithUserAgent(t, suite, "revoke-session@example.com", "Phone")
+
+	sessions := listSessions(t, suite, laptop.Token)
+	assert.Len(t, sessions, 2)
+
+	var phoneSession schemas.SessionInfo
+	for _, session := range sessions {
+		if session.UserAgent == "Phone" {
+			phoneSession = session
+		}
+	}
+	assert.False(t, phoneSession.Current)
+
+	w := revokeSession(suite, laptop.Token, phoneSession.ID)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Both credentials of the phone are dead straight away
+	req, _ := http.NewRequest("GET", "/users/me/sessions", nil)
+	req.Header.Set("Authorization", "Bearer "+phone.Token)
+	w = httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = postJSON(suite, "/auth/refresh", map[string]string{"refresh_token": phone.RefreshToken})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// The laptop is unaffected
+	assert.Len(t, listSessions(t, suite, laptop.Token), 1)
+}
+
+func TestRefreshedTokensStayInSession(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	UserFactory("testPassword123", WithEmail("refresh-session@example.com"))
+	auth := loginWithUserAgent(t, suite, "refresh-session@example.com", "Laptop")
+
+	w := postJSON(suite, "/auth/refresh", map[string]string{"refresh_token": auth.RefreshToken})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var refreshed schemas.AuthResponse
+	json.Unmarshal(w.Body.Bytes(), &refreshed)
+
+	sessions := listSessions(t, suite, refreshed.Token)
+	assert.Len(t, sessions, 1)
+	assert.True(t, sessions[0].Current)
+}
+
+func TestRevokeSessionOfAnotherUser(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	UserFactory("testPassword123", WithEmail("session-owner@example.com"))
+	UserFactory("testPassword123", WithEmail("session-other@example.com"))
+	owner := loginWithUserAgent(t, suite, "session-owner@example.com", "Laptop")
+	other := loginWithUserAgent(t, suite, "session-other@example.com", "Laptop")
+
+	sessions := listSessions(t, suite, owner.Token)
+	w := revokeSession(suite, other.Token, sessions[0].ID)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestLoginAlertForNewDevice(t *testing.T) {
+	t.Setenv("LOGIN_ALERTS_ENABLED", "true")
+
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+	suite.Mailer().Reset()
+
+	UserFactory("testPassword123", WithEmail("alerts@example.com"))
+
+	// Neither the first device nor a known one triggers an alert
+	loginWithUserAgent(t, suite, "alerts@example.com", "Laptop")
+	loginWithUserAgent(t, suite, "alerts@example.com", "Laptop")
+	assert.Empty(t, suite.Mailer().MessagesTo("alerts@example.com"))
+
+	loginWithUserAgent(t, suite, "alerts@example.com", "Unknown tablet")
+	messages := suite.Mailer().MessagesTo("alerts@example.com")
+	assert.Len(t, messages, 1)
+	assert.Equal(t, "New sign-in to your account", messages[0].Subject)
+	assert.Contains(t, messages[0].Body, "Unknown tablet")
+	assert.Contains(t, messages[0].Body, "203.0.113.7")
+}
@@ -0,0 +1,183 @@
+package test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"go-crud/services"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// WebAuthn authenticator data flags
+const (
+	flagUserPresent            = 0x01
+	flagUserVerified           = 0x04
+	flagAttestedCredentialData = 0x40
+)
+
+type softwareCredential struct {
+	id         []byte
+	key        *ecdsa.PrivateKey
+	userHandle []byte
+	signCount  uint32
+}
+
+// SoftwareAuthenticator is a WebAuthn authenticator for tests. It creates ES256 passkeys with
+// "none" attestation and signs assertions the way a platform authenticator would, without hardware.
+type SoftwareAuthenticator struct {
+	Origin      string
+	RPID        string
+	credentials []*softwareCredential
+}
+
+// NewSoftwareAuthenticator creates an authenticator used from the configured frontend origin
+func NewSoftwareAuthenticator() *SoftwareAuthenticator {
+	return &SoftwareAuthenticator{
+		Origin: services.WebAuthnOrigins()[0],
+		RPID:   services.WebAuthnRPID(),
+	}
+}
+
+type creationOptions struct {
+	PublicKey struct {
+		Challenge string `json:"challenge"`
+		User      struct {
+			ID string `json:"id"`
+		} `json:"user"`
+	} `json:"publicKey"`
+}
+
+type assertionOptions struct {
+	PublicKey struct {
+		Challenge string `json:"challenge"`
+	} `json:"publicKey"`
+}
+
+// Register answers the options of navigator.credentials.create() with a new credential,
+// serialized like PublicKeyCredential.toJSON()
+func (a *SoftwareAuthenticator) Register(t *testing.T, options []byte) map[string]interface{} {
+	var parsed creationOptions
+	if err := json.Unmarshal(options, &parsed); err != nil {
+		t.Fatal(err)
+	}
+	userHandle, err := base64.RawURLEncoding.DecodeString(parsed.PublicKey.User.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	credential := &softwareCredential{id: make([]byte, 32), key: key, userHandle: userHandle}
+	if _, err := rand.Read(credential.id); err != nil {
+		t.Fatal(err)
+	}
+	a.credentials = append(a.credentials, credential)
+
+	// COSE_Key for an EC2 P-256 public key used with ES256
+	coseKey, err := cbor.Marshal(map[int]interface{}{
+		1:  2,
+		3:  -7,
+		-1: 1,
+		-2: key.PublicKey.X.FillBytes(make([]byte, 32)),
+		-3: key.PublicKey.Y.FillBytes(make([]byte, 32)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authData := a.authenticatorData(flagUserPresent|flagUserVerified|flagAttestedCredentialData, credential.signCount)
+	authData = append(authData, make([]byte, 16)...) // AAGUID
+	authData = binary.BigEndian.AppendUint16(authData, uint16(len(credential.id)))
+	authData = append(authData, credential.id...)
+	authData = append(authData, coseKey...)
+
+	attestationObject, err := cbor.Marshal(map[string]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[string]interface{}{},
+		"authData": authData,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return map[string]interface{}{
+		"id":    base64.RawURLEncoding.EncodeToString(credential.id),
+		"rawId": base64.RawURLEncoding.EncodeToString(credential.id),
+		"type":  "public-key",
+		"response": map[string]interface{}{
+			"clientDataJSON":    base64.RawURLEncoding.EncodeToString(a.clientData(t, "webauthn.create", parsed.PublicKey.Challenge)),
+			"attestationObject": base64.RawURLEncoding.EncodeToString(attestationObject),
+		},
+	}
+}
+
+// Login answers the options of navigator.credentials.get() with an assertion from the most
+// recently registered credential
+func (a *SoftwareAuthenticator) Login(t *testing.T, options []byte) map[string]interface{} {
+	var parsed assertionOptions
+	if err := json.Unmarshal(options, &parsed); err != nil {
+		t.Fatal(err)
+	}
+	if len(a.credentials) == 0 {
+		t.Fatal("the authenticator holds no credentials")
+	}
+	credential := a.credentials[len(a.credentials)-1]
+
+	credential.signCount++
+	authData := a.authenticatorData(flagUserPresent|flagUserVerified, credential.signCount)
+	clientData := a.clientData(t, "webauthn.get", parsed.PublicKey.Challenge)
+
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, credential.key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return map[string]interface{}{
+		"id":    base64.RawURLEncoding.EncodeToString(credential.id),
+		"rawId": base64.RawURLEncoding.EncodeToString(credential.id),
+		"type":  "public-key",
+		"response": map[string]interface{}{
+			"clientDataJSON":    base64.RawURLEncoding.EncodeToString(clientData),
+			"authenticatorData": base64.RawURLEncoding.EncodeToString(authData),
+			"signature":         base64.RawURLEncoding.EncodeToString(signature),
+			"userHandle":        base64.RawURLEncoding.EncodeToString(credential.userHandle),
+		},
+	}
+}
+
+// ResetSignCount makes the authenticator behave like a clone of itself taken before its last use
+func (a *SoftwareAuthenticator) ResetSignCount() {
+	for _, credential := range a.credentials {
+		credential.signCount = 0
+	}
+}
+
+func (a *SoftwareAuthenticator) authenticatorData(flags byte, signCount uint32) []byte {
+	rpIDHash := sha256.Sum256([]byte(a.RPID))
+	data := append([]byte{}, rpIDHash[:]...)
+	data = append(data, flags)
+	return binary.BigEndian.AppendUint32(data, signCount)
+}
+
+func (a *SoftwareAuthenticator) clientData(t *testing.T, ceremony, challenge string) []byte {
+	data, err := json.Marshal(map[string]interface{}{
+		"type":        ceremony,
+		"challenge":   challenge,
+		"origin":      a.Origin,
+		"crossOrigin": false,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
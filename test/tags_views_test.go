@@ -0,0 +1,79 @@
+package test
+
+import (
+	"encoding/json"
+	"go-crud/initializers"
+	"go-crud/models"
+	"go-crud/schemas"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Helper function to call an authenticated tag endpoint
+func tagRequest(suite *BaseTestSuite, method, path, token string, body interface{}) *httptest.ResponseRecorder {
+	req := newJSONRequest(method, path, body)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	return w
+}
+
+func TestAuthorCannotUpdateOrDeleteTag(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	tag := TagFactory()
+	defer initializers.DB.Delete(&tag)
+
+	UserFactory("testPassword123", WithEmail("tag-author@example.com"))
+	token := getAuthToken(t, suite, "tag-author@example.com")
+	path := "/tags/" + strconv.FormatUint(uint64(tag.ID), 10)
+
+	w := tagRequest(suite, "PUT", path, token, map[string]string{"description": "Vandalized"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	var response schemas.ErrorResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, "permission_denied", response.Code)
+
+	w = tagRequest(suite, "DELETE", path, token, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	var count int64
+	initializers.DB.Model(&models.Tag{}).Where("id = ?", tag.ID).Count(&count)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestEditorCanUpdateAndDeleteTag(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	tag := TagFactory()
+	defer initializers.DB.Delete(&tag)
+
+	UserFactory("testPassword123", WithEmail("tag-editor@example.com"), WithRole(models.RoleEditor))
+	token := getAuthToken(t, suite, "tag-editor@example.com")
+	path := "/tags/" + strconv.FormatUint(uint64(tag.ID), 10)
+
+	w := tagRequest(suite, "PUT", path, token, map[string]string{"description": "Curated description"})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = tagRequest(suite, "DELETE", path, token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestReaderCannotCreateTag(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	UserFactory("testPassword123", WithEmail("tag-reader@example.com"), WithRole(models.RoleReader))
+	token := getAuthToken(t, suite, "tag-reader@example.com")
+
+	w := tagRequest(suite, "POST", "/tags", token, map[string]string{"name": "reader-tag"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
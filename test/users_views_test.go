@@ -3,7 +3,10 @@ package test
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
+	"go-crud/models"
 	"go-crud/schemas"
+	"go-crud/services"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
@@ -19,7 +22,7 @@ func TestCreateUserSuccess(t *testing.T) {
 	requestBody := map[string]string{
 		"name":     "Connor Tran",
 		"email":    "connortran@gmail.com",
-		"password": "password123",
+		"password": "plum-orbit-canvas-42",
 	}
 
 	jsonData, _ := json.Marshal(requestBody)
@@ -197,7 +200,7 @@ func TestPartialUpdateUserValidationError(t *testing.T) {
 	)
 
 	requestBody := map[string]string{
-		"email": "invalid-email",
+		"name": "ab",
 	}
 
 	jsonData, _ := json.Marshal(requestBody)
@@ -232,12 +235,13 @@ func TestDeleteUserSuccess(t *testing.T) {
 	w := httptest.NewRecorder()
 	suite.router.ServeHTTP(w, req)
 
-	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, http.StatusAccepted, w.Code)
 
-	var response schemas.MessageResponse
+	var response schemas.AccountDeletionResponse
 	err := json.Unmarshal(w.Body.Bytes(), &response)
 	assert.NoError(t, err)
-	assert.Contains(t, response.Message, "User deleted successfully")
+	assert.Contains(t, response.Message, "Account scheduled for deletion")
+	assert.Equal(t, user.ID, response.Data.UserID)
 }
 
 func TestDeleteUserNotFound(t *testing.T) {
@@ -262,3 +266,234 @@ func TestDeleteUserNotFound(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Contains(t, response.Error, "User not found")
 }
+
+func TestCreateUserSendsVerificationEmail(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	requestBody := map[string]string{
+		"name":     "Connor Tran",
+		"email":    "verify-me@example.com",
+		"password": "plum-orbit-canvas-42",
+	}
+
+	jsonData, _ := json.Marshal(requestBody)
+
+	req, _ := http.NewRequest("POST", "/users", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var response schemas.AuthResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Nil(t, response.Data.EmailVerifiedAt)
+
+	messages := suite.Mailer().MessagesTo("verify-me@example.com")
+	assert.Len(t, messages, 1)
+	assert.Contains(t, messages[0].Body, "/verify-email?token=")
+}
+
+func TestAdminCanChangeUserRole(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	UserFactory("testPassword123", WithEmail("role-admin@example.com"), WithRole(models.RoleAdmin))
+	target := UserFactory("testPassword123", WithEmail("role-target@example.com"))
+	targetToken := getAuthToken(t, suite, "role-target@example.com")
+
+	// Tokens carry the role they were issued with, so the old one must be revoked
+
+	req := newJSONRequest("PUT", fmt.Sprintf("/users/%d/role", target.ID), map[string]string{"role": "editor"})
+	req.Header.Set("Authorization", "Bearer "+getAuthToken(t, suite, "role-admin@example.com"))
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response schemas.UserResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, models.RoleEditor, response.Data.Role)
+
+	claims, err := services.ParseToken(getAuthToken(t, suite, "role-target@example.com"))
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoleEditor, claims.Role)
+
+	req, _ = http.NewRequest("POST", "/auth/logout-all", nil)
+	req.Header.Set("Authorization", "Bearer "+targetToken)
+	w = httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestChangeUserRoleRequiresAdmin(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	UserFactory("testPassword123", WithEmail("role-editor@example.com"), WithRole(models.RoleEditor))
+	target := UserFactory("testPassword123", WithEmail("role-victim@example.com"))
+
+	req := newJSONRequest("PUT", fmt.Sprintf("/users/%d/role", target.ID), map[string]string{"role": "admin"})
+	req.Header.Set("Authorization", "Bearer "+getAuthToken(t, suite, "role-editor@example.com"))
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+// Helper function to send an authenticated JSON request
+func authJSON(suite *BaseTestSuite, method, path, token string, body interface{}) *httptest.ResponseRecorder {
+	req := newJSONRequest(method, path, body)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	return w
+}
+
+func TestCreateUserRejectsWeakPassword(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	w := postJSON(suite, "/users", map[string]string{
+		"name":     "Weak Password",
+		"email":    "weak@example.com",
+		"password": "qwerty",
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var response schemas.PasswordPolicyErrorResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, "weak_password", response.Code)
+
+	var codes []string
+	for _, violation := range response.Violations {
+		codes = append(codes, violation.Code)
+	}
+	assert.ElementsMatch(t, []string{services.PasswordTooShort, services.PasswordCommon}, codes)
+}
+
+func TestPartialUpdateUserRejectsCredentials(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	user := UserFactory("testPassword123", WithEmail("patch-credentials@example.com"))
+	token := getAuthToken(t, suite, "patch-credentials@example.com")
+
+	w := authJSON(suite, "PATCH", fmt.Sprintf("/users/%d", user.ID), token, map[string]string{"password": "hijacked-password-1"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = authJSON(suite, "PATCH", fmt.Sprintf("/users/%d", user.ID), token, map[string]string{"email": "attacker@example.com"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Still the same credentials
+	getAuthToken(t, suite, "patch-credentials@example.com")
+}
+
+func TestChangePasswordRequiresCurrentPassword(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	UserFactory("testPassword123", WithEmail("change-password@example.com"))
+	login := loginUser(t, suite, "change-password@example.com")
+
+	w := authJSON(suite, "POST", "/users/me/password", login.Token, map[string]string{
+		"current_password": "notMyPassword",
+		"new_password":     "plum-orbit-canvas-42",
+	})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	var errorResponse schemas.ErrorResponse
+	json.Unmarshal(w.Body.Bytes(), &errorResponse)
+	assert.Equal(t, "incorrect_password", errorResponse.Code)
+
+	w = authJSON(suite, "POST", "/users/me/password", login.Token, map[string]string{
+		"current_password": "testPassword123",
+		"new_password":     "plum-orbit-canvas-42",
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// The client gets a fresh session, every other one is signed out
+	var response schemas.AuthResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NotEmpty(t, response.Token)
+
+	w = refreshTokens(suite, login.RefreshToken)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = refreshTokens(suite, response.RefreshToken)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = postJSON(suite, "/auth/login", map[string]string{"email": "change-password@example.com", "password": "plum-orbit-canvas-42"})
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestChangePasswordEnforcesPolicy(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	UserFactory("testPassword123", WithEmail("policy@example.com"))
+	token := getAuthToken(t, suite, "policy@example.com")
+
+	w := authJSON(suite, "POST", "/users/me/password", token, map[string]string{
+		"current_password": "testPassword123",
+		"new_password":     "policy@example.com!",
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var response schemas.PasswordPolicyErrorResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Len(t, response.Violations, 1)
+	assert.Equal(t, services.PasswordContainsEmail, response.Violations[0].Code)
+}
+
+func TestChangeEmailRequiresReverification(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+	suite.Mailer().Reset()
+
+	UserFactory("testPassword123", WithEmail("old-address@example.com"))
+	token := getAuthToken(t, suite, "old-address@example.com")
+
+	w := authJSON(suite, "POST", "/users/me/email", token, map[string]string{
+		"password":  "wrongPassword",
+		"new_email": "new-address@example.com",
+	})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = authJSON(suite, "POST", "/users/me/email", token, map[string]string{
+		"password":  "testPassword123",
+		"new_email": "new-address@example.com",
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response schemas.UserResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, "new-address@example.com", response.Data.Email)
+	assert.Nil(t, response.Data.EmailVerifiedAt)
+
+	assert.Len(t, suite.Mailer().MessagesTo("new-address@example.com"), 1)
+	notices := suite.Mailer().MessagesTo("old-address@example.com")
+	assert.Len(t, notices, 1)
+	assert.Equal(t, "Your email address was changed", notices[0].Subject)
+
+	// Unverified again, so posting is blocked until the new address is confirmed
+	w = authJSON(suite, "POST", "/posts", token, map[string]string{"title": "Hello", "content": "World"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestChangeEmailToAddressInUse(t *testing.T) {
+	suite := NewTestSuite(t)
+	defer suite.TearDown()
+
+	UserFactory("testPassword123", WithEmail("mover@example.com"))
+	UserFactory("testPassword123", WithEmail("occupied@example.com"))
+	token := getAuthToken(t, suite, "mover@example.com")
+
+	w := authJSON(suite, "POST", "/users/me/email", token, map[string]string{
+		"password":  "testPassword123",
+		"new_email": "occupied@example.com",
+	})
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
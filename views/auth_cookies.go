@@ -0,0 +1,98 @@
+package views
+
+import (
+	"crypto/subtle"
+	"go-crud/services"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Cookie-based auth lets browser clients keep tokens out of JavaScript. A client opts in by sending
+// "X-Auth-Mode: cookie" when it logs in; the tokens are then set as HttpOnly cookies instead of being
+// returned in the body. Since browsers attach cookies to cross-site requests on their own, every unsafe
+// request authenticated by cookie must echo the CSRF cookie in the X-CSRF-Token header (double submit).
+const (
+	AccessTokenCookie  = "access_token"
+	RefreshTokenCookie = "refresh_token"
+	CSRFTokenCookie    = "csrf_token"
+
+	AuthModeHeader  = "X-Auth-Mode"
+	CSRFTokenHeader = "X-CSRF-Token"
+
+	// refreshTokenCookiePath limits the refresh token cookie to the endpoints that consume it
+	refreshTokenCookiePath = "/auth"
+
+	cookieAuthContextKey = "cookie_auth"
+)
+
+// cookieAuthRequested reports whether the tokens of this request should be written as cookies:
+// the client asked for it, or it is already authenticated with the access token cookie
+func cookieAuthRequested(c *gin.Context) bool {
+	if !services.AuthCookiesEnabled() {
+		return false
+	}
+	return strings.EqualFold(c.GetHeader(AuthModeHeader), "cookie") || c.GetBool(cookieAuthContextKey)
+}
+
+// requiresCSRFCheck reports whether the method can change state and so needs a CSRF token
+func requiresCSRFCheck(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return false
+	default:
+		return true
+	}
+}
+
+// validCSRFToken reports whether the X-CSRF-Token header matches the CSRF cookie
+func validCSRFToken(c *gin.Context) bool {
+	cookie, err := c.Cookie(CSRFTokenCookie)
+	if err != nil || cookie == "" {
+		return false
+	}
+	header := c.GetHeader(CSRFTokenHeader)
+	return subtle.ConstantTimeCompare([]byte(cookie), []byte(header)) == 1
+}
+
+// setAuthCookies writes the token pair and a fresh CSRF token as cookies
+func setAuthCookies(c *gin.Context, tokens *services.TokenPair) error {
+	csrfToken, err := services.NewCSRFToken()
+	if err != nil {
+		return err
+	}
+
+	refreshTTL := services.RefreshTokenTTL()
+	setCookie(c, AccessTokenCookie, tokens.AccessToken, "/", services.AccessTokenTTL(), true)
+	setCookie(c, RefreshTokenCookie, tokens.RefreshToken, refreshTokenCookiePath, refreshTTL, true)
+	// The frontend has to read the CSRF token to send it back, so this one is not HttpOnly
+	setCookie(c, CSRFTokenCookie, csrfToken, "/", refreshTTL, false)
+	return nil
+}
+
+// clearAuthCookies expires every auth cookie
+func clearAuthCookies(c *gin.Context) {
+	setCookie(c, AccessTokenCookie, "", "/", -1, true)
+	setCookie(c, RefreshTokenCookie, "", refreshTokenCookiePath, -1, true)
+	setCookie(c, CSRFTokenCookie, "", "/", -1, false)
+}
+
+// setCookie sets a cookie with the configured security attributes. A negative maxAge deletes it.
+func setCookie(c *gin.Context, name, value, path string, maxAge time.Duration, httpOnly bool) {
+	cookie := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   services.AuthCookieDomain(),
+		MaxAge:   int(maxAge.Seconds()),
+		Secure:   services.AuthCookieSecure(),
+		HttpOnly: httpOnly,
+		SameSite: services.AuthCookieSameSite(),
+	}
+	if maxAge < 0 {
+		cookie.MaxAge = -1
+	}
+	http.SetCookie(c.Writer, cookie)
+}
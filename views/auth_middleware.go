@@ -1,7 +1,12 @@
 package views
 
 import (
+	"errors"
+	"fmt"
+	"go-crud/models"
+	"go-crud/schemas"
 	"go-crud/services"
+	"log"
 	"net/http"
 	"strings"
 
@@ -9,42 +14,292 @@ import (
 )
 
 const (
-	UserContextKey = "user_id"
+	UserContextKey                = "user_id"
+	ClaimsContextKey              = "token_claims"
+	PersonalAccessTokenContextKey = "personal_access_token"
+
+	EmailNotVerifiedCode      = "email_not_verified"
+	PermissionDeniedCode      = "permission_denied"
+	InsufficientScopeCode     = "insufficient_scope"
+	TooManyAttemptsCode       = "too_many_attempts"
+	WeakPasswordCode          = "weak_password"
+	IncorrectPasswordCode     = "incorrect_password"
+	CSRFTokenInvalidCode      = "csrf_token_invalid"
+	RegistrationClosedCode    = "registration_closed"
+	InviteRequiredCode        = "invite_required"
+	EmailDomainNotAllowedCode = "email_domain_not_allowed"
+	InvalidInviteCode         = "invalid_invite"
+	ImpersonationDeniedCode   = "impersonation_denied"
 )
 
-func AuthMiddleware() gin.HandlerFunc {
+// AuthMiddleware authenticates the request with either an access token (JWT) or a personal access token.
+// Without an Authorization header it falls back to the access token cookie when cookie auth is enabled;
+// unsafe requests authenticated that way must carry a matching CSRF token.
+// Personal access tokens are only accepted on routes that pass the scopes they require, and must hold
+// one of them. Access tokens are not scoped.
+func AuthMiddleware(scopes ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		var tokenString string
 		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
+		switch {
+		case authHeader != "":
+			// Check for Bearer token format
+			bearerToken := strings.Split(authHeader, " ")
+			if len(bearerToken) != 2 || bearerToken[0] != "Bearer" {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization format. Use 'Bearer <token>'"})
+				c.Abort()
+				return
+			}
+			tokenString = bearerToken[1]
+
+		case services.AuthCookiesEnabled():
+			cookie, err := c.Cookie(AccessTokenCookie)
+			if err != nil || cookie == "" {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
+				c.Abort()
+				return
+			}
+
+			// Browsers send cookies along with cross-site requests, the header proves the request
+			// comes from a page that can read our cookies
+			if requiresCSRFCheck(c.Request.Method) && !validCSRFToken(c) {
+				c.JSON(http.StatusForbidden, schemas.ErrorResponse{
+					Error: "Missing or invalid CSRF token",
+					Code:  CSRFTokenInvalidCode,
+				})
+				c.Abort()
+				return
+			}
+
+			// Only access tokens are ever set as cookies
+			if services.IsPersonalAccessToken(cookie) {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+				c.Abort()
+				return
+			}
+			tokenString = cookie
+			c.Set(cookieAuthContextKey, true)
+
+		default:
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
 			c.Abort()
 			return
 		}
 
-		// Check for Bearer token format
-		bearerToken := strings.Split(authHeader, " ")
-		if len(bearerToken) != 2 || bearerToken[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization format. Use 'Bearer <token>'"})
-			c.Abort()
+		if services.IsPersonalAccessToken(tokenString) {
+			authenticatePersonalAccessToken(c, tokenString, scopes)
 			return
 		}
 
-		tokenString := bearerToken[1]
-
 		// Validate token
-		userID, err := services.ValidateToken(tokenString)
+		claims, err := services.ParseToken(tokenString)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
 			c.Abort()
 			return
 		}
 
-		// Store user ID in context for use in handlers
-		c.Set(UserContextKey, userID)
+		// Reject tokens that were logged out before they expired
+		revoked, err := services.NewTokenRevocationService().IsRevoked(claims)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify token"})
+			c.Abort()
+			return
+		}
+		if revoked {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
+			c.Abort()
+			return
+		}
+
+		// Reject tokens whose session was signed out, and keep its last-seen time current
+		if claims.SessionID != 0 {
+			if err := services.NewSessionService().Touch(claims.SessionID); err != nil {
+				if errors.Is(err, services.ErrSessionRevoked) {
+					c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
+				} else {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify token"})
+				}
+				c.Abort()
+				return
+			}
+		}
+
+		// Store user ID and claims in context for use in handlers
+		c.Set(UserContextKey, claims.UserID)
+		c.Set(ClaimsContextKey, claims)
+		if claims.ImpersonatorID != 0 {
+			auditImpersonatedRequest(c, claims)
+			return
+		}
+		c.Next()
+	}
+}
+
+// auditImpersonatedRequest handles a request made with an impersonation token, recording it in the
+// impersonation's audit trail. Requests that cannot be recorded are refused.
+func auditImpersonatedRequest(c *gin.Context, claims *services.AccessClaims) {
+	service := services.NewImpersonationService()
+	request, err := service.RecordRequest(claims, models.ImpersonationRequest{
+		Method:    c.Request.Method,
+		Path:      c.Request.URL.RequestURI(),
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to audit impersonated request"})
+		c.Abort()
+		return
+	}
+
+	c.Next()
+
+	if err := service.CompleteRequest(request, c.Writer.Status()); err != nil {
+		log.Printf("[IMPERSONATION] Failed to record status of request %d: %v", request.ID, err)
+	}
+}
+
+func authenticatePersonalAccessToken(c *gin.Context, token string, scopes []string) {
+	pat, err := services.NewPersonalAccessTokenService().Authenticate(token)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidPersonalAccessToken) {
+			c.JSON(http.StatusUnauthorized, schemas.ErrorResponse{Error: "Invalid or expired token"})
+		} else {
+			c.JSON(http.StatusInternalServerError, schemas.ErrorResponse{Error: "Failed to verify token"})
+		}
+		c.Abort()
+		return
+	}
+
+	allowed := false
+	for _, scope := range scopes {
+		if pat.HasScope(scope) {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		message := "Personal access tokens cannot be used for this endpoint"
+		if len(scopes) > 0 {
+			message = fmt.Sprintf("Token requires one of the scopes: %s", strings.Join(scopes, ", "))
+		}
+		c.JSON(http.StatusForbidden, schemas.ErrorResponse{Error: message, Code: InsufficientScopeCode})
+		c.Abort()
+		return
+	}
+
+	user, err := services.NewUserService().GetByID(pat.UserID)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, schemas.ErrorResponse{Error: "Invalid or expired token"})
+		c.Abort()
+		return
+	}
+
+	// The token acts with the owner's current role, narrowed down by its scopes
+	claims := &services.AccessClaims{
+		UserID: user.ID,
+		Email:  user.Email,
+		Name:   user.Name,
+		Role:   user.Role,
+	}
+
+	c.Set(UserContextKey, user.ID)
+	c.Set(ClaimsContextKey, claims)
+	c.Set(PersonalAccessTokenContextKey, pat)
+	c.Next()
+}
+
+// RequireVerifiedEmail blocks users who have not confirmed their email address yet.
+// It must run after AuthMiddleware.
+func RequireVerifiedEmail() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := GetUserIDFromContext(c)
+		if !exists {
+			c.JSON(http.StatusUnauthorized, schemas.ErrorResponse{Error: "User not authenticated"})
+			c.Abort()
+			return
+		}
+
+		user, err := services.NewUserService().GetByID(userID)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, schemas.ErrorResponse{Error: "User not authenticated"})
+			c.Abort()
+			return
+		}
+
+		if user.EmailVerifiedAt == nil {
+			c.JSON(http.StatusForbidden, schemas.ErrorResponse{
+				Error: "Please verify your email address before continuing",
+				Code:  EmailNotVerifiedCode,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// ForbidImpersonation blocks requests made with an impersonation token. It guards credential
+// changes and account deletion, which only the account owner may do. It must run after AuthMiddleware.
+func ForbidImpersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if claims, exists := GetClaimsFromContext(c); exists && claims.ImpersonatorID != 0 {
+			c.JSON(http.StatusForbidden, schemas.ErrorResponse{
+				Error: "This action is not allowed while impersonating a user",
+				Code:  ImpersonationDeniedCode,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequirePermission blocks users whose role does not grant the permission.
+// It must run after AuthMiddleware.
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		actor, exists := GetActorFromContext(c)
+		if !exists {
+			c.JSON(http.StatusUnauthorized, schemas.ErrorResponse{Error: "User not authenticated"})
+			c.Abort()
+			return
+		}
+
+		if !actor.Can(permission) {
+			c.JSON(http.StatusForbidden, schemas.ErrorResponse{
+				Error: "You do not have permission to perform this action",
+				Code:  PermissionDeniedCode,
+			})
+			c.Abort()
+			return
+		}
+
 		c.Next()
 	}
 }
 
+// GetActorFromContext returns the authenticated user and the role their token was issued with
+func GetActorFromContext(c *gin.Context) (services.Actor, bool) {
+	claims, exists := GetClaimsFromContext(c)
+	if !exists {
+		return services.Actor{}, false
+	}
+
+	actor := services.Actor{UserID: claims.UserID, Role: claims.Role}
+	if actor.Role == "" {
+		// Tokens issued before roles existed do not carry one
+		user, err := services.NewUserService().GetByID(claims.UserID)
+		if err != nil {
+			return services.Actor{}, false
+		}
+		actor.Role = user.Role
+	}
+	return actor, true
+}
+
 // GetUserIDFromContext retrieves the authenticated user ID from the Gin context
 func GetUserIDFromContext(c *gin.Context) (uint, bool) {
 	userID, exists := c.Get(UserContextKey)
@@ -55,3 +310,14 @@ func GetUserIDFromContext(c *gin.Context) (uint, bool) {
 	id, ok := userID.(uint)
 	return id, ok
 }
+
+// GetClaimsFromContext retrieves the claims of the access token used for the request
+func GetClaimsFromContext(c *gin.Context) (*services.AccessClaims, bool) {
+	claims, exists := c.Get(ClaimsContextKey)
+	if !exists {
+		return nil, false
+	}
+
+	accessClaims, ok := claims.(*services.AccessClaims)
+	return accessClaims, ok
+}
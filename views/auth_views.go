@@ -1,21 +1,29 @@
 package views
 
 import (
+	"errors"
 	"fmt"
+	"go-crud/models"
 	"go-crud/schemas"
 	"go-crud/services"
+	"io"
+	"log"
+	"math"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
 
 type AuthViews struct {
-	userService *services.UserService
+	userService     *services.UserService
+	throttleService *services.LoginThrottleService
 }
 
 func NewAuthViews() *AuthViews {
 	return &AuthViews{
-		userService: services.NewUserService(),
+		userService:     services.NewUserService(),
+		throttleService: services.NewLoginThrottleService(),
 	}
 }
 
@@ -23,8 +31,12 @@ func NewAuthViews() *AuthViews {
 // @Tags auth
 // @Accept json
 // @Produce json
+// @Description Returns tokens, or an mfa_token to exchange at /auth/mfa/verify when two-factor authentication is enabled.
+// @Description Repeated failures lock the account or client IP for a while: the response is then 429 with a Retry-After header.
 // @Param loginInput body schemas.LoginInput true "Login credentials"
 // @Success 200 {object} schemas.AuthResponse
+// @Success 200 {object} schemas.MFAChallengeResponse
+// @Failure 429 {object} schemas.ErrorResponse
 // @Router /auth/login [post]
 func (v *AuthViews) Login(c *gin.Context) {
 	var input schemas.LoginInput
@@ -35,25 +47,453 @@ func (v *AuthViews) Login(c *gin.Context) {
 		return
 	}
 
+	attempt := loginAttempt(c, input.Email, nil)
+	if err := v.throttleService.Check(attempt); err != nil {
+		respondToThrottle(c, err)
+		return
+	}
+
 	// Find user by email - need to modify UserService to add FindByEmail method
 	user, err := v.userService.FindByEmail(input.Email)
-	if err != nil {
+	if err == nil {
+		attempt.UserID = &user.ID
+	} else {
+		// Hash the password anyway so that unknown emails take as long to reject as wrong passwords
+		services.CheckDummyPassword(input.Password)
+	}
+
+	// Unknown emails count as failures too, so the response doesn't reveal which accounts exist
+	if err != nil || !v.userService.CheckPassword(user, input.Password) {
+		if err := v.throttleService.RecordFailure(attempt); err != nil {
+			respondToThrottle(c, err)
+			return
+		}
 		c.JSON(http.StatusUnauthorized, schemas.ErrorResponse{
 			Error: "Invalid email or password",
 		})
 		return
 	}
 
-	// Check password
-	if !services.CheckHashedPassword(input.Password, user.HashedPassword) {
+	// With two-factor authentication the login is only complete once the code is verified, which
+	// clears the counter then. Clearing it here would also forget the wrong codes tried so far.
+	if !user.HasTOTPEnabled() {
+		if err := v.throttleService.RecordSuccess(attempt); err != nil {
+			c.JSON(http.StatusInternalServerError, schemas.ErrorResponse{
+				Error: fmt.Sprintf("Failed to log in: %v", err),
+			})
+			return
+		}
+	}
+
+	completeLogin(c, *user, "Login successful")
+}
+
+// @Summary Refresh access token
+// @Description Exchanges a refresh token for a new access token. The refresh token is rotated;
+// @Description presenting an already-used refresh token revokes every token from the same login.
+// @Description Cookie-auth clients may omit the body: the refresh token cookie is used instead, along with the X-CSRF-Token header.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param refreshInput body schemas.RefreshTokenInput true "Refresh token"
+// @Success 200 {object} schemas.AuthResponse
+// @Router /auth/refresh [post]
+func (v *AuthViews) Refresh(c *gin.Context) {
+	var input schemas.RefreshTokenInput
+	if err := c.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, schemas.ErrorResponse{
+			Error: fmt.Sprintf("Invalid request data: %v", err),
+		})
+		return
+	}
+
+	if input.RefreshToken == "" {
+		refreshToken, ok := refreshTokenFromCookie(c)
+		if !ok {
+			return
+		}
+		input.RefreshToken = refreshToken
+	}
+
+	user, tokens, err := services.RefreshTokenPair(input.RefreshToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrRefreshTokenReused):
+			c.JSON(http.StatusUnauthorized, schemas.ErrorResponse{
+				Error: "Refresh token has already been used; all sessions from this login have been revoked",
+			})
+		case errors.Is(err, services.ErrInvalidRefreshToken):
+			c.JSON(http.StatusUnauthorized, schemas.ErrorResponse{
+				Error: "Invalid or expired refresh token",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, schemas.ErrorResponse{
+				Error: fmt.Sprintf("Failed to refresh token: %v", err),
+			})
+		}
+		return
+	}
+
+	writeAuthResponse(c, http.StatusOK, *user, tokens, "Token refreshed successfully")
+}
+
+// @Summary Logout
+// @Description Revokes the access token used for this request. If a refresh token is supplied,
+// @Description every refresh token from the same login is revoked as well. Auth cookies are cleared.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param logoutInput body schemas.LogoutInput false "Refresh token to revoke"
+// @Success 200 {object} schemas.MessageResponse
+// @Router /auth/logout [post]
+func (v *AuthViews) Logout(c *gin.Context) {
+	var input schemas.LogoutInput
+	if err := c.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, schemas.ErrorResponse{
+			Error: fmt.Sprintf("Invalid request data: %v", err),
+		})
+		return
+	}
+
+	claims, exists := GetClaimsFromContext(c)
+	if !exists {
 		c.JSON(http.StatusUnauthorized, schemas.ErrorResponse{
-			Error: "Invalid email or password",
+			Error: "User not authenticated",
+		})
+		return
+	}
+
+	if err := services.NewTokenRevocationService().RevokeToken(claims); err != nil {
+		c.JSON(http.StatusInternalServerError, schemas.ErrorResponse{
+			Error: fmt.Sprintf("Failed to logout: %v", err),
+		})
+		return
+	}
+
+	if input.RefreshToken == "" && c.GetBool(cookieAuthContextKey) {
+		input.RefreshToken, _ = c.Cookie(RefreshTokenCookie)
+	}
+	if input.RefreshToken != "" {
+		err := services.NewRefreshTokenService().RevokeToken(input.RefreshToken, claims.UserID)
+		if err != nil && !errors.Is(err, services.ErrInvalidRefreshToken) {
+			c.JSON(http.StatusInternalServerError, schemas.ErrorResponse{
+				Error: fmt.Sprintf("Failed to logout: %v", err),
+			})
+			return
+		}
+	}
+
+	clearAuthCookies(c)
+	c.JSON(http.StatusOK, schemas.MessageResponse{
+		Message: "Logged out successfully",
+	})
+}
+
+// @Summary Logout everywhere
+// @Description Revokes every access and refresh token of the authenticated user
+// @Tags auth
+// @Produce json
+// @Success 200 {object} schemas.MessageResponse
+// @Router /auth/logout-all [post]
+func (v *AuthViews) LogoutAll(c *gin.Context) {
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, schemas.ErrorResponse{
+			Error: "User not authenticated",
+		})
+		return
+	}
+
+	if err := services.NewTokenRevocationService().RevokeAllForUser(userID); err != nil {
+		c.JSON(http.StatusInternalServerError, schemas.ErrorResponse{
+			Error: fmt.Sprintf("Failed to logout: %v", err),
+		})
+		return
+	}
+
+	clearAuthCookies(c)
+	c.JSON(http.StatusOK, schemas.MessageResponse{
+		Message: "Logged out from all sessions",
+	})
+}
+
+// @Summary Request a password reset
+// @Description Emails a single-use reset link. The response is the same whether or not the email belongs to an account.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param forgotPasswordInput body schemas.ForgotPasswordInput true "Account email"
+// @Success 200 {object} schemas.MessageResponse
+// @Router /auth/password/forgot [post]
+func (v *AuthViews) ForgotPassword(c *gin.Context) {
+	var input schemas.ForgotPasswordInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, schemas.ErrorResponse{
+			Error: fmt.Sprintf("Invalid request data: %v", err),
+		})
+		return
+	}
+
+	// Look the account up and send the email in the background, so the response takes as long
+	// whether or not the account exists
+	go func(email string) {
+		if err := services.NewPasswordResetService().RequestReset(email); err != nil {
+			log.Printf("[AUTH] Failed to process password reset request: %v", err)
+		}
+	}(input.Email)
+
+	c.JSON(http.StatusOK, schemas.MessageResponse{
+		Message: "If an account exists for that email, a password reset link has been sent",
+	})
+}
+
+// @Summary Reset password
+// @Description Sets a new password using a token from the reset email and logs out every session
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param resetPasswordInput body schemas.ResetPasswordInput true "Reset token and new password"
+// @Success 200 {object} schemas.MessageResponse
+// @Failure 400 {object} schemas.PasswordPolicyErrorResponse
+// @Router /auth/password/reset [post]
+func (v *AuthViews) ResetPassword(c *gin.Context) {
+	var input schemas.ResetPasswordInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, schemas.ErrorResponse{
+			Error: fmt.Sprintf("Invalid request data: %v", err),
+		})
+		return
+	}
+
+	if err := services.NewPasswordResetService().ResetPassword(input.Token, input.NewPassword); err != nil {
+		if errors.Is(err, services.ErrInvalidResetToken) {
+			c.JSON(http.StatusBadRequest, schemas.ErrorResponse{
+				Error: "Invalid or expired reset token",
+			})
+			return
+		}
+		if respondToPasswordPolicy(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, schemas.ErrorResponse{
+			Error: fmt.Sprintf("Failed to reset password: %v", err),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, schemas.MessageResponse{
+		Message: "Password has been reset successfully",
+	})
+}
+
+// @Summary Verify email address
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param verifyEmailInput body schemas.VerifyEmailInput true "Token from the verification email"
+// @Success 200 {object} schemas.UserResponse
+// @Router /auth/verify-email [post]
+func (v *AuthViews) VerifyEmail(c *gin.Context) {
+	var input schemas.VerifyEmailInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, schemas.ErrorResponse{
+			Error: fmt.Sprintf("Invalid request data: %v", err),
 		})
 		return
 	}
 
-	// Generate JWT token
-	token, err := services.GenerateToken(*user)
+	user, err := services.NewEmailVerificationService().Verify(input.Token)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidVerificationToken) {
+			c.JSON(http.StatusBadRequest, schemas.ErrorResponse{
+				Error: "Invalid or expired verification token",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, schemas.ErrorResponse{
+			Error: fmt.Sprintf("Failed to verify email: %v", err),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, schemas.UserResponse{
+		Data:    *user,
+		Message: "Email verified successfully",
+	})
+}
+
+// @Summary Resend verification email
+// @Tags auth
+// @Produce json
+// @Success 200 {object} schemas.MessageResponse
+// @Router /auth/verify-email/resend [post]
+func (v *AuthViews) ResendVerificationEmail(c *gin.Context) {
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, schemas.ErrorResponse{
+			Error: "User not authenticated",
+		})
+		return
+	}
+
+	user, err := v.userService.GetByID(userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, schemas.ErrorResponse{
+			Error: "User not found",
+		})
+		return
+	}
+
+	if err := services.NewEmailVerificationService().SendVerification(*user); err != nil {
+		if errors.Is(err, services.ErrEmailAlreadyVerified) {
+			c.JSON(http.StatusBadRequest, schemas.ErrorResponse{
+				Error: "Email is already verified",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, schemas.ErrorResponse{
+			Error: fmt.Sprintf("Failed to send verification email: %v", err),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, schemas.MessageResponse{
+		Message: "Verification email sent",
+	})
+}
+
+// @Summary Request a magic link
+// @Description Emails a single-use sign-in link. The response is the same whether or not the email belongs to an account.
+// @Description Requests are limited per email address: past the limit the response is 429 with a Retry-After header.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param magicLinkInput body schemas.MagicLinkInput true "Account email"
+// @Success 200 {object} schemas.MessageResponse
+// @Failure 429 {object} schemas.ErrorResponse
+// @Router /auth/magic-link [post]
+func (v *AuthViews) RequestMagicLink(c *gin.Context) {
+	var input schemas.MagicLinkInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, schemas.ErrorResponse{
+			Error: fmt.Sprintf("Invalid request data: %v", err),
+		})
+		return
+	}
+
+	// Unknown emails are counted too, so the limit doesn't reveal which accounts exist
+	if err := v.throttleService.CheckMagicLinkRequest(input.Email); err != nil {
+		respondToThrottle(c, err)
+		return
+	}
+	if err := v.throttleService.RecordMagicLinkRequest(input.Email); err != nil {
+		respondToThrottle(c, err)
+		return
+	}
+
+	// Like password resets, the lookup and the email happen in the background so that the
+	// response takes as long whether or not the account exists
+	go func(email string) {
+		if err := services.NewMagicLinkService().Send(email); err != nil {
+			log.Printf("[AUTH] Failed to process magic link request: %v", err)
+		}
+	}(input.Email)
+
+	c.JSON(http.StatusOK, schemas.MessageResponse{
+		Message: "If an account exists for that email, a sign-in link has been sent",
+	})
+}
+
+// @Summary Log in with a magic link
+// @Description Exchanges the token from a magic link email for tokens, like /auth/login. Users with
+// @Description two-factor authentication get an mfa_token to exchange at /auth/mfa/verify instead.
+// @Description Accounts with a password must verify their email address before they can use magic links.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param consumeMagicLinkInput body schemas.ConsumeMagicLinkInput true "Token from the magic link"
+// @Success 200 {object} schemas.AuthResponse
+// @Success 200 {object} schemas.MFAChallengeResponse
+// @Failure 400 {object} schemas.ErrorResponse
+// @Failure 403 {object} schemas.ErrorResponse
+// @Router /auth/magic-link/verify [post]
+func (v *AuthViews) ConsumeMagicLink(c *gin.Context) {
+	var input schemas.ConsumeMagicLinkInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, schemas.ErrorResponse{
+			Error: fmt.Sprintf("Invalid request data: %v", err),
+		})
+		return
+	}
+
+	user, err := services.NewMagicLinkService().Consume(input.Token)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidMagicLink) {
+			c.JSON(http.StatusBadRequest, schemas.ErrorResponse{
+				Error: "Invalid or expired magic link",
+			})
+			return
+		}
+		if errors.Is(err, services.ErrMagicLinkEmailUnverified) {
+			c.JSON(http.StatusForbidden, schemas.ErrorResponse{
+				Error: "Verify your email address or sign in with your password first",
+				Code:  EmailNotVerifiedCode,
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, schemas.ErrorResponse{
+			Error: fmt.Sprintf("Failed to log in: %v", err),
+		})
+		return
+	}
+
+	completeLogin(c, *user, "Login successful")
+}
+
+// loginAttempt describes the current request for the login throttle
+func loginAttempt(c *gin.Context, email string, userID *uint) services.LoginAttempt {
+	return services.LoginAttempt{
+		Email:     email,
+		UserID:    userID,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+}
+
+// clientDevice describes the client of the current request for session tracking
+func clientDevice(c *gin.Context) services.Device {
+	return services.Device{
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+}
+
+// respondToThrottle answers a lockout with 429 and Retry-After, and any other throttle error with 500
+func respondToThrottle(c *gin.Context, err error) {
+	lockout, ok := services.IsLockout(err)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, schemas.ErrorResponse{
+			Error: fmt.Sprintf("Failed to check login attempts: %v", err),
+		})
+		return
+	}
+
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(lockout.RetryAfter.Seconds()))))
+	c.JSON(http.StatusTooManyRequests, schemas.ErrorResponse{
+		Error: "Too many failed login attempts, try again later",
+		Code:  TooManyAttemptsCode,
+	})
+}
+
+// completeLogin finishes a first-factor login: users with two-factor authentication get a
+// pending MFA challenge, everybody else gets tokens straight away
+func completeLogin(c *gin.Context, user models.User, message string) {
+	if !user.HasTOTPEnabled() {
+		respondWithTokens(c, http.StatusOK, user, message)
+		return
+	}
+
+	mfaToken, err := services.NewMFAService().IssuePendingToken(user)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, schemas.ErrorResponse{
 			Error: fmt.Sprintf("Failed to generate token: %v", err),
@@ -61,15 +501,75 @@ func (v *AuthViews) Login(c *gin.Context) {
 		return
 	}
 
+	c.JSON(http.StatusOK, schemas.MFAChallengeResponse{
+		MFARequired: true,
+		MFAToken:    mfaToken,
+		Message:     "Two-factor authentication required",
+	})
+}
+
+// respondWithTokens issues a fresh access/refresh token pair for the user and writes the auth response
+func respondWithTokens(c *gin.Context, status int, user models.User, message string) {
+	tokens, err := services.IssueTokenPair(user, clientDevice(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, schemas.ErrorResponse{
+			Error: fmt.Sprintf("Failed to generate token: %v", err),
+		})
+		return
+	}
+
+	writeAuthResponse(c, status, user, tokens, message)
+}
+
+// writeAuthResponse returns the tokens in the body, or sets them as cookies for cookie-auth clients
+func writeAuthResponse(c *gin.Context, status int, user models.User, tokens *services.TokenPair, message string) {
 	response := schemas.AuthResponse{
-		Token: token,
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
 		UserResponse: schemas.UserResponse{
-			Data:    *user,
-			Message: "Login successful",
+			Data:    user,
+			Message: message,
 		},
 	}
 
-	c.JSON(http.StatusOK, response)
+	if cookieAuthRequested(c) {
+		if err := setAuthCookies(c, tokens); err != nil {
+			c.JSON(http.StatusInternalServerError, schemas.ErrorResponse{
+				Error: fmt.Sprintf("Failed to generate token: %v", err),
+			})
+			return
+		}
+		// Keep the tokens out of reach of scripts
+		response.Token = ""
+		response.RefreshToken = ""
+	}
+
+	c.JSON(status, response)
+}
+
+// refreshTokenFromCookie reads the refresh token of a cookie-auth client. The refresh endpoint is
+// not behind AuthMiddleware, so it checks the CSRF token itself. It responds and returns false when
+// there is no usable token.
+func refreshTokenFromCookie(c *gin.Context) (string, bool) {
+	refreshToken, err := c.Cookie(RefreshTokenCookie)
+	if !services.AuthCookiesEnabled() || err != nil || refreshToken == "" {
+		c.JSON(http.StatusBadRequest, schemas.ErrorResponse{
+			Error: "Invalid request data: refresh_token is required",
+		})
+		return "", false
+	}
+
+	if !validCSRFToken(c) {
+		c.JSON(http.StatusForbidden, schemas.ErrorResponse{
+			Error: "Missing or invalid CSRF token",
+			Code:  CSRFTokenInvalidCode,
+		})
+		return "", false
+	}
+
+	c.Set(cookieAuthContextKey, true)
+	return refreshToken, true
 }
 
 // RegisterRoutes registers auth-related routes
@@ -77,5 +577,14 @@ func (v *AuthViews) RegisterRoutes(router *gin.Engine) {
 	auth := router.Group("/auth")
 	{
 		auth.POST("/login", v.Login)
+		auth.POST("/refresh", v.Refresh)
+		auth.POST("/logout", AuthMiddleware(), v.Logout)
+		auth.POST("/logout-all", AuthMiddleware(), ForbidImpersonation(), v.LogoutAll)
+		auth.POST("/password/forgot", v.ForgotPassword)
+		auth.POST("/password/reset", v.ResetPassword)
+		auth.POST("/verify-email", v.VerifyEmail)
+		auth.POST("/verify-email/resend", AuthMiddleware(), v.ResendVerificationEmail)
+		auth.POST("/magic-link", v.RequestMagicLink)
+		auth.POST("/magic-link/verify", v.ConsumeMagicLink)
 	}
 }
@@ -0,0 +1,156 @@
+package views
+
+import (
+	"errors"
+	"fmt"
+	"go-crud/schemas"
+	"go-crud/services"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DataExportViews struct {
+	service *services.DataExportService
+}
+
+func NewDataExportViews() *DataExportViews {
+	return &DataExportViews{
+		service: services.NewDataExportService(),
+	}
+}
+
+// @Summary Export my data
+// @Description Starts building an archive of the user's profile, posts, tags and account data.
+// @Description Poll the returned export until it is ready. While an export is in progress it is returned again.
+// @Tags users
+// @Produce json
+// @Success 202 {object} schemas.DataExportResponse
+// @Router /users/me/export [post]
+func (v *DataExportViews) RequestExport(c *gin.Context) {
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, schemas.ErrorResponse{
+			Error: "User not authenticated",
+		})
+		return
+	}
+
+	export, err := v.service.Request(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, schemas.ErrorResponse{
+			Error: fmt.Sprintf("Failed to start data export: %v", err),
+		})
+		return
+	}
+
+	// Build the archive right away; the background job picks it up if this process stops first
+	go func(id uint) {
+		if err := v.service.Process(id); err != nil {
+			log.Printf("[EXPORT] Failed to build data export %d: %v", id, err)
+		}
+	}(export.ID)
+
+	c.JSON(http.StatusAccepted, schemas.DataExportResponse{
+		Data:    *export,
+		Message: "Your data export is being prepared",
+	})
+}
+
+// @Summary Get a data export
+// @Description Returns the status of an export, and a signed download URL once it is ready
+// @Tags users
+// @Produce json
+// @Param id path int true "Export ID"
+// @Success 200 {object} schemas.DataExportResponse
+// @Failure 404 {object} schemas.ErrorResponse
+// @Router /users/me/exports/{id} [get]
+func (v *DataExportViews) GetExport(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, schemas.ErrorResponse{
+			Error: "Invalid export ID",
+		})
+		return
+	}
+
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, schemas.ErrorResponse{
+			Error: "User not authenticated",
+		})
+		return
+	}
+
+	export, err := v.service.Get(userID, uint(id))
+	if err != nil {
+		if errors.Is(err, services.ErrDataExportNotFound) {
+			c.JSON(http.StatusNotFound, schemas.ErrorResponse{
+				Error: "Export not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, schemas.ErrorResponse{
+			Error: fmt.Sprintf("Failed to get data export: %v", err),
+		})
+		return
+	}
+
+	response := schemas.DataExportResponse{Data: *export}
+	downloadURL, expiresAt, err := v.service.DownloadURL(*export)
+	switch {
+	case err == nil:
+		response.DownloadURL = downloadURL
+		response.DownloadExpiresAt = &expiresAt
+	case !errors.Is(err, services.ErrDataExportNotReady):
+		c.JSON(http.StatusInternalServerError, schemas.ErrorResponse{
+			Error: fmt.Sprintf("Failed to sign download URL: %v", err),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// @Summary Download a data export
+// @Description Downloads the zip archive of an export. The signed URL comes from GET /users/me/exports/{id}
+// @Description and works without an Authorization header until it expires.
+// @Tags users
+// @Produce application/zip
+// @Param token query string true "Signed download token"
+// @Success 200 {file} file
+// @Failure 404 {object} schemas.ErrorResponse
+// @Router /exports/download [get]
+func (v *DataExportViews) DownloadExport(c *gin.Context) {
+	export, err := v.service.Download(c.Query("token"))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidSignedToken):
+			c.JSON(http.StatusForbidden, schemas.ErrorResponse{
+				Error: "Invalid or expired download link",
+			})
+		case errors.Is(err, services.ErrDataExportNotFound):
+			c.JSON(http.StatusNotFound, schemas.ErrorResponse{
+				Error: "Export not found or no longer available",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, schemas.ErrorResponse{
+				Error: fmt.Sprintf("Failed to download data export: %v", err),
+			})
+		}
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="data-export-%d.zip"`, export.ID))
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "application/zip", export.Archive)
+}
+
+// RegisterRoutes registers data export routes
+func (v *DataExportViews) RegisterRoutes(router *gin.Engine) {
+	router.POST("/users/me/export", AuthMiddleware(), ForbidImpersonation(), v.RequestExport)
+	router.GET("/users/me/exports/:id", AuthMiddleware(), ForbidImpersonation(), v.GetExport)
+	router.GET("/exports/download", v.DownloadExport)
+}
@@ -0,0 +1,170 @@
+package views
+
+import (
+	"errors"
+	"fmt"
+	"go-crud/schemas"
+	"go-crud/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ImpersonationViews struct {
+	service *services.ImpersonationService
+}
+
+func NewImpersonationViews() *ImpersonationViews {
+	return &ImpersonationViews{
+		service: services.NewImpersonationService(),
+	}
+}
+
+// @Summary Impersonate a user
+// @Description Admin only. Issues a short-lived access token that acts as the user, to reproduce problems they report.
+// @Description Every request made with it is audited. It cannot change credentials, end sessions or delete the account,
+// @Description and it comes without a refresh token. Admins cannot be impersonated.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param impersonateInput body schemas.ImpersonateUserInput true "Why the user is impersonated"
+// @Success 201 {object} schemas.ImpersonationTokenResponse
+// @Failure 403 {object} schemas.ErrorResponse
+// @Router /users/{id}/impersonate [post]
+func (v *ImpersonationViews) Impersonate(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, schemas.ErrorResponse{
+			Error: "Invalid user ID",
+		})
+		return
+	}
+
+	var input schemas.ImpersonateUserInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, schemas.ErrorResponse{
+			Error: fmt.Sprintf("Invalid request data: %v", err),
+		})
+		return
+	}
+
+	claims, exists := GetClaimsFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, schemas.ErrorResponse{
+			Error: "User not authenticated",
+		})
+		return
+	}
+
+	impersonation, token, err := v.service.Start(claims, uint(id), input.Reason, clientDevice(c))
+	if err != nil {
+		switch {
+		case err.Error() == "user not found":
+			c.JSON(http.StatusNotFound, schemas.ErrorResponse{
+				Error: "User not found",
+			})
+		case errors.Is(err, services.ErrCannotImpersonate), errors.Is(err, services.ErrNestedImpersonation):
+			c.JSON(http.StatusForbidden, schemas.ErrorResponse{
+				Error: err.Error(),
+				Code:  ImpersonationDeniedCode,
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, schemas.ErrorResponse{
+				Error: fmt.Sprintf("Failed to impersonate user: %v", err),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, schemas.ImpersonationTokenResponse{
+		Data:      *impersonation,
+		Token:     token,
+		ExpiresIn: int64(services.ImpersonationTTL().Seconds()),
+		Message:   "Every request made with this token is audited",
+	})
+}
+
+// @Summary List impersonations
+// @Description Admin only. Lists who impersonated whom and why, most recent first.
+// @Tags users
+// @Produce json
+// @Param user_id query int false "Only impersonations of this user"
+// @Success 200 {object} schemas.ListImpersonationsResponse
+// @Failure 403 {object} schemas.ErrorResponse
+// @Router /impersonations [get]
+func (v *ImpersonationViews) ListImpersonations(c *gin.Context) {
+	var userID *uint
+	if param := c.Query("user_id"); param != "" {
+		id, err := strconv.ParseUint(param, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, schemas.ErrorResponse{
+				Error: "Invalid user ID",
+			})
+			return
+		}
+		filter := uint(id)
+		userID = &filter
+	}
+
+	impersonations, err := v.service.List(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, schemas.ErrorResponse{
+			Error: fmt.Sprintf("Failed to list impersonations: %v", err),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, schemas.ListImpersonationsResponse{
+		Data: impersonations,
+	})
+}
+
+// @Summary Get an impersonation
+// @Description Admin only. Returns an impersonation with every request made with its token.
+// @Tags users
+// @Produce json
+// @Param id path int true "Impersonation ID"
+// @Success 200 {object} schemas.ImpersonationResponse
+// @Failure 404 {object} schemas.ErrorResponse
+// @Router /impersonations/{id} [get]
+func (v *ImpersonationViews) GetImpersonation(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, schemas.ErrorResponse{
+			Error: "Invalid impersonation ID",
+		})
+		return
+	}
+
+	impersonation, requests, err := v.service.Get(uint(id))
+	if err != nil {
+		if errors.Is(err, services.ErrImpersonationNotFound) {
+			c.JSON(http.StatusNotFound, schemas.ErrorResponse{
+				Error: "Impersonation not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, schemas.ErrorResponse{
+			Error: fmt.Sprintf("Failed to get impersonation: %v", err),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, schemas.ImpersonationResponse{
+		Data:     *impersonation,
+		Requests: requests,
+	})
+}
+
+// RegisterRoutes registers impersonation routes
+func (v *ImpersonationViews) RegisterRoutes(router *gin.Engine) {
+	router.POST("/users/:id/impersonate", AuthMiddleware(), RequirePermission(services.PermUsersImpersonate), v.Impersonate)
+
+	impersonations := router.Group("/impersonations")
+	{
+		impersonations.GET("", AuthMiddleware(), RequirePermission(services.PermUsersImpersonate), v.ListImpersonations)
+		impersonations.GET("/:id", AuthMiddleware(), RequirePermission(services.PermUsersImpersonate), v.GetImpersonation)
+	}
+}
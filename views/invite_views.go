@@ -0,0 +1,152 @@
+package views
+
+import (
+	"errors"
+	"fmt"
+	"go-crud/models"
+	"go-crud/schemas"
+	"go-crud/services"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type InviteViews struct {
+	service *services.InviteService
+}
+
+func NewInviteViews() *InviteViews {
+	return &InviteViews{
+		service: services.NewInviteService(),
+	}
+}
+
+// @Summary Create an invite
+// @Description Creates an invite code that lets people sign up while registration is restricted.
+// @Description Accounts created with it get its role. The code is only returned in this response.
+// @Tags invites
+// @Accept json
+// @Produce json
+// @Param inviteInput body schemas.CreateInviteInput true "Role, number of uses and optional expiry"
+// @Success 201 {object} schemas.InviteCreatedResponse
+// @Failure 403 {object} schemas.ErrorResponse
+// @Router /invites [post]
+func (v *InviteViews) CreateInvite(c *gin.Context) {
+	var input schemas.CreateInviteInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, schemas.ErrorResponse{
+			Error: fmt.Sprintf("Invalid request data: %v", err),
+		})
+		return
+	}
+
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, schemas.ErrorResponse{
+			Error: "User not authenticated",
+		})
+		return
+	}
+
+	role := models.RoleAuthor
+	if input.Role != "" {
+		role = models.Role(input.Role)
+	}
+	maxUses := 1
+	if input.MaxUses > 0 {
+		maxUses = input.MaxUses
+	}
+	expiresAt := time.Now().Add(services.InviteDefaultTTL())
+	if input.ExpiresInDays != nil {
+		expiresAt = time.Now().AddDate(0, 0, *input.ExpiresInDays)
+	}
+
+	invite, code, err := v.service.Create(userID, role, maxUses, expiresAt)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidRole) {
+			c.JSON(http.StatusBadRequest, schemas.ErrorResponse{
+				Error: "Invalid role",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, schemas.ErrorResponse{
+			Error: fmt.Sprintf("Failed to create invite: %v", err),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, schemas.InviteCreatedResponse{
+		Data:    *invite,
+		Code:    code,
+		Message: "Copy the invite code now, it will not be shown again",
+	})
+}
+
+// @Summary List invites
+// @Description Lists the invites that have not been revoked, including expired and used up ones
+// @Tags invites
+// @Produce json
+// @Success 200 {object} schemas.ListInvitesResponse
+// @Failure 403 {object} schemas.ErrorResponse
+// @Router /invites [get]
+func (v *InviteViews) ListInvites(c *gin.Context) {
+	invites, err := v.service.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, schemas.ErrorResponse{
+			Error: fmt.Sprintf("Failed to list invites: %v", err),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, schemas.ListInvitesResponse{
+		Data: invites,
+	})
+}
+
+// @Summary Revoke an invite
+// @Description Stops an invite from being used. Accounts already created with it are kept.
+// @Tags invites
+// @Produce json
+// @Param id path int true "Invite ID"
+// @Success 200 {object} schemas.MessageResponse
+// @Failure 403 {object} schemas.ErrorResponse
+// @Failure 404 {object} schemas.ErrorResponse
+// @Router /invites/{id} [delete]
+func (v *InviteViews) RevokeInvite(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, schemas.ErrorResponse{
+			Error: "Invalid invite ID",
+		})
+		return
+	}
+
+	if err := v.service.Revoke(uint(id)); err != nil {
+		if errors.Is(err, services.ErrInviteNotFound) {
+			c.JSON(http.StatusNotFound, schemas.ErrorResponse{
+				Error: "Invite not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, schemas.ErrorResponse{
+			Error: fmt.Sprintf("Failed to revoke invite: %v", err),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, schemas.MessageResponse{
+		Message: "Invite revoked",
+	})
+}
+
+// RegisterRoutes registers invite management routes
+func (v *InviteViews) RegisterRoutes(router *gin.Engine) {
+	invites := router.Group("/invites")
+	{
+		invites.POST("", AuthMiddleware(), RequirePermission(services.PermInvitesManage), v.CreateInvite)
+		invites.GET("", AuthMiddleware(), RequirePermission(services.PermInvitesManage), v.ListInvites)
+		invites.DELETE("/:id", AuthMiddleware(), RequirePermission(services.PermInvitesManage), v.RevokeInvite)
+	}
+}
@@ -0,0 +1,238 @@
+package views
+
+import (
+	"errors"
+	"fmt"
+	"go-crud/schemas"
+	"go-crud/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MFAViews struct {
+	service         *services.MFAService
+	throttleService *services.LoginThrottleService
+}
+
+func NewMFAViews() *MFAViews {
+	return &MFAViews{
+		service:         services.NewMFAService(),
+		throttleService: services.NewLoginThrottleService(),
+	}
+}
+
+// @Summary Start TOTP enrollment
+// @Description Generates a TOTP secret and the otpauth:// provisioning URI to render as a QR code
+// @Tags mfa
+// @Produce json
+// @Success 200 {object} schemas.TOTPEnrollmentResponse
+// @Router /auth/mfa/totp/enroll [post]
+func (v *MFAViews) EnrollTOTP(c *gin.Context) {
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, schemas.ErrorResponse{
+			Error: "User not authenticated",
+		})
+		return
+	}
+
+	enrollment, err := v.service.BeginEnrollment(userID)
+	if err != nil {
+		if errors.Is(err, services.ErrMFAAlreadyEnabled) {
+			c.JSON(http.StatusConflict, schemas.ErrorResponse{
+				Error: "Two-factor authentication is already enabled",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, schemas.ErrorResponse{
+			Error: fmt.Sprintf("Failed to start enrollment: %v", err),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, schemas.TOTPEnrollmentResponse{
+		Secret:          enrollment.Secret,
+		ProvisioningURI: enrollment.ProvisioningURI,
+		Message:         "Scan the QR code with your authenticator app, then confirm with a code",
+	})
+}
+
+// @Summary Confirm TOTP enrollment
+// @Description Enables two-factor authentication and returns one-time recovery codes. They are only shown once.
+// @Tags mfa
+// @Accept json
+// @Produce json
+// @Param codeInput body schemas.MFACodeInput true "Code from the authenticator app"
+// @Success 200 {object} schemas.RecoveryCodesResponse
+// @Router /auth/mfa/totp/confirm [post]
+func (v *MFAViews) ConfirmTOTP(c *gin.Context) {
+	var input schemas.MFACodeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, schemas.ErrorResponse{
+			Error: fmt.Sprintf("Invalid request data: %v", err),
+		})
+		return
+	}
+
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, schemas.ErrorResponse{
+			Error: "User not authenticated",
+		})
+		return
+	}
+
+	codes, err := v.service.ConfirmEnrollment(userID, input.Code)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrMFAAlreadyEnabled):
+			c.JSON(http.StatusConflict, schemas.ErrorResponse{
+				Error: "Two-factor authentication is already enabled",
+			})
+		case errors.Is(err, services.ErrMFAEnrollmentNotFound):
+			c.JSON(http.StatusBadRequest, schemas.ErrorResponse{
+				Error: "Start enrollment before confirming it",
+			})
+		case errors.Is(err, services.ErrInvalidMFACode):
+			c.JSON(http.StatusBadRequest, schemas.ErrorResponse{
+				Error: "Invalid two-factor code",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, schemas.ErrorResponse{
+				Error: fmt.Sprintf("Failed to confirm enrollment: %v", err),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, schemas.RecoveryCodesResponse{
+		RecoveryCodes: codes,
+		Message:       "Two-factor authentication enabled",
+	})
+}
+
+// @Summary Disable TOTP
+// @Tags mfa
+// @Accept json
+// @Produce json
+// @Param codeInput body schemas.MFACodeInput true "Current TOTP code or a recovery code"
+// @Success 200 {object} schemas.MessageResponse
+// @Router /auth/mfa/totp/disable [post]
+func (v *MFAViews) DisableTOTP(c *gin.Context) {
+	var input schemas.MFACodeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, schemas.ErrorResponse{
+			Error: fmt.Sprintf("Invalid request data: %v", err),
+		})
+		return
+	}
+
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, schemas.ErrorResponse{
+			Error: "User not authenticated",
+		})
+		return
+	}
+
+	if err := v.service.Disable(userID, input.Code); err != nil {
+		switch {
+		case errors.Is(err, services.ErrMFANotEnabled):
+			c.JSON(http.StatusBadRequest, schemas.ErrorResponse{
+				Error: "Two-factor authentication is not enabled",
+			})
+		case errors.Is(err, services.ErrInvalidMFACode):
+			c.JSON(http.StatusBadRequest, schemas.ErrorResponse{
+				Error: "Invalid two-factor code",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, schemas.ErrorResponse{
+				Error: fmt.Sprintf("Failed to disable two-factor authentication: %v", err),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, schemas.MessageResponse{
+		Message: "Two-factor authentication disabled",
+	})
+}
+
+// @Summary Complete a two-factor login
+// @Description Exchanges the mfa_token returned by /auth/login plus a TOTP or recovery code for access tokens
+// @Tags mfa
+// @Accept json
+// @Produce json
+// @Param verifyInput body schemas.MFAVerifyInput true "Pending MFA token and code"
+// @Success 200 {object} schemas.AuthResponse
+// @Failure 429 {object} schemas.ErrorResponse
+// @Router /auth/mfa/verify [post]
+func (v *MFAViews) Verify(c *gin.Context) {
+	var input schemas.MFAVerifyInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, schemas.ErrorResponse{
+			Error: fmt.Sprintf("Invalid request data: %v", err),
+		})
+		return
+	}
+
+	user, err := v.service.PendingUser(input.MFAToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidMFAToken), errors.Is(err, services.ErrMFANotEnabled):
+			c.JSON(http.StatusUnauthorized, schemas.ErrorResponse{
+				Error: "Invalid or expired mfa token",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, schemas.ErrorResponse{
+				Error: fmt.Sprintf("Failed to verify code: %v", err),
+			})
+		}
+		return
+	}
+
+	// Wrong codes count towards the same lockout as wrong passwords
+	attempt := loginAttempt(c, user.Email, &user.ID)
+	if err := v.throttleService.Check(attempt); err != nil {
+		respondToThrottle(c, err)
+		return
+	}
+
+	if err := v.service.VerifyCode(user, input.Code); err != nil {
+		if !errors.Is(err, services.ErrInvalidMFACode) {
+			c.JSON(http.StatusInternalServerError, schemas.ErrorResponse{
+				Error: fmt.Sprintf("Failed to verify code: %v", err),
+			})
+			return
+		}
+		if err := v.throttleService.RecordFailure(attempt); err != nil {
+			respondToThrottle(c, err)
+			return
+		}
+		c.JSON(http.StatusUnauthorized, schemas.ErrorResponse{
+			Error: "Invalid two-factor code",
+		})
+		return
+	}
+
+	if err := v.throttleService.RecordSuccess(attempt); err != nil {
+		c.JSON(http.StatusInternalServerError, schemas.ErrorResponse{
+			Error: fmt.Sprintf("Failed to verify code: %v", err),
+		})
+		return
+	}
+
+	respondWithTokens(c, http.StatusOK, *user, "Login successful")
+}
+
+// RegisterRoutes registers two-factor authentication routes
+func (v *MFAViews) RegisterRoutes(router *gin.Engine) {
+	mfa := router.Group("/auth/mfa")
+	{
+		mfa.POST("/verify", v.Verify)
+		mfa.POST("/totp/enroll", AuthMiddleware(), ForbidImpersonation(), v.EnrollTOTP)
+		mfa.POST("/totp/confirm", AuthMiddleware(), ForbidImpersonation(), v.ConfirmTOTP)
+		mfa.POST("/totp/disable", AuthMiddleware(), ForbidImpersonation(), v.DisableTOTP)
+	}
+}
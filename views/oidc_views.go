@@ -0,0 +1,246 @@
+package views
+
+import (
+	"errors"
+	"fmt"
+	"go-crud/schemas"
+	"go-crud/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OIDCViews struct {
+	service *services.OIDCService
+}
+
+func NewOIDCViews() *OIDCViews {
+	return &OIDCViews{
+		service: services.NewOIDCService(),
+	}
+}
+
+// @Summary List social login providers
+// @Description Returns the names of the configured OpenID Connect providers
+// @Tags oidc
+// @Produce json
+// @Success 200 {object} schemas.OIDCProvidersResponse
+// @Router /auth/oidc/providers [get]
+func (v *OIDCViews) ListProviders(c *gin.Context) {
+	providers := services.EnabledOIDCProviders()
+	if providers == nil {
+		providers = []string{}
+	}
+
+	c.JSON(http.StatusOK, schemas.OIDCProvidersResponse{
+		Providers: providers,
+	})
+}
+
+// @Summary Start a social login
+// @Description Returns the provider URL to redirect the browser to (authorization code flow with PKCE)
+// @Tags oidc
+// @Produce json
+// @Param provider path string true "Provider name"
+// @Success 200 {object} schemas.AuthorizationURLResponse
+// @Router /auth/oidc/{provider}/authorize [get]
+func (v *OIDCViews) Authorize(c *gin.Context) {
+	v.startFlow(c, nil)
+}
+
+// @Summary Link a social login to the current account
+// @Description Same as authorize, but the identity returned by the provider is attached to the signed-in user
+// @Tags oidc
+// @Produce json
+// @Param provider path string true "Provider name"
+// @Success 200 {object} schemas.AuthorizationURLResponse
+// @Router /auth/oidc/{provider}/link [post]
+func (v *OIDCViews) Link(c *gin.Context) {
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, schemas.ErrorResponse{
+			Error: "User not authenticated",
+		})
+		return
+	}
+
+	v.startFlow(c, &userID)
+}
+
+func (v *OIDCViews) startFlow(c *gin.Context, linkUserID *uint) {
+	authorizationURL, err := v.service.AuthorizationURL(c.Request.Context(), c.Param("provider"), linkUserID)
+	if err != nil {
+		if errors.Is(err, services.ErrOIDCProviderNotFound) {
+			c.JSON(http.StatusNotFound, schemas.ErrorResponse{
+				Error: "Unknown login provider",
+			})
+			return
+		}
+		c.JSON(http.StatusBadGateway, schemas.ErrorResponse{
+			Error: fmt.Sprintf("Failed to start login: %v", err),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, schemas.AuthorizationURLResponse{
+		AuthorizationURL: authorizationURL,
+	})
+}
+
+// @Summary Complete a social login
+// @Description Exchanges the code and state the provider redirected back with for access tokens.
+// @Description Users with two-factor authentication enabled get an MFA challenge instead.
+// @Tags oidc
+// @Produce json
+// @Param provider path string true "Provider name"
+// @Param code query string true "Authorization code"
+// @Param state query string true "State returned by the provider"
+// @Success 200 {object} schemas.AuthResponse
+// @Success 200 {object} schemas.MFAChallengeResponse
+// @Failure 403 {object} schemas.ErrorResponse
+// @Router /auth/oidc/{provider}/callback [get]
+func (v *OIDCViews) Callback(c *gin.Context) {
+	if providerError := c.Query("error"); providerError != "" {
+		c.JSON(http.StatusUnauthorized, schemas.ErrorResponse{
+			Error: fmt.Sprintf("Login was not completed: %s", providerError),
+		})
+		return
+	}
+
+	code, state := c.Query("code"), c.Query("state")
+	if code == "" || state == "" {
+		c.JSON(http.StatusBadRequest, schemas.ErrorResponse{
+			Error: "code and state are required",
+		})
+		return
+	}
+
+	user, err := v.service.HandleCallback(c.Request.Context(), c.Param("provider"), code, state)
+	if err != nil {
+		// New accounts from a provider follow the same registration policy as signups
+		if respondToRegistrationPolicy(c, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, services.ErrOIDCProviderNotFound):
+			c.JSON(http.StatusNotFound, schemas.ErrorResponse{
+				Error: "Unknown login provider",
+			})
+		case errors.Is(err, services.ErrInvalidOAuthState):
+			c.JSON(http.StatusBadRequest, schemas.ErrorResponse{
+				Error: "Invalid or expired login attempt, please start again",
+			})
+		case errors.Is(err, services.ErrOIDCEmailNotVerified):
+			c.JSON(http.StatusConflict, schemas.ErrorResponse{
+				Error: "An account with this email already exists. Sign in and link the provider from your account settings.",
+			})
+		case errors.Is(err, services.ErrEmailTaken):
+			c.JSON(http.StatusConflict, schemas.ErrorResponse{
+				Error: "Email is already in use",
+			})
+		case errors.Is(err, services.ErrIdentityLinkedElsewhere):
+			c.JSON(http.StatusConflict, schemas.ErrorResponse{
+				Error: "This provider account is already linked to another user",
+			})
+		default:
+			c.JSON(http.StatusUnauthorized, schemas.ErrorResponse{
+				Error: fmt.Sprintf("Login failed: %v", err),
+			})
+		}
+		return
+	}
+
+	completeLogin(c, *user, "Login successful")
+}
+
+// @Summary List linked social logins
+// @Tags oidc
+// @Produce json
+// @Success 200 {object} schemas.ListIdentitiesResponse
+// @Router /users/me/identities [get]
+func (v *OIDCViews) ListIdentities(c *gin.Context) {
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, schemas.ErrorResponse{
+			Error: "User not authenticated",
+		})
+		return
+	}
+
+	identities, err := v.service.ListIdentities(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, schemas.ErrorResponse{
+			Error: fmt.Sprintf("Failed to list identities: %v", err),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, schemas.ListIdentitiesResponse{
+		Data: identities,
+	})
+}
+
+// @Summary Unlink a social login
+// @Description Fails when the identity is the only way to sign in to an account without a password
+// @Tags oidc
+// @Produce json
+// @Param id path int true "Identity ID"
+// @Success 200 {object} schemas.MessageResponse
+// @Router /users/me/identities/{id} [delete]
+func (v *OIDCViews) UnlinkIdentity(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, schemas.ErrorResponse{
+			Error: "Invalid identity ID",
+		})
+		return
+	}
+
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, schemas.ErrorResponse{
+			Error: "User not authenticated",
+		})
+		return
+	}
+
+	if err := v.service.Unlink(userID, uint(id)); err != nil {
+		switch {
+		case errors.Is(err, services.ErrIdentityNotFound):
+			c.JSON(http.StatusNotFound, schemas.ErrorResponse{
+				Error: "Identity not found",
+			})
+		case errors.Is(err, services.ErrLastLoginMethod):
+			c.JSON(http.StatusConflict, schemas.ErrorResponse{
+				Error: "Set a password or link another provider before removing this one",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, schemas.ErrorResponse{
+				Error: fmt.Sprintf("Failed to unlink identity: %v", err),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, schemas.MessageResponse{
+		Message: "Identity unlinked",
+	})
+}
+
+// RegisterRoutes registers OpenID Connect login and identity routes
+func (v *OIDCViews) RegisterRoutes(router *gin.Engine) {
+	oidc := router.Group("/auth/oidc")
+	{
+		oidc.GET("/providers", v.ListProviders)
+		oidc.GET("/:provider/authorize", v.Authorize)
+		oidc.POST("/:provider/link", AuthMiddleware(), ForbidImpersonation(), v.Link)
+		oidc.GET("/:provider/callback", v.Callback)
+	}
+
+	identities := router.Group("/users/me/identities")
+	{
+		identities.GET("", AuthMiddleware(), v.ListIdentities)
+		identities.DELETE("/:id", AuthMiddleware(), ForbidImpersonation(), v.UnlinkIdentity)
+	}
+}
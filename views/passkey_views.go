@@ -0,0 +1,331 @@
+package views
+
+import (
+	"errors"
+	"fmt"
+	"go-crud/schemas"
+	"go-crud/services"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PasskeyViews struct {
+	service         *services.PasskeyService
+	userService     *services.UserService
+	throttleService *services.LoginThrottleService
+}
+
+func NewPasskeyViews() *PasskeyViews {
+	return &PasskeyViews{
+		service:         services.NewPasskeyService(),
+		userService:     services.NewUserService(),
+		throttleService: services.NewLoginThrottleService(),
+	}
+}
+
+// @Summary Start passkey registration
+// @Description Requires the current password, or a two-factor code when it is enabled. Returns the options to pass to navigator.credentials.create(). The challenge expires after 5 minutes.
+// @Tags passkeys
+// @Accept json
+// @Produce json
+// @Param beginPasskeyRegistrationInput body schemas.BeginPasskeyRegistrationInput true "Current password or two-factor code"
+// @Success 200 {object} protocol.CredentialCreation
+// @Failure 400 {object} schemas.ErrorResponse
+// @Failure 403 {object} schemas.ErrorResponse
+// @Failure 429 {object} schemas.ErrorResponse
+// @Router /users/me/passkeys/register/begin [post]
+func (v *PasskeyViews) BeginRegistration(c *gin.Context) {
+	var input schemas.BeginPasskeyRegistrationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, schemas.ErrorResponse{
+			Error: fmt.Sprintf("Invalid request data: %v", err),
+		})
+		return
+	}
+
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, schemas.ErrorResponse{
+			Error: "User not authenticated",
+		})
+		return
+	}
+
+	user, err := v.userService.GetByID(userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, schemas.ErrorResponse{
+			Error: "User not found",
+		})
+		return
+	}
+
+	// A passkey signs in on its own, so adding one checks the password or code like a login does
+	attempt := loginAttempt(c, user.Email, &user.ID)
+	if err := v.throttleService.Check(attempt); err != nil {
+		respondToThrottle(c, err)
+		return
+	}
+	if err := v.userService.ConfirmIdentity(user, input.Password, input.Code); err != nil {
+		switch {
+		case errors.Is(err, services.ErrIncorrectPassword), errors.Is(err, services.ErrInvalidMFACode):
+			if err := v.throttleService.RecordFailure(attempt); err != nil {
+				respondToThrottle(c, err)
+				return
+			}
+			c.JSON(http.StatusForbidden, schemas.ErrorResponse{
+				Error: "Current password or code is incorrect",
+				Code:  IncorrectPasswordCode,
+			})
+		case errors.Is(err, services.ErrPasswordNotSet):
+			c.JSON(http.StatusBadRequest, schemas.ErrorResponse{
+				Error: "Your account has no password yet; confirm with a two-factor code or set one through the password reset flow",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, schemas.ErrorResponse{
+				Error: fmt.Sprintf("Failed to start passkey registration: %v", err),
+			})
+		}
+		return
+	}
+	if err := v.throttleService.RecordSuccess(attempt); err != nil {
+		log.Printf("[THROTTLE] Failed to clear login failures of user %d: %v", user.ID, err)
+	}
+
+	creation, err := v.service.BeginRegistration(*user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, schemas.ErrorResponse{
+			Error: fmt.Sprintf("Failed to start passkey registration: %v", err),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, creation)
+}
+
+// @Summary Finish passkey registration
+// @Description Verifies the credential created by the authenticator and stores it as a passkey
+// @Tags passkeys
+// @Accept json
+// @Produce json
+// @Param finishPasskeyRegistrationInput body schemas.FinishPasskeyRegistrationInput true "Passkey name and credential"
+// @Success 201 {object} schemas.PasskeyResponse
+// @Failure 400 {object} schemas.ErrorResponse
+// @Failure 409 {object} schemas.ErrorResponse
+// @Router /users/me/passkeys/register/finish [post]
+func (v *PasskeyViews) FinishRegistration(c *gin.Context) {
+	var input schemas.FinishPasskeyRegistrationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, schemas.ErrorResponse{
+			Error: fmt.Sprintf("Invalid request data: %v", err),
+		})
+		return
+	}
+
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, schemas.ErrorResponse{
+			Error: "User not authenticated",
+		})
+		return
+	}
+
+	user, err := v.userService.GetByID(userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, schemas.ErrorResponse{
+			Error: "User not found",
+		})
+		return
+	}
+
+	passkey, err := v.service.FinishRegistration(*user, input.Name, input.Credential, clientDevice(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidPasskeyChallenge):
+			c.JSON(http.StatusBadRequest, schemas.ErrorResponse{
+				Error: "Invalid or expired passkey challenge",
+			})
+		case errors.Is(err, services.ErrPasskeyVerificationFailed):
+			c.JSON(http.StatusBadRequest, schemas.ErrorResponse{
+				Error: "Passkey could not be verified",
+			})
+		case errors.Is(err, services.ErrPasskeyAlreadyRegistered):
+			c.JSON(http.StatusConflict, schemas.ErrorResponse{
+				Error: "This passkey is already registered",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, schemas.ErrorResponse{
+				Error: fmt.Sprintf("Failed to register passkey: %v", err),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, schemas.PasskeyResponse{
+		Data:    *passkey,
+		Message: "Passkey registered successfully",
+	})
+}
+
+// @Summary List passkeys
+// @Tags passkeys
+// @Produce json
+// @Success 200 {object} schemas.ListPasskeysResponse
+// @Router /users/me/passkeys [get]
+func (v *PasskeyViews) ListPasskeys(c *gin.Context) {
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, schemas.ErrorResponse{
+			Error: "User not authenticated",
+		})
+		return
+	}
+
+	passkeys, err := v.service.List(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, schemas.ErrorResponse{
+			Error: fmt.Sprintf("Failed to list passkeys: %v", err),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, schemas.ListPasskeysResponse{
+		Data: passkeys,
+	})
+}
+
+// @Summary Remove a passkey
+// @Description Deletes a passkey. The last way to sign in to an account cannot be removed.
+// @Tags passkeys
+// @Produce json
+// @Param id path int true "Passkey ID"
+// @Success 200 {object} schemas.MessageResponse
+// @Failure 409 {object} schemas.ErrorResponse
+// @Router /users/me/passkeys/{id} [delete]
+func (v *PasskeyViews) DeletePasskey(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, schemas.ErrorResponse{
+			Error: "Invalid passkey ID",
+		})
+		return
+	}
+
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, schemas.ErrorResponse{
+			Error: "User not authenticated",
+		})
+		return
+	}
+
+	if err := v.service.Delete(userID, uint(id)); err != nil {
+		switch {
+		case errors.Is(err, services.ErrPasskeyNotFound):
+			c.JSON(http.StatusNotFound, schemas.ErrorResponse{
+				Error: "Passkey not found",
+			})
+		case errors.Is(err, services.ErrLastLoginMethod):
+			c.JSON(http.StatusConflict, schemas.ErrorResponse{
+				Error: "Set a password or add another sign-in method before removing this passkey",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, schemas.ErrorResponse{
+				Error: fmt.Sprintf("Failed to remove passkey: %v", err),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, schemas.MessageResponse{
+		Message: "Passkey removed",
+	})
+}
+
+// @Summary Start passkey login
+// @Description Returns the options to pass to navigator.credentials.get(). The authenticator chooses the account.
+// @Tags auth
+// @Produce json
+// @Success 200 {object} protocol.CredentialAssertion
+// @Router /auth/passkeys/login/begin [post]
+func (v *PasskeyViews) BeginLogin(c *gin.Context) {
+	assertion, err := v.service.BeginLogin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, schemas.ErrorResponse{
+			Error: fmt.Sprintf("Failed to start passkey login: %v", err),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, assertion)
+}
+
+// @Summary Finish passkey login
+// @Description Verifies the assertion signed by the authenticator and returns tokens, like /auth/login.
+// @Description Passkeys verify the user on the device, so no second factor is asked for.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param finishPasskeyLoginInput body schemas.FinishPasskeyLoginInput true "Credential"
+// @Success 200 {object} schemas.AuthResponse
+// @Failure 401 {object} schemas.ErrorResponse
+// @Failure 429 {object} schemas.ErrorResponse
+// @Router /auth/passkeys/login/finish [post]
+func (v *PasskeyViews) FinishLogin(c *gin.Context) {
+	var input schemas.FinishPasskeyLoginInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, schemas.ErrorResponse{
+			Error: fmt.Sprintf("Invalid request data: %v", err),
+		})
+		return
+	}
+
+	// The account is only known once the assertion is verified, so failures count against the IP
+	attempt := loginAttempt(c, "", nil)
+	if err := v.throttleService.Check(attempt); err != nil {
+		respondToThrottle(c, err)
+		return
+	}
+
+	user, err := v.service.FinishLogin(input.Credential, clientDevice(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidPasskeyChallenge),
+			errors.Is(err, services.ErrPasskeyVerificationFailed),
+			errors.Is(err, services.ErrPasskeyCloned):
+			if err := v.throttleService.RecordFailure(attempt); err != nil {
+				respondToThrottle(c, err)
+				return
+			}
+			c.JSON(http.StatusUnauthorized, schemas.ErrorResponse{
+				Error: "Passkey could not be verified",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, schemas.ErrorResponse{
+				Error: fmt.Sprintf("Failed to log in: %v", err),
+			})
+		}
+		return
+	}
+
+	respondWithTokens(c, http.StatusOK, *user, "Login successful")
+}
+
+// RegisterRoutes registers passkey management and login routes
+func (v *PasskeyViews) RegisterRoutes(router *gin.Engine) {
+	passkeys := router.Group("/users/me/passkeys")
+	{
+		passkeys.GET("", AuthMiddleware(), v.ListPasskeys)
+		passkeys.POST("/register/begin", AuthMiddleware(), ForbidImpersonation(), v.BeginRegistration)
+		passkeys.POST("/register/finish", AuthMiddleware(), ForbidImpersonation(), v.FinishRegistration)
+		passkeys.DELETE("/:id", AuthMiddleware(), ForbidImpersonation(), v.DeletePasskey)
+	}
+
+	auth := router.Group("/auth/passkeys")
+	{
+		auth.POST("/login/begin", v.BeginLogin)
+		auth.POST("/login/finish", v.FinishLogin)
+	}
+}
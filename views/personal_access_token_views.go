@@ -0,0 +1,154 @@
+package views
+
+import (
+	"errors"
+	"fmt"
+	"go-crud/schemas"
+	"go-crud/services"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PersonalAccessTokenViews struct {
+	service *services.PersonalAccessTokenService
+}
+
+func NewPersonalAccessTokenViews() *PersonalAccessTokenViews {
+	return &PersonalAccessTokenViews{
+		service: services.NewPersonalAccessTokenService(),
+	}
+}
+
+// @Summary Create a personal access token
+// @Description Creates a scoped token for automation. The secret is only returned in this response.
+// @Tags tokens
+// @Accept json
+// @Produce json
+// @Param tokenInput body schemas.CreatePersonalAccessTokenInput true "Token name, scopes and optional expiry"
+// @Success 201 {object} schemas.PersonalAccessTokenCreatedResponse
+// @Router /users/me/tokens [post]
+func (v *PersonalAccessTokenViews) CreateToken(c *gin.Context) {
+	var input schemas.CreatePersonalAccessTokenInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, schemas.ErrorResponse{
+			Error: fmt.Sprintf("Invalid request data: %v", err),
+		})
+		return
+	}
+
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, schemas.ErrorResponse{
+			Error: "User not authenticated",
+		})
+		return
+	}
+
+	var expiresAt *time.Time
+	if input.ExpiresInDays != nil {
+		expiry := time.Now().AddDate(0, 0, *input.ExpiresInDays)
+		expiresAt = &expiry
+	}
+
+	pat, token, err := v.service.Create(userID, input.Name, input.Scopes, expiresAt)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidScope) {
+			c.JSON(http.StatusBadRequest, schemas.ErrorResponse{
+				Error: "Invalid scope",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, schemas.ErrorResponse{
+			Error: fmt.Sprintf("Failed to create token: %v", err),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, schemas.PersonalAccessTokenCreatedResponse{
+		Data:    *pat,
+		Token:   token,
+		Message: "Copy the token now, it will not be shown again",
+	})
+}
+
+// @Summary List personal access tokens
+// @Tags tokens
+// @Produce json
+// @Success 200 {object} schemas.ListPersonalAccessTokensResponse
+// @Router /users/me/tokens [get]
+func (v *PersonalAccessTokenViews) ListTokens(c *gin.Context) {
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, schemas.ErrorResponse{
+			Error: "User not authenticated",
+		})
+		return
+	}
+
+	tokens, err := v.service.List(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, schemas.ErrorResponse{
+			Error: fmt.Sprintf("Failed to list tokens: %v", err),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, schemas.ListPersonalAccessTokensResponse{
+		Data: tokens,
+	})
+}
+
+// @Summary Revoke a personal access token
+// @Tags tokens
+// @Produce json
+// @Param id path int true "Token ID"
+// @Success 200 {object} schemas.MessageResponse
+// @Router /users/me/tokens/{id} [delete]
+func (v *PersonalAccessTokenViews) RevokeToken(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, schemas.ErrorResponse{
+			Error: "Invalid token ID",
+		})
+		return
+	}
+
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, schemas.ErrorResponse{
+			Error: "User not authenticated",
+		})
+		return
+	}
+
+	if err := v.service.Revoke(userID, uint(id)); err != nil {
+		if errors.Is(err, services.ErrPersonalAccessTokenNotFound) {
+			c.JSON(http.StatusNotFound, schemas.ErrorResponse{
+				Error: "Token not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, schemas.ErrorResponse{
+			Error: fmt.Sprintf("Failed to revoke token: %v", err),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, schemas.MessageResponse{
+		Message: "Token revoked",
+	})
+}
+
+// RegisterRoutes registers personal access token management routes.
+// Managing tokens requires a regular login; a personal access token cannot mint more tokens.
+func (v *PersonalAccessTokenViews) RegisterRoutes(router *gin.Engine) {
+	tokens := router.Group("/users/me/tokens")
+	{
+		tokens.POST("", AuthMiddleware(), ForbidImpersonation(), v.CreateToken)
+		tokens.GET("", AuthMiddleware(), v.ListTokens)
+		tokens.DELETE("/:id", AuthMiddleware(), ForbidImpersonation(), v.RevokeToken)
+	}
+}
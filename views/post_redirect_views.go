@@ -0,0 +1,160 @@
+package views
+
+import (
+	"errors"
+	"fmt"
+	"go-crud/schemas"
+	"go-crud/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PostRedirectViews struct {
+	service *services.PostRedirectService
+}
+
+func NewPostRedirectViews() *PostRedirectViews {
+	return &PostRedirectViews{
+		service: services.NewPostRedirectService(),
+	}
+}
+
+// @Summary Create a redirect
+// @Description Sends lookups of a slug to a published post. The slug must not be used by a post or another redirect.
+// @Tags redirects
+// @Accept json
+// @Produce json
+// @Param redirectInput body schemas.CreatePostRedirectInput true "Slug to redirect and the post it leads to"
+// @Success 201 {object} schemas.PostRedirectResponse
+// @Failure 403 {object} schemas.ErrorResponse
+// @Failure 409 {object} schemas.ErrorResponse
+// @Failure 422 {object} schemas.ErrorResponse
+// @Router /redirects [post]
+func (v *PostRedirectViews) CreateRedirect(c *gin.Context) {
+	var input schemas.CreatePostRedirectInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, schemas.ErrorResponse{
+			Error: fmt.Sprintf("Invalid request data: %v", err),
+		})
+		return
+	}
+
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, schemas.ErrorResponse{
+			Error: "User not authenticated",
+		})
+		return
+	}
+
+	redirect, err := v.service.Create(input.FromSlug, input.PostID, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidSlug):
+			c.JSON(http.StatusBadRequest, schemas.ErrorResponse{
+				Error: "Slug must contain letters or digits",
+			})
+		case errors.Is(err, services.ErrSlugTaken), errors.Is(err, services.ErrRedirectExists):
+			c.JSON(http.StatusConflict, schemas.ErrorResponse{
+				Error: err.Error(),
+			})
+		case errors.Is(err, services.ErrRedirectTargetUnavailable):
+			c.JSON(http.StatusUnprocessableEntity, schemas.ErrorResponse{
+				Error: err.Error(),
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, schemas.ErrorResponse{
+				Error: fmt.Sprintf("Failed to create redirect: %v", err),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, schemas.PostRedirectResponse{
+		Data:    *redirect,
+		Message: "Redirect created successfully",
+	})
+}
+
+// @Summary List redirects
+// @Description Lists manual redirects and the old slugs of renamed posts, most recent first
+// @Tags redirects
+// @Produce json
+// @Param post_id query int false "Only redirects to this post"
+// @Success 200 {object} schemas.ListPostRedirectsResponse
+// @Failure 403 {object} schemas.ErrorResponse
+// @Router /redirects [get]
+func (v *PostRedirectViews) ListRedirects(c *gin.Context) {
+	var postID *uint
+	if param := c.Query("post_id"); param != "" {
+		id, err := strconv.ParseUint(param, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, schemas.ErrorResponse{
+				Error: "Invalid post ID",
+			})
+			return
+		}
+		filter := uint(id)
+		postID = &filter
+	}
+
+	redirects, err := v.service.List(postID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, schemas.ErrorResponse{
+			Error: fmt.Sprintf("Failed to list redirects: %v", err),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, schemas.ListPostRedirectsResponse{
+		Data: redirects,
+	})
+}
+
+// @Summary Delete a redirect
+// @Description Deletes a manual redirect or the redirect kept for an old slug. Lookups of the slug then return 404.
+// @Tags redirects
+// @Produce json
+// @Param id path int true "Redirect ID"
+// @Success 200 {object} schemas.MessageResponse
+// @Failure 403 {object} schemas.ErrorResponse
+// @Failure 404 {object} schemas.ErrorResponse
+// @Router /redirects/{id} [delete]
+func (v *PostRedirectViews) DeleteRedirect(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, schemas.ErrorResponse{
+			Error: "Invalid redirect ID",
+		})
+		return
+	}
+
+	if err := v.service.Delete(uint(id)); err != nil {
+		if errors.Is(err, services.ErrRedirectNotFound) {
+			c.JSON(http.StatusNotFound, schemas.ErrorResponse{
+				Error: "Redirect not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, schemas.ErrorResponse{
+			Error: fmt.Sprintf("Failed to delete redirect: %v", err),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, schemas.MessageResponse{
+		Message: "Redirect deleted successfully",
+	})
+}
+
+// RegisterRoutes registers redirect management routes
+func (v *PostRedirectViews) RegisterRoutes(router *gin.Engine) {
+	redirects := router.Group("/redirects")
+	{
+		redirects.POST("", AuthMiddleware(services.ScopePostsWrite), RequirePermission(services.PermRedirectsManage), v.CreateRedirect)
+		redirects.GET("", AuthMiddleware(), RequirePermission(services.PermRedirectsManage), v.ListRedirects)
+		redirects.DELETE("/:id", AuthMiddleware(services.ScopePostsWrite), RequirePermission(services.PermRedirectsManage), v.DeleteRedirect)
+	}
+}
@@ -0,0 +1,234 @@
+package views
+
+import (
+	"errors"
+	"fmt"
+	"go-crud/schemas"
+	"go-crud/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PostRevisionViews struct {
+	service     *services.PostRevisionService
+	postService *services.PostService
+}
+
+func NewPostRevisionViews() *PostRevisionViews {
+	return &PostRevisionViews{
+		service:     services.NewPostRevisionService(),
+		postService: services.NewPostService(),
+	}
+}
+
+// @Summary List post revisions
+// @Description Lists the revisions of a post, latest first, without their content. Only those who can edit the post may see them.
+// @Tags posts
+// @Produce json
+// @Param id path int true "Post ID"
+// @Success 200 {object} schemas.ListPostRevisionsResponse
+// @Failure 403 {object} schemas.ErrorResponse
+// @Failure 404 {object} schemas.ErrorResponse
+// @Router /posts/{id}/revisions [get]
+func (v *PostRevisionViews) ListRevisions(c *gin.Context) {
+	postID, ok := v.editablePost(c)
+	if !ok {
+		return
+	}
+
+	revisions, err := v.service.List(postID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, schemas.ErrorResponse{
+			Error: fmt.Sprintf("Failed to list revisions: %v", err),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, schemas.ListPostRevisionsResponse{
+		Data: revisions,
+	})
+}
+
+// @Summary Get a post revision
+// @Description Returns a revision of a post with its title and content in both formats
+// @Tags posts
+// @Produce json
+// @Param id path int true "Post ID"
+// @Param number path int true "Revision number"
+// @Success 200 {object} schemas.PostRevisionResponse
+// @Failure 403 {object} schemas.ErrorResponse
+// @Failure 404 {object} schemas.ErrorResponse
+// @Router /posts/{id}/revisions/{number} [get]
+func (v *PostRevisionViews) GetRevision(c *gin.Context) {
+	postID, ok := v.editablePost(c)
+	if !ok {
+		return
+	}
+	number, ok := revisionNumber(c)
+	if !ok {
+		return
+	}
+
+	revision, err := v.service.Get(postID, number)
+	if err != nil {
+		respondToRevisionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, schemas.PostRevisionResponse{
+		Data: *revision,
+	})
+}
+
+// @Summary Diff two post revisions
+// @Description Compares the markdown of two revisions line by line. Lines only in "from" are deletions, lines only in "to" insertions.
+// @Tags posts
+// @Produce json
+// @Param id path int true "Post ID"
+// @Param from query int true "Revision number to compare from"
+// @Param to query int true "Revision number to compare to"
+// @Success 200 {object} schemas.PostRevisionDiffResponse
+// @Failure 403 {object} schemas.ErrorResponse
+// @Failure 404 {object} schemas.ErrorResponse
+// @Router /posts/{id}/revisions/diff [get]
+func (v *PostRevisionViews) DiffRevisions(c *gin.Context) {
+	postID, ok := v.editablePost(c)
+	if !ok {
+		return
+	}
+
+	var query schemas.PostRevisionDiffQueryParams
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, schemas.ErrorResponse{
+			Error: fmt.Sprintf("Invalid query params: %v", err),
+		})
+		return
+	}
+
+	lines, err := v.service.Diff(postID, query.From, query.To)
+	if err != nil {
+		respondToRevisionError(c, err)
+		return
+	}
+
+	response := schemas.PostRevisionDiffResponse{
+		From:  query.From,
+		To:    query.To,
+		Lines: lines,
+	}
+	for _, line := range lines {
+		switch line.Type {
+		case services.DiffInsert:
+			response.Additions++
+		case services.DiffDelete:
+			response.Deletions++
+		}
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// @Summary Restore a post revision
+// @Description Brings back the title and content of a revision. The post is saved as a new revision; later revisions are kept.
+// @Tags posts
+// @Produce json
+// @Param id path int true "Post ID"
+// @Param number path int true "Revision number"
+// @Success 200 {object} schemas.PostResponse
+// @Failure 403 {object} schemas.ErrorResponse
+// @Failure 404 {object} schemas.ErrorResponse
+// @Router /posts/{id}/revisions/{number}/restore [post]
+func (v *PostRevisionViews) RestoreRevision(c *gin.Context) {
+	postID, ok := v.editablePost(c)
+	if !ok {
+		return
+	}
+	number, ok := revisionNumber(c)
+	if !ok {
+		return
+	}
+
+	userID, _ := GetUserIDFromContext(c)
+	post, err := v.service.Restore(postID, number, userID)
+	if err != nil {
+		respondToRevisionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, schemas.PostResponse{
+		Data:    *post,
+		Message: fmt.Sprintf("Revision %d restored", number),
+	})
+}
+
+// editablePost checks that the post in the path exists and that the authenticated user may edit it,
+// answering the request otherwise
+func (v *PostRevisionViews) editablePost(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, schemas.ErrorResponse{
+			Error: "Invalid ID format",
+		})
+		return 0, false
+	}
+
+	actor, exists := GetActorFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, schemas.ErrorResponse{
+			Error: "User not authenticated",
+		})
+		return 0, false
+	}
+
+	post, err := v.postService.GetByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, schemas.ErrorResponse{
+			Error: "Post not found",
+		})
+		return 0, false
+	}
+
+	if !services.CanUpdatePost(actor, *post) {
+		c.JSON(http.StatusForbidden, schemas.ErrorResponse{
+			Error: "You can only see the revisions of posts you can edit",
+		})
+		return 0, false
+	}
+	return post.ID, true
+}
+
+// revisionNumber parses the revision number in the path, answering the request when it is invalid
+func revisionNumber(c *gin.Context) (int, bool) {
+	number, err := strconv.Atoi(c.Param("number"))
+	if err != nil || number < 1 {
+		c.JSON(http.StatusBadRequest, schemas.ErrorResponse{
+			Error: "Invalid revision number",
+		})
+		return 0, false
+	}
+	return number, true
+}
+
+func respondToRevisionError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrRevisionNotFound) {
+		c.JSON(http.StatusNotFound, schemas.ErrorResponse{
+			Error: "Revision not found",
+		})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, schemas.ErrorResponse{
+		Error: fmt.Sprintf("Failed to load revision: %v", err),
+	})
+}
+
+// RegisterRoutes registers post revision routes
+func (v *PostRevisionViews) RegisterRoutes(router *gin.Engine) {
+	revisions := router.Group("/posts/:id/revisions")
+	{
+		revisions.GET("", AuthMiddleware(services.ScopeRead, services.ScopePostsWrite), v.ListRevisions)
+		revisions.GET("/diff", AuthMiddleware(services.ScopeRead, services.ScopePostsWrite), v.DiffRevisions)
+		revisions.GET("/:number", AuthMiddleware(services.ScopeRead, services.ScopePostsWrite), v.GetRevision)
+		revisions.POST("/:number/restore", AuthMiddleware(services.ScopePostsWrite), v.RestoreRevision)
+	}
+}
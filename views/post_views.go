@@ -1,6 +1,7 @@
 package views
 
 import (
+	"errors"
 	"fmt"
 	// "go-crud/models"
 	"go-crud/schemas"
@@ -13,19 +14,26 @@ import (
 )
 
 type PostViews struct {
-	service *services.PostService
+	service         *services.PostService
+	redirectService *services.PostRedirectService
+	searchService   *services.PostSearchService
 }
 
 func NewPostViews() *PostViews {
 	return &PostViews{
-		service: services.NewPostService(),
+		service:         services.NewPostService(),
+		redirectService: services.NewPostRedirectService(),
+		searchService:   services.NewPostSearchService(),
 	}
 }
 
 // @Summary Create post
+// @Description The slug is generated from the title, with a numeric suffix when it is taken, unless one is given.
+// @Description Scheduled posts need publish_at and are published when it is reached; unpublish_at puts the post back to draft.
 // @Tags posts
 // @Param post body schemas.CreatePostRequest true "Post data"
 // @Success 201 {object} schemas.PostResponse
+// @Failure 409 {object} schemas.ErrorResponse
 // @Router /posts [post]
 func (v *PostViews) CreatePost(c *gin.Context) {
 	var input schemas.CreatePostRequest
@@ -50,6 +58,9 @@ func (v *PostViews) CreatePost(c *gin.Context) {
 
 	result, err := v.service.Create(postModel, input.TagNames)
 	if err != nil {
+		if respondToInvalidPost(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, schemas.ErrorResponse{
 			Error: fmt.Sprintf("Failed to create post: %v", err),
 		})
@@ -65,8 +76,11 @@ func (v *PostViews) CreatePost(c *gin.Context) {
 
 // @Summary List posts
 // @Tags posts
+// @Description Status filters on the effective status: scheduled posts whose publish_at has passed count as published,
+// @Description and posts whose unpublish_at has passed as drafts, even before the scheduler has updated them.
 // @Param page query int false "Page number" default(1)
 // @Param limit query int false "Items per page" default(10)
+// @Param status query string false "Effective status" Enums(draft, published, scheduled) default(published)
 // @Success 200 {object} schemas.ListPostsResponse
 // @Router /posts [get]
 func (v *PostViews) ListPosts(c *gin.Context) {
@@ -84,21 +98,8 @@ func (v *PostViews) ListPosts(c *gin.Context) {
 	query.SetDefaults()
 
 	// Handle tag filtering
-	if tagsParam := c.Query("tags"); tagsParam != "" {
-		// Split comma-separated tag names
-		tagNames := strings.Split(tagsParam, ",")
-		// Trim spaces and filter out empty strings
-		for i, tag := range tagNames {
-			tagNames[i] = strings.TrimSpace(tag)
-		}
-		// Filter out empty tag names
-		var filteredTags []string
-		for _, tag := range tagNames {
-			if tag != "" {
-				filteredTags = append(filteredTags, tag)
-			}
-		}
-		query.TagNames = filteredTags
+	if tagNames := tagNamesFromQuery(c); tagNames != nil {
+		query.TagNames = tagNames
 	}
 
 	results, total, err := v.service.GetWithPagination(query)
@@ -118,6 +119,58 @@ func (v *PostViews) ListPosts(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// @Summary Search posts
+// @Description Full-text search over titles and markdown content, best matches first; title matches rank higher.
+// @Description All words must match, in any grammatical form. "Quoted words" must appear together in that order,
+// @Description and a word ending with * matches any word starting with it. Matches are wrapped in <mark></mark>
+// @Description in title_highlight and snippet, whose text is otherwise HTML-escaped. Filters work as on GET /posts.
+// @Tags posts
+// @Param q query string true "Search query"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Param user_id query int false "Author ID"
+// @Param status query string false "Effective status" Enums(draft, published, scheduled) default(published)
+// @Param tags query string false "Comma-separated tag names"
+// @Success 200 {object} schemas.SearchPostsResponse
+// @Failure 400 {object} schemas.ErrorResponse
+// @Router /posts/search [get]
+func (v *PostViews) SearchPosts(c *gin.Context) {
+	var query schemas.SearchPostsQueryParams
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, schemas.ErrorResponse{
+			Error: fmt.Sprintf("Invalid query params: %v", err),
+		})
+		return
+	}
+	query.SetDefaults()
+	if tagNames := tagNamesFromQuery(c); tagNames != nil {
+		query.TagNames = tagNames
+	}
+
+	results, total, err := v.searchService.Search(query)
+	if err != nil {
+		if errors.Is(err, services.ErrEmptySearchQuery) {
+			c.JSON(http.StatusBadRequest, schemas.ErrorResponse{
+				Error: "Search query must contain letters or digits",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, schemas.ErrorResponse{
+			Error: fmt.Sprintf("Failed to search posts: %v", err),
+		})
+		return
+	}
+
+	response := schemas.SearchPostsResponse{
+		Data:  results,
+		Query: query.Query,
+		Limit: query.Limit,
+		Page:  query.Page,
+		Total: int(total),
+	}
+	c.JSON(http.StatusOK, response)
+}
+
 // @Summary Get post
 // @Tags posts
 // @Param id path int true "Post ID"
@@ -146,6 +199,34 @@ func (v *PostViews) GetPost(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// @Summary Get post by slug
+// @Description Old slugs of renamed posts and manual redirects answer 301 with the post's current location.
+// @Tags posts
+// @Param slug path string true "Post slug"
+// @Success 200 {object} schemas.PostResponse
+// @Success 301 "Location: /posts/by-slug/{current slug}"
+// @Failure 404 {object} schemas.ErrorResponse
+// @Router /posts/by-slug/{slug} [get]
+func (v *PostViews) GetPostBySlug(c *gin.Context) {
+	slug := c.Param("slug")
+	result, err := v.service.GetBySlug(slug)
+	if err != nil {
+		if target, redirectErr := v.redirectService.Resolve(slug); redirectErr == nil {
+			c.Redirect(http.StatusMovedPermanently, "/posts/by-slug/"+target.Slug)
+			return
+		}
+		c.JSON(http.StatusNotFound, schemas.ErrorResponse{
+			Error: fmt.Sprintf("Post not found: %v", err),
+		})
+		return
+	}
+
+	response := schemas.PostResponse{
+		Data: *result,
+	}
+	c.JSON(http.StatusOK, response)
+}
+
 // @Summary Update post
 // @Tags posts
 // @Param id path int true "Post ID"
@@ -161,8 +242,8 @@ func (v *PostViews) UpdatePost(c *gin.Context) {
 		return
 	}
 
-	// Check if post exists and belongs to authenticated user
-	authenticatedUserID, exists := GetUserIDFromContext(c)
+	// Check if post exists and the authenticated user may act on it
+	actor, exists := GetActorFromContext(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, schemas.ErrorResponse{
 			Error: "User not authenticated",
@@ -178,7 +259,7 @@ func (v *PostViews) UpdatePost(c *gin.Context) {
 		return
 	}
 
-	if post.UserID != authenticatedUserID {
+	if !services.CanUpdatePost(actor, *post) {
 		c.JSON(http.StatusForbidden, schemas.ErrorResponse{
 			Error: "You can only update your own posts",
 		})
@@ -193,8 +274,11 @@ func (v *PostViews) UpdatePost(c *gin.Context) {
 		return
 	}
 
-	result, err := v.service.Update(uint(id), input.ToModel())
+	result, err := v.service.Update(uint(id), input.ToModel(), actor.UserID)
 	if err != nil {
+		if respondToInvalidPost(c, err) {
+			return
+		}
 		c.JSON(http.StatusNotFound, schemas.ErrorResponse{
 			Error: fmt.Sprintf("Failed to update post: %v", err),
 		})
@@ -209,6 +293,7 @@ func (v *PostViews) UpdatePost(c *gin.Context) {
 }
 
 // @Summary Patch post
+// @Description Changing the title keeps the slug; send slug to change it.
 // @Tags posts
 // @Param id path int true "Post ID"
 // @Param post body schemas.PatchPostRequest true "Patch data"
@@ -223,8 +308,8 @@ func (v *PostViews) PartialUpdatePost(c *gin.Context) {
 		return
 	}
 
-	// Check if post exists and belongs to authenticated user
-	authenticatedUserID, exists := GetUserIDFromContext(c)
+	// Check if post exists and the authenticated user may act on it
+	actor, exists := GetActorFromContext(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, schemas.ErrorResponse{
 			Error: "User not authenticated",
@@ -240,7 +325,7 @@ func (v *PostViews) PartialUpdatePost(c *gin.Context) {
 		return
 	}
 
-	if post.UserID != authenticatedUserID {
+	if !services.CanUpdatePost(actor, *post) {
 		c.JSON(http.StatusForbidden, schemas.ErrorResponse{
 			Error: "You can only update your own posts",
 		})
@@ -269,8 +354,11 @@ func (v *PostViews) PartialUpdatePost(c *gin.Context) {
 		return
 	}
 
-	result, err := v.service.PartialUpdate(uint(id), input.ToMap())
+	result, err := v.service.PartialUpdate(uint(id), input.ToMap(), actor.UserID)
 	if err != nil {
+		if respondToInvalidPost(c, err) {
+			return
+		}
 		statusCode := http.StatusInternalServerError
 		if err.Error() == "post not found" {
 			statusCode = http.StatusNotFound
@@ -305,8 +393,8 @@ func (v *PostViews) DeletePost(c *gin.Context) {
 		return
 	}
 
-	// Check if post exists and belongs to authenticated user
-	authenticatedUserID, exists := GetUserIDFromContext(c)
+	// Check if post exists and the authenticated user may act on it
+	actor, exists := GetActorFromContext(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, schemas.ErrorResponse{
 			Error: "User not authenticated",
@@ -322,7 +410,7 @@ func (v *PostViews) DeletePost(c *gin.Context) {
 		return
 	}
 
-	if post.UserID != authenticatedUserID {
+	if !services.CanDeletePost(actor, *post) {
 		c.JSON(http.StatusForbidden, schemas.ErrorResponse{
 			Error: "You can only delete your own posts",
 		})
@@ -343,14 +431,62 @@ func (v *PostViews) DeletePost(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// tagNamesFromQuery returns the tag names of the comma-separated tags query parameter, or nil when
+// it is not set
+func tagNamesFromQuery(c *gin.Context) []string {
+	tagsParam := c.Query("tags")
+	if tagsParam == "" {
+		return nil
+	}
+
+	// Split comma-separated tag names
+	tagNames := strings.Split(tagsParam, ",")
+	// Trim spaces and filter out empty strings
+	for i, tag := range tagNames {
+		tagNames[i] = strings.TrimSpace(tag)
+	}
+	// Filter out empty tag names
+	var filteredTags []string
+	for _, tag := range tagNames {
+		if tag != "" {
+			filteredTags = append(filteredTags, tag)
+		}
+	}
+	return filteredTags
+}
+
+// respondToInvalidPost answers a slug or publishing times chosen by the author that cannot be used,
+// and reports whether it did
+func respondToInvalidPost(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, services.ErrPublishAtRequired), errors.Is(err, services.ErrInvalidUnpublishAt):
+		c.JSON(http.StatusBadRequest, schemas.ErrorResponse{
+			Error: err.Error(),
+		})
+	case errors.Is(err, services.ErrSlugTaken):
+		c.JSON(http.StatusConflict, schemas.ErrorResponse{
+			Error: "Slug is already used by another post",
+		})
+	case errors.Is(err, services.ErrInvalidSlug):
+		c.JSON(http.StatusBadRequest, schemas.ErrorResponse{
+			Error: "Slug must contain letters or digits",
+		})
+	default:
+		return false
+	}
+	return true
+}
+
 func (v *PostViews) RegisterRoutes(router *gin.Engine) {
 	posts := router.Group("/posts")
 	{
-		posts.POST("", AuthMiddleware(), v.CreatePost)
+		posts.POST("", AuthMiddleware(services.ScopePostsWrite), RequirePermission(services.PermPostsCreate), RequireVerifiedEmail(), v.CreatePost)
 		posts.GET("", v.ListPosts)
+		posts.GET("/search", v.SearchPosts)
+		posts.GET("/by-slug/:slug", v.GetPostBySlug)
 		posts.GET("/:id", v.GetPost)
-		posts.PUT("/:id", AuthMiddleware(), v.UpdatePost)
-		posts.PATCH("/:id", AuthMiddleware(), v.PartialUpdatePost)
-		posts.DELETE("/:id", AuthMiddleware(), v.DeletePost)
+		posts.PUT("/:id", AuthMiddleware(services.ScopePostsWrite), v.UpdatePost)
+		posts.PATCH("/:id", AuthMiddleware(services.ScopePostsWrite), v.PartialUpdatePost)
+		posts.DELETE("/:id", AuthMiddleware(services.ScopePostsWrite), v.DeletePost)
 	}
 }
@@ -0,0 +1,109 @@
+package views
+
+import (
+	"errors"
+	"fmt"
+	"go-crud/schemas"
+	"go-crud/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SessionViews struct {
+	service *services.SessionService
+}
+
+func NewSessionViews() *SessionViews {
+	return &SessionViews{
+		service: services.NewSessionService(),
+	}
+}
+
+// @Summary List active sessions
+// @Description Lists the devices the user is signed in on. The session of the current token is flagged with current.
+// @Tags sessions
+// @Produce json
+// @Success 200 {object} schemas.ListSessionsResponse
+// @Router /users/me/sessions [get]
+func (v *SessionViews) ListSessions(c *gin.Context) {
+	claims, exists := GetClaimsFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, schemas.ErrorResponse{
+			Error: "User not authenticated",
+		})
+		return
+	}
+
+	sessions, err := v.service.List(claims.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, schemas.ErrorResponse{
+			Error: fmt.Sprintf("Failed to list sessions: %v", err),
+		})
+		return
+	}
+
+	data := make([]schemas.SessionInfo, 0, len(sessions))
+	for _, session := range sessions {
+		data = append(data, schemas.SessionInfo{
+			Session: session,
+			Current: session.ID == claims.SessionID,
+		})
+	}
+
+	c.JSON(http.StatusOK, schemas.ListSessionsResponse{
+		Data: data,
+	})
+}
+
+// @Summary Revoke a session
+// @Description Signs the device out: the session's refresh tokens and access tokens stop working
+// @Tags sessions
+// @Produce json
+// @Param id path int true "Session ID"
+// @Success 200 {object} schemas.MessageResponse
+// @Router /users/me/sessions/{id} [delete]
+func (v *SessionViews) RevokeSession(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, schemas.ErrorResponse{
+			Error: "Invalid session ID",
+		})
+		return
+	}
+
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, schemas.ErrorResponse{
+			Error: "User not authenticated",
+		})
+		return
+	}
+
+	if err := v.service.Revoke(userID, uint(id)); err != nil {
+		if errors.Is(err, services.ErrSessionNotFound) {
+			c.JSON(http.StatusNotFound, schemas.ErrorResponse{
+				Error: "Session not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, schemas.ErrorResponse{
+			Error: fmt.Sprintf("Failed to revoke session: %v", err),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, schemas.MessageResponse{
+		Message: "Session revoked",
+	})
+}
+
+// RegisterRoutes registers session management routes
+func (v *SessionViews) RegisterRoutes(router *gin.Engine) {
+	sessions := router.Group("/users/me/sessions")
+	{
+		sessions.GET("", AuthMiddleware(), v.ListSessions)
+		sessions.DELETE("/:id", AuthMiddleware(), ForbidImpersonation(), v.RevokeSession)
+	}
+}
@@ -266,12 +266,12 @@ func (v *TagViews) DeleteTag(c *gin.Context) {
 func (v *TagViews) RegisterRoutes(router *gin.Engine) {
 	tags := router.Group("/tags")
 	{
-		tags.POST("", AuthMiddleware(), v.CreateTag)
+		tags.POST("", AuthMiddleware(services.ScopeTagsWrite), RequirePermission(services.PermTagsCreate), v.CreateTag)
 		tags.GET("", v.ListTags)
 		tags.GET("/popular", v.GetPopularTags)
 		tags.GET("/search", v.SearchTags)
 		tags.GET("/:id", v.GetTag)
-		tags.PUT("/:id", AuthMiddleware(), v.UpdateTag)
-		tags.DELETE("/:id", AuthMiddleware(), v.DeleteTag)
+		tags.PUT("/:id", AuthMiddleware(services.ScopeTagsWrite), RequirePermission(services.PermTagsUpdate), v.UpdateTag)
+		tags.DELETE("/:id", AuthMiddleware(services.ScopeTagsWrite), RequirePermission(services.PermTagsDelete), v.DeleteTag)
 	}
 }
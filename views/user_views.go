@@ -1,10 +1,13 @@
 package views
 
 import (
+	"errors"
 	"fmt"
 	"go-crud/models"
 	"go-crud/schemas"
 	"go-crud/services"
+	"io"
+	"log"
 	"net/http"
 	"strconv"
 
@@ -12,19 +15,27 @@ import (
 )
 
 type UserViews struct {
-	service *services.UserService
+	service         *services.UserService
+	throttleService *services.LoginThrottleService
+	deletionService *services.AccountDeletionService
 }
 
 func NewUserViews() *UserViews {
 	return &UserViews{
-		service: services.NewUserService(),
+		service:         services.NewUserService(),
+		throttleService: services.NewLoginThrottleService(),
+		deletionService: services.NewAccountDeletionService(),
 	}
 }
 
 // @Summary Create user
+// @Description Signs up a new user. Depending on REGISTRATION_MODE, an invite code or an allowed email domain may be required.
 // @Tags users
 // @Param user body schemas.CreateUserInput true "User data"
 // @Success 201 {object} schemas.AuthResponse
+// @Failure 400 {object} schemas.PasswordPolicyErrorResponse
+// @Failure 403 {object} schemas.ErrorResponse
+// @Failure 409 {object} schemas.ErrorResponse
 // @Router /users [post]
 func (v *UserViews) CreateUser(c *gin.Context) {
 	var input schemas.CreateUserInput
@@ -35,35 +46,34 @@ func (v *UserViews) CreateUser(c *gin.Context) {
 		return
 	}
 
-	result, err := v.service.Create(models.User{
+	result, err := v.service.Register(models.User{
 		Name:           input.Name,
 		Email:          input.Email,
 		HashedPassword: input.Password,
-	})
+	}, input.InviteCode)
 	if err != nil {
+		if respondToPasswordPolicy(c, err) || respondToRegistrationPolicy(c, err) {
+			return
+		}
+		if errors.Is(err, services.ErrEmailTaken) {
+			c.JSON(http.StatusConflict, schemas.ErrorResponse{
+				Error: "Email is already in use",
+			})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, schemas.ErrorResponse{
 			Error: fmt.Sprintf("Failed to create user: %v", err),
 		})
 		return
 	}
 
-	// Generate JWT token for newly registered user
-	token, err := services.GenerateToken(*result)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, schemas.ErrorResponse{
-			Error: fmt.Sprintf("Failed to generate token: %v", err),
-		})
-		return
+	// Ask the user to confirm their address; signup still succeeds if the email cannot be sent
+	if err := services.NewEmailVerificationService().SendVerification(*result); err != nil {
+		log.Printf("[MAILER] Failed to send verification email to user %d: %v", result.ID, err)
 	}
 
-	response := schemas.AuthResponse{
-		Token: token,
-		UserResponse: schemas.UserResponse{
-			Data:    *result,
-			Message: "User created successfully",
-		},
-	}
-	c.JSON(http.StatusCreated, response)
+	// Log the newly registered user straight in
+	respondWithTokens(c, http.StatusCreated, *result, "User created successfully")
 }
 
 // @Summary Get user by ID
@@ -144,6 +154,12 @@ func (v *UserViews) PartialUpdateUser(c *gin.Context) {
 		})
 		return
 	}
+	if input.Email != nil || input.Password != nil {
+		c.JSON(http.StatusBadRequest, schemas.ErrorResponse{
+			Error: "Use POST /users/me/email or POST /users/me/password to change credentials",
+		})
+		return
+	}
 
 	result, err := v.service.PartialUpdate(uint(id), input)
 	if err != nil {
@@ -166,10 +182,193 @@ func (v *UserViews) PartialUpdateUser(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// @Summary Change password
+// @Description Requires the current password. Every existing session is signed out and a new one is started for this client.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param passwordInput body schemas.ChangePasswordInput true "Current and new password"
+// @Success 200 {object} schemas.AuthResponse
+// @Failure 400 {object} schemas.PasswordPolicyErrorResponse
+// @Router /users/me/password [post]
+func (v *UserViews) ChangePassword(c *gin.Context) {
+	var input schemas.ChangePasswordInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, schemas.ErrorResponse{
+			Error: fmt.Sprintf("Invalid request data: %v", err),
+		})
+		return
+	}
+
+	user, ok := v.reauthenticate(c)
+	if !ok {
+		return
+	}
+
+	result, err := v.service.ChangePassword(user.ID, input.CurrentPassword, input.NewPassword)
+	if err != nil {
+		v.respondToCredentialChangeError(c, *user, err)
+		return
+	}
+
+	if err := v.throttleService.RecordSuccess(loginAttempt(c, user.Email, &user.ID)); err != nil {
+		log.Printf("[THROTTLE] Failed to clear login failures of user %d: %v", user.ID, err)
+	}
+
+	respondWithTokens(c, http.StatusOK, *result, "Password changed successfully")
+}
+
+// @Summary Change email address
+// @Description Requires the current password. The new address has to be verified again, and the old one is notified.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param emailInput body schemas.ChangeEmailInput true "Current password and new email"
+// @Success 200 {object} schemas.UserResponse
+// @Router /users/me/email [post]
+func (v *UserViews) ChangeEmail(c *gin.Context) {
+	var input schemas.ChangeEmailInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, schemas.ErrorResponse{
+			Error: fmt.Sprintf("Invalid request data: %v", err),
+		})
+		return
+	}
+
+	user, ok := v.reauthenticate(c)
+	if !ok {
+		return
+	}
+
+	result, err := v.service.ChangeEmail(user.ID, input.Password, input.NewEmail)
+	if err != nil {
+		if errors.Is(err, services.ErrEmailTaken) {
+			c.JSON(http.StatusConflict, schemas.ErrorResponse{
+				Error: "Email is already in use",
+			})
+			return
+		}
+		v.respondToCredentialChangeError(c, *user, err)
+		return
+	}
+
+	if err := v.throttleService.RecordSuccess(loginAttempt(c, user.Email, &user.ID)); err != nil {
+		log.Printf("[THROTTLE] Failed to clear login failures of user %d: %v", user.ID, err)
+	}
+
+	c.JSON(http.StatusOK, schemas.UserResponse{
+		Data:    *result,
+		Message: "Email changed, check your inbox to verify the new address",
+	})
+}
+
+// reauthenticate loads the authenticated user and makes sure they are not locked out, since
+// credential changes check the password just like a login does
+func (v *UserViews) reauthenticate(c *gin.Context) (*models.User, bool) {
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, schemas.ErrorResponse{
+			Error: "User not authenticated",
+		})
+		return nil, false
+	}
+
+	user, err := v.service.GetByID(userID)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, schemas.ErrorResponse{
+			Error: "User not authenticated",
+		})
+		return nil, false
+	}
+
+	if err := v.throttleService.Check(loginAttempt(c, user.Email, &user.ID)); err != nil {
+		respondToThrottle(c, err)
+		return nil, false
+	}
+	return user, true
+}
+
+// respondToCredentialChangeError answers the errors shared by the credential change endpoints.
+// Wrong passwords count towards the login lockout.
+func (v *UserViews) respondToCredentialChangeError(c *gin.Context, user models.User, err error) {
+	switch {
+	case errors.Is(err, services.ErrIncorrectPassword):
+		if err := v.throttleService.RecordFailure(loginAttempt(c, user.Email, &user.ID)); err != nil {
+			respondToThrottle(c, err)
+			return
+		}
+		c.JSON(http.StatusForbidden, schemas.ErrorResponse{
+			Error: "Current password is incorrect",
+			Code:  IncorrectPasswordCode,
+		})
+	case errors.Is(err, services.ErrPasswordNotSet):
+		c.JSON(http.StatusBadRequest, schemas.ErrorResponse{
+			Error: "Your account has no password yet; set one through the password reset flow",
+		})
+	default:
+		if respondToPasswordPolicy(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, schemas.ErrorResponse{
+			Error: fmt.Sprintf("Failed to update credentials: %v", err),
+		})
+	}
+}
+
+// respondToPasswordPolicy answers password policy violations with a structured 400 and reports whether it did
+func respondToPasswordPolicy(c *gin.Context, err error) bool {
+	var policyErr *services.PasswordPolicyError
+	if !errors.As(err, &policyErr) {
+		return false
+	}
+
+	c.JSON(http.StatusBadRequest, schemas.PasswordPolicyErrorResponse{
+		Error:      "Password does not meet the password policy",
+		Code:       WeakPasswordCode,
+		Violations: policyErr.Violations,
+	})
+	return true
+}
+
+// respondToRegistrationPolicy writes the response for a signup refused by the registration
+// policy and reports whether it did
+func respondToRegistrationPolicy(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, services.ErrRegistrationClosed):
+		c.JSON(http.StatusForbidden, schemas.ErrorResponse{
+			Error: "Registration is closed",
+			Code:  RegistrationClosedCode,
+		})
+	case errors.Is(err, services.ErrInviteRequired):
+		c.JSON(http.StatusForbidden, schemas.ErrorResponse{
+			Error: "An invite code is required to register",
+			Code:  InviteRequiredCode,
+		})
+	case errors.Is(err, services.ErrEmailDomainNotAllowed):
+		c.JSON(http.StatusForbidden, schemas.ErrorResponse{
+			Error: "Registration is not open to this email domain",
+			Code:  EmailDomainNotAllowedCode,
+		})
+	case errors.Is(err, services.ErrInvalidInvite):
+		c.JSON(http.StatusBadRequest, schemas.ErrorResponse{
+			Error: "Invite code is invalid, expired or used up",
+			Code:  InvalidInviteCode,
+		})
+	default:
+		return false
+	}
+	return true
+}
+
 // @Summary Delete user
+// @Description Schedules the account for deletion after ACCOUNT_DELETION_GRACE_PERIOD, during which it can be cancelled.
+// @Description The posts of the account are deleted, reassigned to a "Deleted user" placeholder, or (admins only) transferred to another author.
 // @Tags users
+// @Accept json
 // @Param id path int true "User ID"
-// @Success 200 {object} schemas.MessageResponse
+// @Param deleteInput body schemas.DeleteUserInput false "What happens to the user's posts"
+// @Success 202 {object} schemas.AccountDeletionResponse
+// @Failure 409 {object} schemas.ErrorResponse
 // @Router /users/{id} [delete]
 func (v *UserViews) DeleteUser(c *gin.Context) {
 	idParam := c.Param("id")
@@ -196,30 +395,198 @@ func (v *UserViews) DeleteUser(c *gin.Context) {
 		return
 	}
 
-	// Then check if user is authorized to delete this account
-	authenticatedUserID, exists := GetUserIDFromContext(c)
-	if !exists || authenticatedUserID != uint(id) {
+	// Then check if user is authorized to delete this account (admins may delete anyone)
+	actor, exists := GetActorFromContext(c)
+	if !exists || (actor.UserID != uint(id) && !actor.Can(services.PermUsersDelete)) {
 		c.JSON(http.StatusForbidden, schemas.ErrorResponse{
 			Error: "You can only delete your own account",
 		})
 		return
 	}
 
-	if err := v.service.Delete(uint(id)); err != nil {
-		if err.Error() == "user not found" {
+	// The body is optional
+	var input schemas.DeleteUserInput
+	if err := c.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, schemas.ErrorResponse{
+			Error: fmt.Sprintf("Invalid request data: %v", err),
+		})
+		return
+	}
+
+	// Handing posts to someone else needs their consent, which only admins can vouch for
+	policy := models.DeletedPostsPolicy(input.Posts)
+	if policy == models.TransferPosts && !actor.Can(services.PermUsersDelete) {
+		c.JSON(http.StatusForbidden, schemas.ErrorResponse{
+			Error: "Only admins can transfer posts to another author",
+			Code:  PermissionDeniedCode,
+		})
+		return
+	}
+
+	deletion, err := v.deletionService.Schedule(uint(id), actor.UserID, policy, input.TransferToID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrAccountDeletionScheduled):
+			c.JSON(http.StatusConflict, schemas.ErrorResponse{
+				Error: "Account deletion is already scheduled",
+			})
+		case errors.Is(err, services.ErrInvalidPostsPolicy), errors.Is(err, services.ErrInvalidTransferTarget),
+			errors.Is(err, services.ErrDeletedUserPlaceholder):
+			c.JSON(http.StatusBadRequest, schemas.ErrorResponse{
+				Error: err.Error(),
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, schemas.ErrorResponse{
+				Error: fmt.Sprintf("Failed to delete user: %v", err),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusAccepted, schemas.AccountDeletionResponse{
+		Data:    *deletion,
+		Message: "Account scheduled for deletion",
+	})
+}
+
+// @Summary Get a scheduled account deletion
+// @Tags users
+// @Param id path int true "User ID"
+// @Success 200 {object} schemas.AccountDeletionResponse
+// @Failure 404 {object} schemas.ErrorResponse
+// @Router /users/{id}/deletion [get]
+func (v *UserViews) GetAccountDeletion(c *gin.Context) {
+	id, ok := v.deletionTarget(c)
+	if !ok {
+		return
+	}
+
+	deletion, err := v.deletionService.Get(id)
+	if err != nil {
+		if errors.Is(err, services.ErrAccountDeletionNotFound) {
 			c.JSON(http.StatusNotFound, schemas.ErrorResponse{
-				Error: "User not found",
+				Error: "No account deletion is scheduled",
 			})
 			return
 		}
-		c.JSON(http.StatusNotFound, schemas.ErrorResponse{
-			Error: fmt.Sprintf("Failed to delete user: %v", err),
+		c.JSON(http.StatusInternalServerError, schemas.ErrorResponse{
+			Error: fmt.Sprintf("Failed to get account deletion: %v", err),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, schemas.AccountDeletionResponse{Data: *deletion})
+}
+
+// @Summary Cancel a scheduled account deletion
+// @Description Keeps the account. Only possible until the grace period is over.
+// @Tags users
+// @Param id path int true "User ID"
+// @Success 200 {object} schemas.MessageResponse
+// @Failure 404 {object} schemas.ErrorResponse
+// @Router /users/{id}/deletion [delete]
+func (v *UserViews) CancelAccountDeletion(c *gin.Context) {
+	id, ok := v.deletionTarget(c)
+	if !ok {
+		return
+	}
+
+	if err := v.deletionService.Cancel(id); err != nil {
+		if errors.Is(err, services.ErrAccountDeletionNotFound) {
+			c.JSON(http.StatusNotFound, schemas.ErrorResponse{
+				Error: "No account deletion is scheduled",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, schemas.ErrorResponse{
+			Error: fmt.Sprintf("Failed to cancel account deletion: %v", err),
 		})
 		return
 	}
 
 	c.JSON(http.StatusOK, schemas.MessageResponse{
-		Message: "User deleted successfully",
+		Message: "Account deletion cancelled",
+	})
+}
+
+// deletionTarget parses the user ID of a deletion route and checks that the caller may manage
+// that account's deletion, like DeleteUser does
+func (v *UserViews) deletionTarget(c *gin.Context) (uint, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, schemas.ErrorResponse{
+			Error: "Invalid user ID",
+		})
+		return 0, false
+	}
+
+	actor, exists := GetActorFromContext(c)
+	if !exists || (actor.UserID != uint(id) && !actor.Can(services.PermUsersDelete)) {
+		c.JSON(http.StatusForbidden, schemas.ErrorResponse{
+			Error: "You can only manage the deletion of your own account",
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// @Summary Change a user's role
+// @Description Admin only. The user's existing tokens are revoked so that the new role applies immediately.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param roleInput body schemas.UpdateRoleInput true "New role"
+// @Success 200 {object} schemas.UserResponse
+// @Router /users/{id}/role [put]
+func (v *UserViews) UpdateUserRole(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, schemas.ErrorResponse{
+			Error: "Invalid user ID",
+		})
+		return
+	}
+
+	var input schemas.UpdateRoleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, schemas.ErrorResponse{
+			Error: fmt.Sprintf("Invalid request data: %v", err),
+		})
+		return
+	}
+
+	// Admins cannot demote themselves, so there is always someone left to manage roles
+	actor, _ := GetActorFromContext(c)
+	if actor.UserID == uint(id) {
+		c.JSON(http.StatusBadRequest, schemas.ErrorResponse{
+			Error: "You cannot change your own role",
+		})
+		return
+	}
+
+	result, err := v.service.UpdateRole(uint(id), models.Role(input.Role))
+	if err != nil {
+		switch {
+		case err.Error() == "user not found":
+			c.JSON(http.StatusNotFound, schemas.ErrorResponse{
+				Error: "User not found",
+			})
+		case errors.Is(err, services.ErrInvalidRole):
+			c.JSON(http.StatusBadRequest, schemas.ErrorResponse{
+				Error: "Invalid role",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, schemas.ErrorResponse{
+				Error: fmt.Sprintf("Failed to update role: %v", err),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, schemas.UserResponse{
+		Data:    *result,
+		Message: "Role updated successfully",
 	})
 }
 
@@ -259,9 +626,9 @@ func (v *UserViews) ListUserPosts(c *gin.Context) {
 	if statusParam := c.Query("status"); statusParam != "" {
 		status := models.PostStatus(statusParam)
 		// Validate status value
-		if status != models.Draft && status != models.Published {
+		if !status.IsValid() {
 			c.JSON(http.StatusBadRequest, schemas.ErrorResponse{
-				Error: "Invalid status: must be 'draft' or 'published'",
+				Error: "Invalid status: must be 'draft', 'published' or 'scheduled'",
 			})
 			return
 		}
@@ -292,9 +659,14 @@ func (v *UserViews) RegisterRoutes(router *gin.Engine) {
 	users := router.Group("/users")
 	{
 		users.POST("", v.CreateUser)
-		users.GET("/me/posts", AuthMiddleware(), v.ListUserPosts)
+		users.GET("/me/posts", AuthMiddleware(services.ScopeRead), v.ListUserPosts)
+		users.POST("/me/password", AuthMiddleware(), ForbidImpersonation(), v.ChangePassword)
+		users.POST("/me/email", AuthMiddleware(), ForbidImpersonation(), v.ChangeEmail)
 		users.GET("/:id", v.GetUserByID)
 		users.PATCH("/:id", AuthMiddleware(), v.PartialUpdateUser)
-		users.DELETE("/:id", AuthMiddleware(), v.DeleteUser)
+		users.DELETE("/:id", AuthMiddleware(), ForbidImpersonation(), v.DeleteUser)
+		users.GET("/:id/deletion", AuthMiddleware(), v.GetAccountDeletion)
+		users.DELETE("/:id/deletion", AuthMiddleware(), ForbidImpersonation(), v.CancelAccountDeletion)
+		users.PUT("/:id/role", AuthMiddleware(), RequirePermission(services.PermUsersManage), v.UpdateUserRole)
 	}
 }
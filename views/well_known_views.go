@@ -0,0 +1,46 @@
+package views
+
+import (
+	"fmt"
+	"go-crud/schemas"
+	"go-crud/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WellKnownViews struct{}
+
+func NewWellKnownViews() *WellKnownViews {
+	return &WellKnownViews{}
+}
+
+// @Summary JSON Web Key Set
+// @Description Public keys that verify the access tokens issued by this API, selected by the token's kid header.
+// @Description Retired keys stay listed until the tokens they signed have expired.
+// @Tags auth
+// @Produce json
+// @Success 200 {object} schemas.JWKSResponse
+// @Router /.well-known/jwks.json [get]
+func (v *WellKnownViews) JWKS(c *gin.Context) {
+	ring, err := services.CurrentKeyRing()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, schemas.ErrorResponse{
+			Error: fmt.Sprintf("Failed to load signing keys: %v", err),
+		})
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, schemas.JWKSResponse{
+		Keys: ring.JWKS(),
+	})
+}
+
+// RegisterRoutes registers the /.well-known discovery routes
+func (v *WellKnownViews) RegisterRoutes(router *gin.Engine) {
+	wellKnown := router.Group("/.well-known")
+	{
+		wellKnown.GET("/jwks.json", v.JWKS)
+	}
+}